@@ -0,0 +1,298 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Post-game analysis
+//
+// An Analyzer follows a game's events and builds a Report for reviewing it
+// afterwards: every placement with the time it took and the chain potential
+// it left, the placements that threw away a much longer chain than they
+// fired, and the field the biggest chain fired from. The report is printed
+// when the game ends and saved as JSON under ~/.puyo/reports.
+
+// MissedChainMargin is how many links longer than the chain fired the
+// available chain must be for a placement to count as a missed opportunity
+const MissedChainMargin = 2
+
+// ReportPotential is a chain potential as stored in a report
+type ReportPotential struct {
+	Chain    int    `json:"chain"`
+	Score    int    `json:"score,omitempty"`
+	Column   int    `json:"column,omitempty"`    // From 0 at the left
+	Color    string `json:"color,omitempty"`     // Field notation letter
+	KeyPuyos int    `json:"key_puyos,omitempty"` // Puyos needed to fire the chain
+}
+
+// reportPotential converts a potential for a report
+func reportPotential(p Potential) ReportPotential {
+	if p.Chain == 0 {
+		return ReportPotential{}
+	}
+	return ReportPotential{
+		Chain:    p.Chain,
+		Score:    p.Score,
+		Column:   p.Column,
+		Color:    string(colorLetter(p.Color)),
+		KeyPuyos: p.KeyPuyos,
+	}
+}
+
+// ReportCell is one puyo of a placed piece
+type ReportCell struct {
+	X     int    `json:"x"`
+	Y     int    `json:"y"`
+	Color string `json:"color"` // Field notation letter
+}
+
+// Placement is one piece in the report's timeline
+type Placement struct {
+	Index     int             `json:"index"`  // From 1
+	Frame     int             `json:"frame"`  // Game time when the piece locked
+	Frames    int             `json:"frames"` // Time from the piece appearing to locking
+	Cells     []ReportCell    `json:"cells"`
+	Chain     int             `json:"chain"`     // Chain the piece fired
+	Score     int             `json:"score"`     // Points for that chain
+	Available ReportPotential `json:"available"` // Potential before the piece
+	Potential ReportPotential `json:"potential"` // Potential left after the chain settled
+}
+
+// MissedChance is a placement that fired a much shorter chain than was
+// available and did not keep the longer chain
+type MissedChance struct {
+	Placement int             `json:"placement"`
+	Available ReportPotential `json:"available"`
+	Fired     int             `json:"fired"`
+}
+
+// BiggestChain is the longest chain of a game and the field it fired from
+type BiggestChain struct {
+	Placement int      `json:"placement"`
+	Chain     int      `json:"chain"`
+	Score     int      `json:"score"`
+	Field     []string `json:"field"` // Field notation rows, as the chain started
+}
+
+// Report is the analysis of one game
+type Report struct {
+	Date       time.Time      `json:"date"`
+	Score      int            `json:"score"`
+	Level      int            `json:"level"`
+	Frames     int            `json:"frames"`
+	Placements []Placement    `json:"placements"`
+	Missed     []MissedChance `json:"missed"`
+	Biggest    *BiggestChain  `json:"biggest_chain,omitempty"`
+}
+
+// AverageFrames returns the mean time per placement
+func (r *Report) AverageFrames() int {
+	if len(r.Placements) == 0 {
+		return 0
+	}
+	total := 0
+	for _, p := range r.Placements {
+		total += p.Frames
+	}
+	return total / len(r.Placements)
+}
+
+// Analyzer builds a report from a game's events
+type Analyzer struct {
+	game       *Game
+	report     Report
+	spawnFrame int        // Game time the current piece appeared
+	pending    *Placement // Locked piece whose chain has not settled yet
+	fired      *Field     // Field as the pending piece's chain starts
+}
+
+// NewAnalyzer creates an analyzer and subscribes it to the game's events
+// After a restart keeps the event bus, Reset points it at the new game.
+func NewAnalyzer(g *Game) *Analyzer {
+	a := &Analyzer{}
+	a.Reset(g)
+	g.Events.Subscribe(a.handle)
+	return a
+}
+
+// Reset starts a new report for a game
+func (a *Analyzer) Reset(g *Game) {
+	a.game = g
+	a.report = Report{Placements: []Placement{}, Missed: []MissedChance{}}
+	a.spawnFrame = g.Frames
+	a.pending = nil
+	a.fired = nil
+}
+
+// handle records one event
+func (a *Analyzer) handle(ev Event) {
+	g := a.game
+	switch e := ev.(type) {
+	case PairLocked:
+		a.lock(e)
+	case ChainStep:
+		if a.pending != nil {
+			a.pending.Chain = e.Chain
+			a.pending.Score += e.Score
+		}
+	case PairSpawned:
+		a.settle()
+		a.spawnFrame = g.Frames
+	case GameOver:
+		a.settle()
+	}
+}
+
+// lock starts the placement for a piece that has just locked
+func (a *Analyzer) lock(e PairLocked) {
+	g := a.game
+	p := &Placement{
+		Index:  len(a.report.Placements) + 1,
+		Frame:  g.Frames,
+		Frames: g.Frames - a.spawnFrame,
+		Cells:  make([]ReportCell, len(e.Cells)),
+	}
+
+	// The potential before the piece is the field without it
+	before := g.Field.Clone()
+	for i, c := range e.Cells {
+		p.Cells[i] = ReportCell{X: c.Pos.X, Y: c.Pos.Y, Color: string(colorLetter(c.Color))}
+		if before.InBounds(c.Pos.X, c.Pos.Y) {
+			before.Grid[c.Pos.Y][c.Pos.X] = Empty
+		}
+	}
+	p.Available = reportPotential(ChainPotential(before, g.Rules, MaxKeyPuyos))
+
+	a.fired = g.Field.Clone()
+	a.fired.settle()
+	a.pending = p
+}
+
+// settle finishes the pending placement once its chain is over
+func (a *Analyzer) settle() {
+	p := a.pending
+	if p == nil {
+		return
+	}
+	g := a.game
+	p.Potential = reportPotential(ChainPotential(g.Field, g.Rules, MaxKeyPuyos))
+	a.report.Placements = append(a.report.Placements, *p)
+
+	if p.Available.Chain-p.Chain >= MissedChainMargin && p.Potential.Chain < p.Available.Chain {
+		a.report.Missed = append(a.report.Missed, MissedChance{
+			Placement: p.Index,
+			Available: p.Available,
+			Fired:     p.Chain,
+		})
+	}
+
+	if best := a.report.Biggest; p.Chain > 0 && (best == nil || p.Chain > best.Chain) {
+		a.report.Biggest = &BiggestChain{
+			Placement: p.Index,
+			Chain:     p.Chain,
+			Score:     p.Score,
+			Field:     strings.Split(strings.TrimSuffix(a.fired.Notation(), "\n"), "\n"),
+		}
+	}
+
+	a.pending = nil
+	a.fired = nil
+}
+
+// Report returns the report so far, including the game's final result
+func (a *Analyzer) Report() *Report {
+	r := a.report
+	r.Date = time.Now().Truncate(time.Second)
+	r.Score = a.game.Score
+	r.Level = a.game.Level
+	r.Frames = a.game.Frames
+	return &r
+}
+
+// WriteText prints the report for reading in a terminal
+func (r *Report) WriteText(w io.Writer) {
+	fmt.Fprintf(w, "\n%s\n", T("report.title"))
+	fmt.Fprintf(w, "%s\n", T("report.summary", len(r.Placements), formatFrames(r.AverageFrames())))
+
+	if b := r.Biggest; b != nil {
+		fmt.Fprintf(w, "%s\n", T("report.biggest", b.Chain, b.Placement, b.Score))
+		for _, row := range b.Field {
+			fmt.Fprintf(w, "  %s\n", row)
+		}
+	}
+
+	fmt.Fprintf(w, "%s\n", T("report.timeline"))
+	for _, p := range r.Placements {
+		chain := "-"
+		if p.Chain > 0 {
+			chain = fmt.Sprintf("%d", p.Chain)
+		}
+		fmt.Fprintf(w, "  %3d  %s  %3s  %s\n", p.Index, formatFrames(p.Frames), chain, potentialText(p.Potential))
+	}
+
+	if len(r.Missed) == 0 {
+		fmt.Fprintf(w, "%s\n", T("report.no_missed"))
+		return
+	}
+	fmt.Fprintf(w, "%s\n", T("report.missed"))
+	for _, m := range r.Missed {
+		fmt.Fprintf(w, "  %s\n", T("report.missed_item", m.Placement, potentialText(m.Available), m.Fired))
+	}
+}
+
+// potentialText describes a potential in one line
+func potentialText(p ReportPotential) string {
+	if p.Chain == 0 {
+		return "-"
+	}
+	return T("report.potential", p.Chain, p.KeyPuyos, p.Color, p.Column+1)
+}
+
+// getReportsDir returns the directory the reports are saved in
+func getReportsDir() (string, error) {
+	return getConfigPath("reports")
+}
+
+// SaveReport writes a report as JSON into the reports directory
+// Returns the path of the new file. Reports from the same second get a
+// sequence number, so a quick restart never overwrites the last report.
+func SaveReport(r *Report) (string, error) {
+	dir, err := getReportsDir()
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return "", err
+	}
+
+	base := filepath.Join(dir, "report-"+r.Date.Format("20060102-150405"))
+	for n := 1; ; n++ {
+		path := base + ".json"
+		if n > 1 {
+			path = fmt.Sprintf("%s-%d.json", base, n)
+		}
+		f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+		if os.IsExist(err) {
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+		if _, err := f.Write(data); err != nil {
+			f.Close()
+			return "", err
+		}
+		return path, f.Close()
+	}
+}
@@ -0,0 +1,188 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+)
+
+// placePair drops a vertical pair into column x after frames of thinking
+// and resolves the field
+func placePair(g *Game, x int, main, sub Color, frames int) {
+	g.Frames += frames
+	g.Current = newPiece(PiecePair, main, sub)
+	g.Current.Pos = Position{x, 1}
+	g.HardDrop()
+	g.LockPair()
+	for g.ProcessChainStep() {
+	}
+}
+
+// analyzedGame creates a game on a field with an analyzer attached
+func analyzedGame(t *testing.T, field string) (*Game, *Analyzer) {
+	t.Helper()
+	game := NewGameWithRules(DefaultRules(), 1)
+	game.Field, _, _ = ParseFieldNotation(field, FieldWidth, FieldHeight)
+	return game, NewAnalyzer(game)
+}
+
+func TestAnalyzerTimeline(t *testing.T) {
+	game, analyzer := analyzedGame(t, almostTwoChain)
+
+	placePair(game, 5, Blue, Yellow, 30)
+	placePair(game, 4, Red, Red, 45)
+
+	r := analyzer.Report()
+	if len(r.Placements) != 2 {
+		t.Fatalf("Expected 2 placements, got %d", len(r.Placements))
+	}
+
+	first := r.Placements[0]
+	if first.Index != 1 || first.Frames != 30 || first.Frame != 30 {
+		t.Errorf("Expected placement 1 after 30 frames, got %+v", first)
+	}
+	if len(first.Cells) != 2 || first.Cells[0].X != 5 || first.Cells[0].Color != "B" {
+		t.Errorf("Expected the blue-yellow pair in column 5, got %+v", first.Cells)
+	}
+	if first.Chain != 0 || first.Potential.Chain != 2 {
+		t.Errorf("Expected no chain and a 2-chain left, got %+v", first)
+	}
+
+	second := r.Placements[1]
+	if second.Frames != 45 || second.Frame != 75 {
+		t.Errorf("Expected placement 2 to take 45 frames, got %+v", second)
+	}
+	if second.Chain != 2 || second.Score != 400 || second.Available.Chain != 2 {
+		t.Errorf("Expected the available 2-chain to fire, got %+v", second)
+	}
+	want := ReportPotential{Chain: 2, Score: 400, Column: 4, Color: "R", KeyPuyos: 1}
+	if second.Available != want {
+		t.Errorf("Expected %+v available, got %+v", want, second.Available)
+	}
+	if len(r.Missed) != 0 {
+		t.Errorf("Expected no missed opportunities, got %+v", r.Missed)
+	}
+	if r.AverageFrames() != 37 {
+		t.Errorf("Expected 37 frames per placement, got %d", r.AverageFrames())
+	}
+}
+
+func TestAnalyzerMissedOpportunity(t *testing.T) {
+	game, analyzer := analyzedGame(t, almostTwoChain)
+
+	// Blue covers the red trigger, leaving only a 1-chain
+	placePair(game, 4, Blue, Blue, 10)
+
+	r := analyzer.Report()
+	if len(r.Missed) != 1 {
+		t.Fatalf("Expected 1 missed opportunity, got %+v", r.Missed)
+	}
+	m := r.Missed[0]
+	if m.Placement != 1 || m.Available.Chain != 2 || m.Fired != 0 {
+		t.Errorf("Expected a missed 2-chain on placement 1, got %+v", m)
+	}
+}
+
+func TestAnalyzerKeptChainIsNotMissed(t *testing.T) {
+	game, analyzer := analyzedGame(t, almostTwoChain)
+
+	// Building elsewhere keeps the 2-chain available
+	placePair(game, 5, Yellow, Yellow, 10)
+
+	if r := analyzer.Report(); len(r.Missed) != 0 {
+		t.Errorf("Expected no missed opportunities, got %+v", r.Missed)
+	}
+}
+
+func TestAnalyzerBiggestChain(t *testing.T) {
+	game, analyzer := analyzedGame(t, almostTwoChain)
+
+	placePair(game, 4, Red, Red, 10)
+
+	b := analyzer.Report().Biggest
+	if b == nil {
+		t.Fatal("Expected a biggest chain")
+	}
+	if b.Placement != 1 || b.Chain != 2 || b.Score != 400 {
+		t.Errorf("Expected the 2-chain of placement 1, got %+v", b)
+	}
+	want := []string{".GGGR.", "GRRRR."}
+	if strings.Join(b.Field, "/") != strings.Join(want, "/") {
+		t.Errorf("Expected field %v as the chain fired, got %v", want, b.Field)
+	}
+}
+
+func TestAnalyzerReset(t *testing.T) {
+	game, analyzer := analyzedGame(t, almostTwoChain)
+	placePair(game, 4, Red, Red, 10)
+
+	next := NewGameWithRules(DefaultRules(), 2)
+	next.Events = game.Events
+	analyzer.Reset(next)
+	placePair(next, 0, Blue, Yellow, 20)
+
+	r := analyzer.Report()
+	if len(r.Placements) != 1 || r.Biggest != nil {
+		t.Fatalf("Expected only the new game's placement, got %+v", r)
+	}
+	if r.Placements[0].Frames != 20 {
+		t.Errorf("Expected 20 frames, got %d", r.Placements[0].Frames)
+	}
+}
+
+func TestReportText(t *testing.T) {
+	SetLanguage("en")
+	game, analyzer := analyzedGame(t, almostTwoChain)
+	placePair(game, 4, Blue, Blue, 60)
+
+	var buf bytes.Buffer
+	analyzer.Report().WriteText(&buf)
+	text := buf.String()
+
+	for _, want := range []string{
+		"Placements: 1, average 0:01.00 per placement",
+		"Missed opportunities:",
+		"Placement 1: 2-chain (1×R in column 5) was available, fired 0",
+	} {
+		if !strings.Contains(text, want) {
+			t.Errorf("Expected %q in report:\n%s", want, text)
+		}
+	}
+}
+
+func TestSaveReport(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	game, analyzer := analyzedGame(t, almostTwoChain)
+	placePair(game, 4, Red, Red, 10)
+
+	path, err := SaveReport(analyzer.Report())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var r Report
+	if err := json.Unmarshal(data, &r); err != nil {
+		t.Fatal(err)
+	}
+	if r.Score != game.Score || len(r.Placements) != 1 || r.Biggest == nil || r.Biggest.Chain != 2 {
+		t.Errorf("Expected the saved report to round-trip, got %+v", r)
+	}
+
+	// A second report from the same second gets its own file
+	second, err := SaveReport(analyzer.Report())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if second == path {
+		t.Errorf("Expected a new file, both reports went to %s", path)
+	}
+	if again, _ := os.ReadFile(path); string(again) != string(data) {
+		t.Error("Expected the first report to be kept")
+	}
+}
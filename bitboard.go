@@ -0,0 +1,249 @@
+package main
+
+import (
+	"fmt"
+	"math/bits"
+)
+
+// Bitboards
+//
+// A Bitboard holds a field as one bit mask per color, for search code that
+// resolves millions of fields. Each column takes 16 bits of a 128-bit mask,
+// counted from the bottom row, so the masks fit fields up to
+// BitboardMaxWidth x BitboardMaxHeight. The top bit of every column is kept
+// empty: shifting a column up or down then never carries a puyo into the
+// next column, and whole groups are found, dropped and popped with a few
+// shifts and masks per step instead of a cell-by-cell search.
+
+// Bitboard size limits
+const (
+	BitboardMaxWidth  = 8
+	BitboardMaxHeight = 15
+)
+
+// bitsPerColumn is the number of bits each column takes in a mask
+const bitsPerColumn = 16
+
+// bits128 is a set of cells, bit x*16+row with row 0 at the bottom
+type bits128 struct {
+	lo, hi uint64 // Columns 0-3 and 4-7
+}
+
+func (a bits128) and(b bits128) bits128    { return bits128{a.lo & b.lo, a.hi & b.hi} }
+func (a bits128) or(b bits128) bits128     { return bits128{a.lo | b.lo, a.hi | b.hi} }
+func (a bits128) andNot(b bits128) bits128 { return bits128{a.lo &^ b.lo, a.hi &^ b.hi} }
+func (a bits128) empty() bool              { return a.lo|a.hi == 0 }
+func (a bits128) count() int               { return bits.OnesCount64(a.lo) + bits.OnesCount64(a.hi) }
+
+// lowest returns the set holding only the lowest cell of a
+func (a bits128) lowest() bits128 {
+	if a.lo != 0 {
+		return bits128{lo: a.lo & -a.lo}
+	}
+	return bits128{hi: a.hi & -a.hi}
+}
+
+// neighbors returns a with every cell's four neighbors added
+// Cells shifted into a column's empty top bit or off the board must be
+// masked off by the caller.
+func (a bits128) neighbors() bits128 {
+	up := bits128{a.lo << 1, a.hi << 1}
+	down := bits128{a.lo >> 1, a.hi >> 1}
+	left := bits128{a.lo>>bitsPerColumn | a.hi<<(64-bitsPerColumn), a.hi >> bitsPerColumn}
+	right := bits128{a.lo << bitsPerColumn, a.hi<<bitsPerColumn | a.lo>>(64-bitsPerColumn)}
+	return a.or(up).or(down).or(left).or(right)
+}
+
+// column returns the cells of column x, bottom row first
+func (a bits128) column(x int) uint16 {
+	if x < 4 {
+		return uint16(a.lo >> (x * bitsPerColumn))
+	}
+	return uint16(a.hi >> ((x - 4) * bitsPerColumn))
+}
+
+// setColumn replaces the cells of column x
+func (a *bits128) setColumn(x int, c uint16) {
+	if x < 4 {
+		shift := x * bitsPerColumn
+		a.lo = a.lo&^(0xffff<<shift) | uint64(c)<<shift
+		return
+	}
+	shift := (x - 4) * bitsPerColumn
+	a.hi = a.hi&^(0xffff<<shift) | uint64(c)<<shift
+}
+
+// cellBit returns the set holding the cell at (x, row)
+func cellBit(x, row int) bits128 {
+	var b bits128
+	b.setColumn(x, 1<<row)
+	return b
+}
+
+// Bitboard is a field stored as one cell set per color
+// It is a plain value: copying it copies the field.
+type Bitboard struct {
+	Width, Height int
+	colors        [Nuisance + 1]bits128 // Indexed by color; Empty is unused
+}
+
+// NewBitboard converts a field to a bitboard
+func NewBitboard(f *Field) (Bitboard, error) {
+	if f.Width > BitboardMaxWidth || f.Height > BitboardMaxHeight {
+		return Bitboard{}, fmt.Errorf("field is %dx%d, bitboards hold at most %dx%d", f.Width, f.Height, BitboardMaxWidth, BitboardMaxHeight)
+	}
+	b := Bitboard{Width: f.Width, Height: f.Height}
+	for y := 0; y < f.Height; y++ {
+		for x := 0; x < f.Width; x++ {
+			if c := f.Grid[y][x]; c != Empty {
+				b.Set(x, y, c)
+			}
+		}
+	}
+	return b, nil
+}
+
+// Field converts the bitboard back to a field
+func (b *Bitboard) Field() *Field {
+	f := NewFieldSize(b.Width, b.Height)
+	for y := 0; y < b.Height; y++ {
+		for x := 0; x < b.Width; x++ {
+			f.Grid[y][x] = b.Get(x, y)
+		}
+	}
+	return f
+}
+
+// Get returns the color at (x, y), with y counted from the top as in Field
+func (b *Bitboard) Get(x, y int) Color {
+	bit := cellBit(x, b.Height-1-y)
+	for c := Red; c <= Nuisance; c++ {
+		if !b.colors[c].and(bit).empty() {
+			return c
+		}
+	}
+	return Empty
+}
+
+// Set puts a color at (x, y), with y counted from the top as in Field
+func (b *Bitboard) Set(x, y int, c Color) {
+	bit := cellBit(x, b.Height-1-y)
+	for i := range b.colors {
+		b.colors[i] = b.colors[i].andNot(bit)
+	}
+	if c != Empty {
+		b.colors[c] = b.colors[c].or(bit)
+	}
+}
+
+// occupied returns every cell holding a puyo
+func (b *Bitboard) occupied() bits128 {
+	var all bits128
+	for c := Red; c <= Nuisance; c++ {
+		all = all.or(b.colors[c])
+	}
+	return all
+}
+
+// IsEmpty reports whether the bitboard has no puyos
+func (b *Bitboard) IsEmpty() bool {
+	return b.occupied().empty()
+}
+
+// ColumnHeight returns the number of puyos in column x
+func (b *Bitboard) ColumnHeight(x int) int {
+	return bits.OnesCount16(b.occupied().column(x))
+}
+
+// Drop lets every puyo fall to the bottom of its column
+// Each column's colors are packed down past its empty cells.
+func (b *Bitboard) Drop() {
+	all := b.occupied()
+	for x := 0; x < b.Width; x++ {
+		occ := all.column(x)
+		if occ&(occ+1) == 0 {
+			continue // Already packed from the bottom
+		}
+		for c := Red; c <= Nuisance; c++ {
+			if col := b.colors[c].column(x); col != 0 {
+				b.colors[c].setColumn(x, compress(col, occ))
+			}
+		}
+	}
+}
+
+// compress packs the bits of v selected by mask into the low bits, in order
+func compress(v, mask uint16) uint16 {
+	var packed, bit uint16 = 0, 1
+	for ; mask != 0; mask &= mask - 1 {
+		if v&mask&-mask != 0 {
+			packed |= bit
+		}
+		bit <<= 1
+	}
+	return packed
+}
+
+// Pop removes every group of at least popCount puyos and the nuisance next to them
+// Returns the points for the link under the rules and the puyos removed, 0
+// if nothing popped. The field should be dropped first.
+func (b *Bitboard) Pop(rules Rules, chain int) (int, int) {
+	var sizesBuf [BitboardMaxWidth * BitboardMaxHeight / 2]int
+	sizes := sizesBuf[:0]
+	var popping bits128
+	colors := 0
+
+	for c := Red; c <= MaxColor; c++ {
+		popped := false
+		for rest := b.colors[c]; !rest.empty(); {
+			// Flood fill from the lowest cell left, a whole ring at a time
+			group := rest.lowest()
+			for {
+				grown := group.neighbors().and(b.colors[c])
+				if grown == group {
+					break
+				}
+				group = grown
+			}
+			rest = rest.andNot(group)
+
+			if size := group.count(); size >= rules.PopCount {
+				popping = popping.or(group)
+				sizes = append(sizes, size)
+				popped = true
+			}
+		}
+		if popped {
+			colors++
+		}
+	}
+	if popping.empty() {
+		return 0, 0
+	}
+
+	nuisance := popping.neighbors().and(b.colors[Nuisance])
+	for c := Red; c <= MaxColor; c++ {
+		b.colors[c] = b.colors[c].andNot(popping)
+	}
+	b.colors[Nuisance] = b.colors[Nuisance].andNot(nuisance)
+
+	return rules.stepScore(chain, sizes, colors), popping.count() + nuisance.count()
+}
+
+// Resolve drops and pops until nothing more pops, leaving the settled field
+// The result is the same as ChainSimulator.Resolve on the same field.
+func (b *Bitboard) Resolve(rules Rules) ChainSummary {
+	var sum ChainSummary
+	for {
+		b.Drop()
+		score, cleared := b.Pop(rules, sum.Chain+1)
+		if cleared == 0 {
+			break
+		}
+		sum.Chain++
+		sum.Score += score
+		sum.Cleared += cleared
+	}
+	sum.AllClear = sum.Chain > 0 && b.IsEmpty()
+	return sum
+}
@@ -0,0 +1,201 @@
+package main
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestBitboardRoundTrip(t *testing.T) {
+	field := randomField(rand.New(rand.NewSource(1)), FieldWidth, FieldHeight, 6)
+	b, err := NewBitboard(field)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b.Field().Notation() != field.Notation() {
+		t.Errorf("Expected the same field back:\n%s\nvs\n%s", b.Field().Notation(), field.Notation())
+	}
+
+	b.Set(0, 0, Purple)
+	if b.Get(0, 0) != Purple {
+		t.Error("Expected Set to place a puyo")
+	}
+	b.Set(0, 0, Empty)
+	if b.Get(0, 0) != Empty {
+		t.Error("Expected Set to clear a cell")
+	}
+}
+
+func TestBitboardSizeLimit(t *testing.T) {
+	if _, err := NewBitboard(NewFieldSize(BitboardMaxWidth, BitboardMaxHeight)); err != nil {
+		t.Errorf("Expected the largest field to fit: %v", err)
+	}
+	if _, err := NewBitboard(NewFieldSize(BitboardMaxWidth+1, FieldHeight)); err == nil {
+		t.Error("Expected a wider field to be rejected")
+	}
+	if _, err := NewBitboard(NewFieldSize(FieldWidth, BitboardMaxHeight+1)); err == nil {
+		t.Error("Expected a taller field to be rejected")
+	}
+}
+
+func TestBitboardDrop(t *testing.T) {
+	field, _, _ := ParseFieldNotation(`
+R.....
+G....Y
+....N.
+B.P...
+`, FieldWidth, FieldHeight)
+	b, _ := NewBitboard(field)
+	b.Drop()
+
+	want, _, _ := ParseFieldNotation(`
+R.....
+G.....
+B.P.NY
+`, FieldWidth, FieldHeight)
+	if b.Field().Notation() != want.Notation() {
+		t.Errorf("Unexpected field after the drop:\n%s", b.Field().Notation())
+	}
+}
+
+func TestBitboardPop(t *testing.T) {
+	// Both groups pop in one link and take the nuisance between them along
+	field, _, _ := ParseFieldNotation(`
+RRN.BB
+RRN.BB
+`, FieldWidth, FieldHeight)
+	b, _ := NewBitboard(field)
+
+	score, cleared := b.Pop(RulesByName("tsu"), 1)
+	if cleared != 10 || !b.IsEmpty() {
+		t.Errorf("Expected 8 puyos and 2 nuisance cleared, got %d", cleared)
+	}
+	// 8 puyos x (0 chain power + 3 for two colors) x 10
+	if score != 240 {
+		t.Errorf("Expected 240 points, got %d", score)
+	}
+
+	if _, cleared := b.Pop(DefaultRules(), 2); cleared != 0 {
+		t.Error("Expected nothing to pop on an empty field")
+	}
+}
+
+func TestBitboardResolve(t *testing.T) {
+	field, _, _ := ParseFieldNotation(twoChainField, FieldWidth, FieldHeight)
+	b, _ := NewBitboard(field)
+
+	sum := b.Resolve(DefaultRules())
+	if sum != (ChainSummary{Chain: 2, Score: 400, Cleared: 8, AllClear: true}) {
+		t.Errorf("Unexpected result %+v", sum)
+	}
+}
+
+// checkBitboardMatches resolves a field as a bitboard and with the chain
+// simulator and fails if the results differ
+func checkBitboardMatches(t *testing.T, field *Field, rules Rules) {
+	t.Helper()
+	b, err := NewBitboard(field)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := b.Resolve(rules)
+
+	s := NewChainSimulator(rules)
+	want := s.Resolve(field)
+	if got != want {
+		t.Fatalf("Bitboard gave %+v, simulator %+v for\n%s", got, want, field.Notation())
+	}
+	if b.Field().Notation() != s.Field().Notation() {
+		t.Fatalf("Final fields differ:\n%s\nvs\n%s", b.Field().Notation(), s.Field().Notation())
+	}
+}
+
+func TestBitboardMatchesSimulator(t *testing.T) {
+	r := rand.New(rand.NewSource(2))
+	for _, name := range []string{"standard", "wide", "casual", "tsu"} {
+		rules := RulesByName(name)
+		for i := 0; i < 500; i++ {
+			checkBitboardMatches(t, randomField(r, rules.Width, rules.Height, 4+r.Intn(3)), rules)
+		}
+	}
+}
+
+// FuzzBitboardMatchesSimulator builds fields from fuzz input: the first byte
+// picks the rules, then each byte fills one cell, column by column from the
+// bottom, with a zero byte ending the column
+func FuzzBitboardMatchesSimulator(f *testing.F) {
+	f.Add([]byte{0, 1, 1, 1, 0, 1, 2, 2, 0, 2, 2, 0, 3})
+	f.Add([]byte{1, 7, 1, 7, 1, 0, 1, 1, 0, 2, 2, 2, 0, 7, 7})
+	f.Add([]byte{2, 1, 2, 3, 4, 5, 6, 1, 2, 3, 4, 5, 6, 1, 2})
+	f.Add([]byte{3, 1, 1, 2, 2, 0, 1, 2, 2, 0, 1, 1, 0, 3, 3, 3, 0, 3})
+
+	presets := []string{"standard", "wide", "casual", "tsu"}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		if len(data) == 0 {
+			return
+		}
+		rules := RulesByName(presets[int(data[0])%len(presets)])
+		field := NewFieldSize(rules.Width, rules.Height)
+
+		x, y := 0, rules.Height-1
+		for _, v := range data[1:] {
+			if v == 0 || y < 0 {
+				x, y = x+1, rules.Height-1
+				if x == rules.Width {
+					break
+				}
+				if v == 0 {
+					continue
+				}
+			}
+			field.Grid[y][x] = Color(1 + int(v)%int(Nuisance))
+			y--
+		}
+		checkBitboardMatches(t, field, rules)
+	})
+}
+
+// chainBenchFields returns random fields to resolve in the benchmarks
+func chainBenchFields() []*Field {
+	r := rand.New(rand.NewSource(4))
+	fields := make([]*Field, 64)
+	for i := range fields {
+		fields[i] = randomField(r, FieldWidth, FieldHeight, 4)
+	}
+	return fields
+}
+
+// BenchmarkGameChain resolves fields with the game's own chain steps
+func BenchmarkGameChain(b *testing.B) {
+	fields := chainBenchFields()
+	game := NewGame()
+	game.Events = nil
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		f := fields[i%len(fields)]
+		for y := range f.Grid {
+			copy(game.Field.Grid[y], f.Grid[y])
+		}
+		game.GameOver = false
+		game.State = StateDropping
+		game.ChainCount = 0
+		for game.ProcessChainStep() {
+		}
+	}
+}
+
+func BenchmarkBitboardResolve(b *testing.B) {
+	fields := chainBenchFields()
+	boards := make([]Bitboard, len(fields))
+	for i, f := range fields {
+		boards[i], _ = NewBitboard(f)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		board := boards[i%len(boards)]
+		board.Resolve(DefaultRules())
+	}
+}
@@ -0,0 +1,229 @@
+package main
+
+// Chain simulation
+//
+// SimulateChain answers "what happens if this field resolves?" without a
+// Game: it pops, drops and scores every link of the chain on a copy of the
+// field, exactly as ProcessChainStep would, and reports the whole result.
+// Bots and search code that call it in a loop use a ChainSimulator, which
+// keeps its scratch buffers between calls and does not allocate.
+
+// ChainGroup is one group popped in a chain link
+type ChainGroup struct {
+	Color     Color
+	Positions []Position // Top to bottom, then left to right
+}
+
+// ChainLink is one step of a chain
+type ChainLink struct {
+	Groups   []ChainGroup // In the order ProcessChainStep pops them
+	Colors   int          // Distinct colors popped
+	Puyos    int          // Colored puyos popped
+	Nuisance int          // Nuisance puyos cleared next to the groups
+	Score    int          // Points for the link
+}
+
+// ChainSummary is the outcome of a chain without its details
+type ChainSummary struct {
+	Chain    int  // Number of links
+	Score    int  // Points for all links; the all-clear bonus is not included
+	Cleared  int  // Puyos removed, nuisance included
+	AllClear bool // The chain left the field empty
+}
+
+// ChainResult is the full resolution of a field
+type ChainResult struct {
+	ChainSummary
+	Links []ChainLink
+	Field *Field // The field after the chain, settled
+}
+
+// SimulateChain resolves every chain link of a field under the rules
+// The field is not modified. Floating puyos fall before the first link, as
+// they do after a piece locks.
+func SimulateChain(f *Field, rules Rules) *ChainResult {
+	s := NewChainSimulator(rules)
+	result := &ChainResult{}
+	s.run(f, result)
+	result.Field = s.field.Clone()
+	return result
+}
+
+// ChainSimulator resolves chains with reusable scratch buffers
+// It is not safe for concurrent use; give each goroutine its own.
+type ChainSimulator struct {
+	rules   Rules
+	field   *Field     // Scratch field the chain is resolved on
+	visited []bool     // Indexed y*Width+x, for the current link
+	stack   []Position // Flood fill work list
+	popping []Position // Puyos popping in the current link
+	sizes   []int      // Group sizes of the current link, for scoring
+}
+
+// NewChainSimulator creates a simulator for the rules
+func NewChainSimulator(rules Rules) *ChainSimulator {
+	return &ChainSimulator{rules: rules}
+}
+
+// Resolve resolves a field and returns the outcome without its details
+// The field is not modified; the field after the chain is available from
+// Field until the next call. Resolve does not allocate once the scratch
+// buffers have grown to the field's size.
+func (s *ChainSimulator) Resolve(f *Field) ChainSummary {
+	return s.run(f, nil)
+}
+
+// Field returns the field left by the last Resolve
+func (s *ChainSimulator) Field() *Field {
+	return s.field
+}
+
+// run resolves a field, recording every link in result if it is not nil
+func (s *ChainSimulator) run(f *Field, result *ChainResult) ChainSummary {
+	s.load(f)
+
+	var sum ChainSummary
+	for {
+		s.field.settle()
+
+		var link *ChainLink
+		if result != nil {
+			result.Links = append(result.Links, ChainLink{})
+			link = &result.Links[len(result.Links)-1]
+		}
+		score, cleared := s.pop(sum.Chain+1, link)
+		if cleared == 0 {
+			if result != nil {
+				result.Links = result.Links[:len(result.Links)-1]
+			}
+			break
+		}
+		sum.Chain++
+		sum.Score += score
+		sum.Cleared += cleared
+	}
+
+	sum.AllClear = sum.Chain > 0 && s.field.IsEmpty()
+	if result != nil {
+		result.ChainSummary = sum
+	}
+	return sum
+}
+
+// load copies f into the scratch field and sizes the buffers for it
+func (s *ChainSimulator) load(f *Field) {
+	if s.field == nil || s.field.Width != f.Width || s.field.Height != f.Height {
+		s.field = NewFieldSize(f.Width, f.Height)
+		s.visited = make([]bool, f.Width*f.Height)
+	}
+	for y := range f.Grid {
+		copy(s.field.Grid[y], f.Grid[y])
+	}
+}
+
+// pop removes the groups that pop in one link and the nuisance next to them
+// Returns the link's points and the number of puyos removed, 0 if nothing popped.
+func (s *ChainSimulator) pop(chain int, link *ChainLink) (int, int) {
+	f := s.field
+	clear(s.visited)
+	s.popping = s.popping[:0]
+	s.sizes = s.sizes[:0]
+	var colors uint
+
+	for y := 0; y < f.Height; y++ {
+		for x := 0; x < f.Width; x++ {
+			color := f.Grid[y][x]
+			if color == Empty || color == Nuisance || s.visited[y*f.Width+x] {
+				continue
+			}
+
+			start := len(s.popping)
+			s.fill(x, y, color)
+			size := len(s.popping) - start
+			if size < s.rules.PopCount {
+				s.popping = s.popping[:start]
+				continue
+			}
+
+			s.sizes = append(s.sizes, size)
+			colors |= 1 << color
+			if link != nil {
+				group := ChainGroup{Color: color, Positions: append([]Position(nil), s.popping[start:]...)}
+				sortPositions(group.Positions)
+				link.Groups = append(link.Groups, group)
+			}
+		}
+	}
+	if len(s.sizes) == 0 {
+		return 0, 0
+	}
+
+	// Nuisance next to a popping puyo goes with it
+	nuisance := 0
+	for _, p := range s.popping {
+		for _, d := range directions {
+			nx, ny := p.X+d.dx, p.Y+d.dy
+			if f.InBounds(nx, ny) && f.Grid[ny][nx] == Nuisance {
+				f.Grid[ny][nx] = Empty
+				nuisance++
+			}
+		}
+	}
+	for _, p := range s.popping {
+		f.Grid[p.Y][p.X] = Empty
+	}
+
+	numColors := 0
+	for ; colors != 0; colors &= colors - 1 {
+		numColors++
+	}
+	score := s.rules.stepScore(chain, s.sizes, numColors)
+
+	if link != nil {
+		link.Colors = numColors
+		link.Puyos = len(s.popping)
+		link.Nuisance = nuisance
+		link.Score = score
+	}
+	return score, len(s.popping) + nuisance
+}
+
+// fill appends the group of color connected to (x, y) to s.popping
+// The flood fill works from a stack instead of recursing, and marks the
+// cells it reaches in s.visited.
+func (s *ChainSimulator) fill(x, y int, color Color) {
+	f := s.field
+	s.visited[y*f.Width+x] = true
+	s.stack = append(s.stack[:0], Position{x, y})
+
+	for len(s.stack) > 0 {
+		p := s.stack[len(s.stack)-1]
+		s.stack = s.stack[:len(s.stack)-1]
+		s.popping = append(s.popping, p)
+
+		for _, d := range directions {
+			nx, ny := p.X+d.dx, p.Y+d.dy
+			if !f.InBounds(nx, ny) || s.visited[ny*f.Width+nx] || f.Grid[ny][nx] != color {
+				continue
+			}
+			s.visited[ny*f.Width+nx] = true
+			s.stack = append(s.stack, Position{nx, ny})
+		}
+	}
+}
+
+// settle drops every puyo to the lowest free cell of its column
+func (f *Field) settle() {
+	for x := 0; x < f.Width; x++ {
+		writeY := f.Height - 1
+		for y := f.Height - 1; y >= 0; y-- {
+			if f.Grid[y][x] != Empty {
+				if writeY != y {
+					f.Grid[writeY][x] = f.Grid[y][x]
+					f.Grid[y][x] = Empty
+				}
+				writeY--
+			}
+		}
+	}
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+// randomField fills the columns of a field to random heights with random
+// colors and some nuisance, leaving it full of groups ready to pop
+func randomField(r *rand.Rand, width, height, colors int) *Field {
+	f := NewFieldSize(width, height)
+	for x := 0; x < width; x++ {
+		for y := height - 1 - r.Intn(height); y < height; y++ {
+			if r.Intn(10) == 0 {
+				f.Grid[y][x] = Nuisance
+			} else {
+				f.Grid[y][x] = Color(1 + r.Intn(colors))
+			}
+		}
+	}
+	return f
+}
+
+func TestSimulateChain(t *testing.T) {
+	field, _, _ := ParseFieldNotation(twoChainField, FieldWidth, FieldHeight)
+	before := field.Notation()
+
+	result := SimulateChain(field, DefaultRules())
+
+	if field.Notation() != before {
+		t.Error("Expected the field not to change")
+	}
+	if result.Chain != 2 || len(result.Links) != 2 {
+		t.Fatalf("Expected a 2-chain, got %d", result.Chain)
+	}
+	if result.Score != 400 || result.Links[0].Score != 100 || result.Links[1].Score != 300 {
+		t.Errorf("Expected 100 + 300 points, got %+v", result.Links)
+	}
+	if result.Cleared != 8 || !result.AllClear || !result.Field.IsEmpty() {
+		t.Errorf("Expected all 8 puyos cleared, got %d (all clear %v)", result.Cleared, result.AllClear)
+	}
+
+	first := result.Links[0]
+	want := ChainGroup{Color: Red, Positions: []Position{{1, 11}, {2, 11}, {3, 11}, {4, 11}}}
+	if len(first.Groups) != 1 || !reflect.DeepEqual(first.Groups[0], want) {
+		t.Errorf("Expected the red group first, got %+v", first.Groups)
+	}
+	if first.Colors != 1 || first.Puyos != 4 {
+		t.Errorf("Expected 4 puyos of 1 color, got %d of %d", first.Puyos, first.Colors)
+	}
+	if result.Links[1].Groups[0].Color != Green {
+		t.Error("Expected the green group second")
+	}
+}
+
+func TestSimulateChainNuisanceAndGravity(t *testing.T) {
+	// The top reds float until the field settles
+	field, _, _ := ParseFieldNotation(`
+RR....
+......
+RRN...
+NNNB..
+`, FieldWidth, FieldHeight)
+
+	result := SimulateChain(field, DefaultRules())
+
+	// The reds land on the nuisance and clear three of it
+	if result.Chain != 1 || result.Links[0].Nuisance != 3 || result.Cleared != 7 {
+		t.Fatalf("Expected 4 reds and 3 nuisance cleared, got %+v", result.Links)
+	}
+	if result.AllClear {
+		t.Error("Expected puyos to be left")
+	}
+	want, _, _ := ParseFieldNotation("..NB..", FieldWidth, FieldHeight)
+	if result.Field.Notation() != want.Notation() {
+		t.Errorf("Unexpected field after the chain:\n%s", result.Field.Notation())
+	}
+}
+
+func TestSimulateChainWithoutPops(t *testing.T) {
+	field, _, _ := ParseFieldNotation(`
+R.....
+......
+GGG...
+`, FieldWidth, FieldHeight)
+
+	result := SimulateChain(field, DefaultRules())
+
+	if result.Chain != 0 || result.Score != 0 || len(result.Links) != 0 || result.AllClear {
+		t.Errorf("Expected no chain, got %+v", result.ChainSummary)
+	}
+	want, _, _ := ParseFieldNotation("R.....\nGGG...", FieldWidth, FieldHeight)
+	if result.Field.Notation() != want.Notation() {
+		t.Errorf("Expected the settled field, got:\n%s", result.Field.Notation())
+	}
+}
+
+// TestSimulateChainMatchesGame resolves random fields with both the
+// simulation and the game and compares every popped group, score and the
+// final field
+func TestSimulateChainMatchesGame(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for _, rules := range []Rules{DefaultRules(), RulesByName("tsu"), RulesByName("casual")} {
+		rules.AllClearBonus = 0
+		for i := 0; i < 200; i++ {
+			field := randomField(r, rules.Width, rules.Height, rules.Colors)
+			result := SimulateChain(field, rules)
+
+			game := NewGameWithRules(rules, 1)
+			game.Field = field.Clone()
+			events := recordEvents(game)
+			runChain(game)
+
+			if game.Score != result.Score || game.MaxChain != result.Chain {
+				t.Fatalf("%s: game scored %d in %d links, simulation %d in %d\n%s",
+					rules.Scoring, game.Score, game.MaxChain, result.Score, result.Chain, field.Notation())
+			}
+			if game.Field.Notation() != result.Field.Notation() {
+				t.Fatalf("Final fields differ:\n%s\nvs\n%s", game.Field.Notation(), result.Field.Notation())
+			}
+
+			var groups []GroupPopped
+			for _, ev := range *events {
+				if g, ok := ev.(GroupPopped); ok {
+					groups = append(groups, g)
+				}
+			}
+			n := 0
+			for link, l := range result.Links {
+				for _, group := range l.Groups {
+					if n >= len(groups) || groups[n].Chain != link+1 || groups[n].Color != group.Color ||
+						!reflect.DeepEqual(groups[n].Positions, group.Positions) {
+						t.Fatalf("Group %d of link %d differs from the game", n, link+1)
+					}
+					n++
+				}
+			}
+			if n != len(groups) {
+				t.Fatalf("Game popped %d groups, simulation %d", len(groups), n)
+			}
+		}
+	}
+}
+
+func TestChainSimulatorReuse(t *testing.T) {
+	r := rand.New(rand.NewSource(2))
+	s := NewChainSimulator(DefaultRules())
+	for i := 0; i < 50; i++ {
+		field := randomField(r, FieldWidth, FieldHeight, 4)
+		if got, want := s.Resolve(field), SimulateChain(field, DefaultRules()); got != want.ChainSummary {
+			t.Fatalf("Resolve gave %+v, SimulateChain %+v", got, want.ChainSummary)
+		}
+		if s.Field().Notation() != SimulateChain(field, DefaultRules()).Field.Notation() {
+			t.Fatal("Expected the simulator's field to match")
+		}
+	}
+}
+
+func TestChainSimulatorDoesNotAllocate(t *testing.T) {
+	field := randomField(rand.New(rand.NewSource(3)), FieldWidth, FieldHeight, 4)
+	s := NewChainSimulator(DefaultRules())
+	s.Resolve(field)
+
+	if allocs := testing.AllocsPerRun(100, func() { s.Resolve(field) }); allocs != 0 {
+		t.Errorf("Expected no allocations, got %v per call", allocs)
+	}
+}
+
+func BenchmarkChainSimulator(b *testing.B) {
+	fields := chainBenchFields()
+	s := NewChainSimulator(DefaultRules())
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		s.Resolve(fields[i%len(fields)])
+	}
+}
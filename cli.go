@@ -0,0 +1,414 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// Command line
+//
+// Without arguments puyo shows the main menu. Subcommands start a game
+// directly or print records without opening the full-screen UI, so scripts
+// can launch a specific setup and check the exit code:
+//
+//	puyo play [--colors N] [--seed N] [--mode NAME] [--level N]
+//	puyo puzzle [MISSION]
+//	puyo scores | stats [--player NAME] | field [--mode NAME] [FILE]
+//	puyo replay | arena | version | help
+
+// Exit codes
+const (
+	ExitOK          = 0 // Finished normally
+	ExitError       = 1 // Something failed at run time, such as the terminal
+	ExitUsage       = 2 // Unknown command, bad flag or unreadable input
+	ExitUnavailable = 3 // The command is not available in this version
+	ExitNotCleared  = 4 // A mission or timed run ended without reaching its goal
+)
+
+// version is the program version, set at build time with
+// -ldflags "-X main.version=1.2.3"
+var version = "dev"
+
+// modeAliases maps the short mode names of the command line to rules presets
+var modeAliases = map[string]string{
+	"endless": "standard",
+	"sprint":  "sprint-chain",
+}
+
+// cli runs one command with its output streams
+type cli struct {
+	settings       *Settings // Loaded by the commands that need them
+	stdout, stderr io.Writer
+}
+
+// load loads the settings, the language and the user themes and missions
+// once. version, help and the unavailable commands never call it, so they
+// do not touch ~/.puyo.
+func (c *cli) load() *Settings {
+	if c.settings == nil {
+		c.settings = loadSettings()
+	}
+	return c.settings
+}
+
+// cliCommand is a subcommand of the command line
+type cliCommand struct {
+	name    string
+	summary string
+	run     func(c *cli, args []string) int
+}
+
+// cliCommands lists the subcommands in help order
+func cliCommands() []cliCommand {
+	return []cliCommand{
+		{"play", "Start a game, skipping the menu when flags are given", (*cli).play},
+		{"puzzle", "List the missions, or start the one given", (*cli).puzzle},
+		{"scores", "Print the high score and leaderboards", (*cli).scores},
+		{"stats", "Print a player's lifetime statistics", (*cli).stats},
+		{"field", "Resolve a field in field notation and print the chain", (*cli).field},
+		{"replay", "Play back a saved replay (not available yet)", (*cli).replay},
+		{"arena", "Play against a computer opponent (not available yet)", (*cli).arena},
+		{"version", "Print the version", (*cli).version},
+		{"help", "Print this help", (*cli).help},
+	}
+}
+
+// runCLI runs the command line and returns the exit code
+func runCLI(args []string, stdout, stderr io.Writer) int {
+	c := &cli{stdout: stdout, stderr: stderr}
+
+	if len(args) == 0 {
+		return playFromMenu(c.load())
+	}
+	name := args[0]
+	switch {
+	case name == "-h" || name == "-help" || name == "--help":
+		return c.help(nil)
+	case name == "-version" || name == "--version":
+		return c.version(nil)
+	case strings.HasPrefix(name, "-"):
+		// Flags without a command are play flags
+		return c.play(args)
+	}
+
+	for _, cmd := range cliCommands() {
+		if cmd.name == name {
+			return cmd.run(c, args[1:])
+		}
+	}
+	fmt.Fprintf(c.stderr, "puyo: unknown command %q\n", name)
+	c.usage(c.stderr)
+	return ExitUsage
+}
+
+// usage prints the list of commands
+func (c *cli) usage(w io.Writer) {
+	fmt.Fprintln(w, "Usage: puyo [command] [flags]")
+	fmt.Fprintln(w, "\nWithout a command, the main menu is shown.\n\nCommands:")
+	for _, cmd := range cliCommands() {
+		fmt.Fprintf(w, "  %-8s %s\n", cmd.name, cmd.summary)
+	}
+	fmt.Fprintln(w, "\nRun \"puyo <command> -h\" for the flags of a command.")
+}
+
+// flagSet creates the flag set of a command, printing errors to stderr
+func (c *cli) flagSet(name, args string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(c.stderr)
+	fs.Usage = func() {
+		fmt.Fprintf(c.stderr, "Usage: puyo %s %s\n", name, args)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// parseFlags parses a command's flags
+// Returns false with the exit code if the command should stop: ExitOK for
+// -h, ExitUsage for bad flags or more than maxArgs arguments.
+func (c *cli) parseFlags(fs *flag.FlagSet, args []string, maxArgs int) (int, bool) {
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return ExitOK, false
+		}
+		return ExitUsage, false
+	}
+	if fs.NArg() > maxArgs {
+		fmt.Fprintf(c.stderr, "puyo %s: unexpected argument %q\n", fs.Name(), fs.Arg(maxArgs))
+		fs.Usage()
+		return ExitUsage, false
+	}
+	return ExitOK, true
+}
+
+// fail prints an error for a command and returns the exit code
+func (c *cli) fail(code int, command, format string, args ...any) int {
+	fmt.Fprintf(c.stderr, "puyo %s: %s\n", command, fmt.Sprintf(format, args...))
+	return code
+}
+
+// playOptions is a game chosen on the command line
+type playOptions struct {
+	Choice MenuResult
+	Seed   int64
+	Level  int  // Start level, 0 for the setting
+	Menu   bool // No flags were given, so the menu chooses the game
+}
+
+// rulesForMode returns the rules preset for a --mode value
+func rulesForMode(mode string) (string, error) {
+	if alias, ok := modeAliases[mode]; ok {
+		return alias, nil
+	}
+	if containsString(RulesNames, mode) {
+		return mode, nil
+	}
+	return "", fmt.Errorf("unknown mode %q (modes: endless, sprint, %s)", mode, strings.Join(RulesNames, ", "))
+}
+
+// parsePlay parses the flags of the play command
+// Missing flags fall back to the last selection in the settings.
+func (c *cli) parsePlay(args []string) (*playOptions, int, bool) {
+	fs := c.flagSet("play", "[flags]")
+	colors := fs.Int("colors", 0, "number of colors, 3 to 6 (default: last used)")
+	seed := fs.Int64("seed", 0, "seed for the piece sequence (default: random)")
+	mode := fs.String("mode", "", "endless, sprint or a rules preset (default: last used)")
+	level := fs.Int("level", 0, fmt.Sprintf("start level, 1 to %d (default: from settings)", MaxStartLevel))
+	if code, ok := c.parseFlags(fs, args, 0); !ok {
+		return nil, code, false
+	}
+
+	settings := c.load()
+	opts := &playOptions{
+		Choice: MenuResult{Action: MenuPlay, ColorCount: settings.ColorCount, Rules: settings.Rules},
+		Seed:   time.Now().UnixNano(),
+		Menu:   fs.NFlag() == 0,
+	}
+	set := map[string]bool{}
+	fs.Visit(func(f *flag.Flag) { set[f.Name] = true })
+
+	if set["mode"] {
+		name, err := rulesForMode(*mode)
+		if err != nil {
+			return nil, c.fail(ExitUsage, "play", "%v", err), false
+		}
+		opts.Choice.Rules = name
+	}
+	if set["colors"] {
+		opts.Choice.ColorCount = *colors
+	}
+	rules := RulesByName(opts.Choice.Rules)
+	rules.Colors = opts.Choice.ColorCount
+	if err := rules.Validate(); err != nil {
+		return nil, c.fail(ExitUsage, "play", "%v", err), false
+	}
+	if set["seed"] {
+		opts.Seed = *seed
+	}
+	if set["level"] {
+		if *level < 1 || *level > MaxStartLevel {
+			return nil, c.fail(ExitUsage, "play", "level must be 1 to %d, got %d", MaxStartLevel, *level), false
+		}
+		opts.Level = *level
+	}
+	return opts, ExitOK, true
+}
+
+// play starts a game
+func (c *cli) play(args []string) int {
+	opts, code, ok := c.parsePlay(args)
+	if !ok {
+		return code
+	}
+	if opts.Menu {
+		return playFromMenu(c.load())
+	}
+
+	// The start level applies to this run only, restarts included
+	settings := c.load()
+	if opts.Level > 0 {
+		copied := *settings
+		copied.StartLevel = opts.Level
+		settings = &copied
+	}
+	return playGame(settings, opts.Choice, opts.Seed)
+}
+
+// puzzle lists the missions, or plays the one given
+func (c *cli) puzzle(args []string) int {
+	fs := c.flagSet("puzzle", "[mission]")
+	if code, ok := c.parseFlags(fs, args, 1); !ok {
+		return code
+	}
+	c.load()
+
+	if fs.NArg() == 1 {
+		id := fs.Arg(0)
+		if MissionByID(id) == nil {
+			return c.fail(ExitUsage, "puzzle", "unknown mission %q", id)
+		}
+		return playGame(c.settings, MenuResult{Action: MenuPlay, Mission: id}, time.Now().UnixNano())
+	}
+
+	records, err := LoadMissionRecords()
+	if err != nil {
+		records = NewMissionRecords()
+	}
+	for _, m := range Missions() {
+		title := m.Title()
+		if rec := records.Missions[m.ID]; rec.Completed {
+			title = T("missions.cleared", title, formatFrames(rec.BestFrames))
+		}
+		fmt.Fprintf(c.stdout, "%-14s %s\n", m.ID, title)
+	}
+	return ExitOK
+}
+
+// scores prints the high score and the leaderboards of the timed modes
+func (c *cli) scores(args []string) int {
+	fs := c.flagSet("scores", "")
+	if code, ok := c.parseFlags(fs, args, 0); !ok {
+		return code
+	}
+	c.load()
+
+	highScore, err := LoadHighScore()
+	if err != nil {
+		return c.fail(ExitError, "scores", "%v", err)
+	}
+	boards, err := LoadLeaderboards()
+	if err != nil {
+		return c.fail(ExitError, "scores", "%v", err)
+	}
+
+	fmt.Fprintln(c.stdout, T("stats.high_score", highScore.Score, highScore.Level, highScore.Chains, highScore.AllClears))
+	for _, name := range []string{"sprint-chain", "sprint-score", "ultra"} {
+		rules := RulesByName(name)
+		fmt.Fprintf(c.stdout, "\n%s\n", T("rules."+name))
+
+		board := boards.Board(rules)
+		if len(board) == 0 {
+			fmt.Fprintf(c.stdout, "  %s\n", T("board.empty"))
+		}
+		for i, entry := range board {
+			if rules.Goal == GoalUltra {
+				fmt.Fprintf(c.stdout, "  %s\n", T("board.score_row", i+1, entry.Score, entry.Player))
+			} else {
+				fmt.Fprintf(c.stdout, "  %s\n", T("board.time_row", i+1, formatFrames(entry.Frames), entry.Player))
+			}
+		}
+	}
+	return ExitOK
+}
+
+// stats prints a player's lifetime statistics
+func (c *cli) stats(args []string) int {
+	fs := c.flagSet("stats", "[flags]")
+	player := fs.String("player", currentPlayerName(), "player to show")
+	if code, ok := c.parseFlags(fs, args, 0); !ok {
+		return code
+	}
+	c.load()
+
+	stats, err := LoadStats(*player)
+	if err != nil {
+		return c.fail(ExitError, "stats", "%v", err)
+	}
+	highScore, err := LoadHighScore()
+	if err != nil {
+		return c.fail(ExitError, "stats", "%v", err)
+	}
+
+	fmt.Fprintln(c.stdout, T("stats.title", *player))
+	for _, line := range statsLines(stats, highScore) {
+		fmt.Fprintf(c.stdout, "  %s\n", line)
+	}
+	fmt.Fprintln(c.stdout, T("stats.histogram"))
+	for _, length := range stats.chainLengths() {
+		fmt.Fprintf(c.stdout, "  %s\n", T("stats.histogram_row", length, stats.ChainHistogram[length]))
+	}
+	fmt.Fprintln(c.stdout, T("stats.popped"))
+	for color := Red; color <= MaxColor; color++ {
+		fmt.Fprintf(c.stdout, "  %s\n", T("stats.popped_row", T("color."+color.Name()), stats.PuyosPopped[color.Name()]))
+	}
+	return ExitOK
+}
+
+// field resolves a field read from a file or stdin and prints the chain
+// Lowercase cells are the trigger: they are placed before the field resolves.
+func (c *cli) field(args []string) int {
+	fs := c.flagSet("field", "[flags] [file]")
+	mode := fs.String("mode", "standard", "rules preset for the field size and scoring")
+	if code, ok := c.parseFlags(fs, args, 1); !ok {
+		return code
+	}
+	c.load()
+
+	name, err := rulesForMode(*mode)
+	if err != nil {
+		return c.fail(ExitUsage, "field", "%v", err)
+	}
+	rules := RulesByName(name)
+
+	var data []byte
+	if path := fs.Arg(0); path != "" && path != "-" {
+		data, err = os.ReadFile(path)
+	} else {
+		data, err = io.ReadAll(os.Stdin)
+	}
+	if err != nil {
+		return c.fail(ExitUsage, "field", "%v", err)
+	}
+
+	f, triggers, err := ParseFieldNotation(string(data), rules.Width, rules.Height)
+	if err != nil {
+		return c.fail(ExitUsage, "field", "%v", err)
+	}
+	for _, cell := range triggers {
+		f.Grid[cell.Pos.Y][cell.Pos.X] = cell.Color
+	}
+
+	result := SimulateChain(f, rules)
+	for i, link := range result.Links {
+		fmt.Fprintln(c.stdout, T("field.link", i+1, link.Puyos, link.Colors, link.Score))
+	}
+	fmt.Fprintln(c.stdout, T("field.result", result.Chain, result.Score, result.Cleared))
+	if result.AllClear {
+		fmt.Fprintln(c.stdout, T("game.all_clear"))
+		return ExitOK
+	}
+	if result.Chain > 0 {
+		fmt.Fprintln(c.stdout, T("field.after"))
+		fmt.Fprint(c.stdout, result.Field.Notation())
+	}
+	p := ChainPotential(result.Field, rules, MaxKeyPuyos)
+	fmt.Fprintln(c.stdout, T("field.potential", potentialText(reportPotential(p))))
+	return ExitOK
+}
+
+// replay would play back a saved replay; games are not recorded yet
+func (c *cli) replay(args []string) int {
+	return c.fail(ExitUnavailable, "replay", "replays are not recorded in this version")
+}
+
+// arena would start a game against a computer opponent, which does not exist yet
+func (c *cli) arena(args []string) int {
+	return c.fail(ExitUnavailable, "arena", "there is no computer opponent in this version")
+}
+
+// version prints the program and Go versions
+func (c *cli) version(args []string) int {
+	fmt.Fprintf(c.stdout, "puyo %s (%s)\n", version, runtime.Version())
+	return ExitOK
+}
+
+// help prints the list of commands
+func (c *cli) help(args []string) int {
+	c.usage(c.stdout)
+	return ExitOK
+}
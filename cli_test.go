@@ -0,0 +1,178 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// runTestCLI runs the command line with a fresh home directory in English
+func runTestCLI(t *testing.T, args ...string) (int, string, string) {
+	t.Helper()
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("LC_ALL", "en_US.UTF-8")
+	var stdout, stderr bytes.Buffer
+	code := runCLI(args, &stdout, &stderr)
+	return code, stdout.String(), stderr.String()
+}
+
+// testCLI returns a command line with the default settings
+func testCLI() (*cli, *bytes.Buffer) {
+	var stderr bytes.Buffer
+	return &cli{settings: DefaultSettings(), stdout: &bytes.Buffer{}, stderr: &stderr}, &stderr
+}
+
+func TestCLIVersion(t *testing.T) {
+	code, out, _ := runTestCLI(t, "version")
+	if code != ExitOK || !strings.HasPrefix(out, "puyo "+version) {
+		t.Errorf("Expected the version, got %d %q", code, out)
+	}
+}
+
+func TestCLIHelp(t *testing.T) {
+	code, out, _ := runTestCLI(t, "--help")
+	if code != ExitOK {
+		t.Errorf("Expected help to exit %d, got %d", ExitOK, code)
+	}
+	for _, cmd := range cliCommands() {
+		if !strings.Contains(out, "  "+cmd.name+" ") {
+			t.Errorf("Expected %s in the help:\n%s", cmd.name, out)
+		}
+	}
+}
+
+func TestCLIWithoutSettings(t *testing.T) {
+	for _, args := range [][]string{{"version"}, {"help"}, {"--version"}, {"bogus"}, {"replay"}, {"arena"}} {
+		home := t.TempDir()
+		t.Setenv("HOME", home)
+		var stdout, stderr bytes.Buffer
+		runCLI(args, &stdout, &stderr)
+
+		if _, err := os.Stat(filepath.Join(home, ".puyo")); !os.IsNotExist(err) {
+			t.Errorf("%v: expected ~/.puyo not to be created, got %v", args, err)
+		}
+	}
+}
+
+func TestCLIExitCodes(t *testing.T) {
+	tests := []struct {
+		args []string
+		code int
+	}{
+		{[]string{"bogus"}, ExitUsage},
+		{[]string{"play", "--colors", "9"}, ExitUsage},
+		{[]string{"play", "--mode", "nope"}, ExitUsage},
+		{[]string{"play", "--level", "0"}, ExitUsage},
+		{[]string{"play", "--seed", "x"}, ExitUsage},
+		{[]string{"play", "extra"}, ExitUsage},
+		{[]string{"--colors", "2"}, ExitUsage},
+		{[]string{"play", "-h"}, ExitOK},
+		{[]string{"puzzle", "no-such-mission"}, ExitUsage},
+		{[]string{"field", "no-such-file"}, ExitUsage},
+		{[]string{"replay"}, ExitUnavailable},
+		{[]string{"arena"}, ExitUnavailable},
+	}
+	for _, tt := range tests {
+		code, _, stderr := runTestCLI(t, tt.args...)
+		if code != tt.code {
+			t.Errorf("%v: expected exit code %d, got %d (%s)", tt.args, tt.code, code, stderr)
+		}
+	}
+}
+
+func TestParsePlay(t *testing.T) {
+	c, _ := testCLI()
+
+	opts, _, ok := c.parsePlay([]string{"--colors", "5", "--seed", "42", "--mode", "sprint", "--level", "3"})
+	if !ok {
+		t.Fatal("Expected the flags to parse")
+	}
+	if opts.Menu || opts.Seed != 42 || opts.Level != 3 {
+		t.Errorf("Expected seed 42 at level 3 without the menu, got %+v", opts)
+	}
+	if opts.Choice.ColorCount != 5 || opts.Choice.Rules != "sprint-chain" {
+		t.Errorf("Expected 5 colors of sprint-chain, got %+v", opts.Choice)
+	}
+
+	// Without flags the menu chooses
+	opts, _, ok = c.parsePlay(nil)
+	if !ok || !opts.Menu {
+		t.Errorf("Expected the menu without flags, got %+v", opts)
+	}
+
+	// Missing flags come from the settings
+	c.settings.Rules = "wide"
+	opts, _, _ = c.parsePlay([]string{"--colors", "3"})
+	if opts.Choice.Rules != "wide" || opts.Choice.ColorCount != 3 {
+		t.Errorf("Expected 3 colors of the last rules, got %+v", opts.Choice)
+	}
+}
+
+func TestRulesForMode(t *testing.T) {
+	for mode, want := range map[string]string{"endless": "standard", "sprint": "sprint-chain", "ultra": "ultra"} {
+		if got, err := rulesForMode(mode); err != nil || got != want {
+			t.Errorf("%s: expected %s, got %s (%v)", mode, want, got, err)
+		}
+	}
+	if _, err := rulesForMode("arcade"); err == nil {
+		t.Error("Expected an unknown mode to fail")
+	}
+}
+
+func TestCLIField(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "field.txt")
+	if err := os.WriteFile(path, []byte(almostTwoChain+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	code, out, stderr := runTestCLI(t, "field", path)
+	if code != ExitOK {
+		t.Fatalf("Expected exit code %d, got %d (%s)", ExitOK, code, stderr)
+	}
+	for _, want := range []string{"0-chain", "Chain potential: 2-chain (1×R in column 5)"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected %q in:\n%s", want, out)
+		}
+	}
+
+	// Lowercase cells are placed before resolving
+	if err := os.WriteFile(path, []byte(".GGG..\nGRRRr.\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	_, out, _ = runTestCLI(t, "field", path)
+	for _, want := range []string{"Link 2: 4 puyos, 1 colors, 300 points", "2-chain, 400 points, 8 puyos cleared", "ALL CLEAR!"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected %q in:\n%s", want, out)
+		}
+	}
+}
+
+func TestCLIRecords(t *testing.T) {
+	code, out, _ := runTestCLI(t, "scores")
+	if code != ExitOK || !strings.Contains(out, "No runs yet") {
+		t.Errorf("Expected empty leaderboards, got %d:\n%s", code, out)
+	}
+
+	code, out, _ = runTestCLI(t, "stats", "--player", "tester")
+	if code != ExitOK || !strings.Contains(out, "Stats - tester") || !strings.Contains(out, "Games played: 0") {
+		t.Errorf("Expected empty stats, got %d:\n%s", code, out)
+	}
+
+	code, out, _ = runTestCLI(t, "puzzle")
+	if code != ExitOK || !strings.Contains(out, "green-8") {
+		t.Errorf("Expected the mission list, got %d:\n%s", code, out)
+	}
+}
+
+func TestClearedExitCode(t *testing.T) {
+	game := NewGameWithRules(RulesByName("sprint-chain"), 1)
+	if clearedExitCode(game) != ExitNotCleared {
+		t.Error("Expected an uncleared run to exit with ExitNotCleared")
+	}
+	game.Cleared = true
+	if clearedExitCode(game) != ExitOK {
+		t.Error("Expected a cleared run to exit with ExitOK")
+	}
+}
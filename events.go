@@ -0,0 +1,163 @@
+package main
+
+// Event is something that happened in a game
+// Events are delivered synchronously, in the order they happen, to every
+// subscriber of the game's EventBus.
+type Event interface {
+	event()
+}
+
+// PairSpawned is sent when a new pair appears at the top of the field
+type PairSpawned struct {
+	Pair PuyoPair
+	Next PuyoPair
+}
+
+// PairMoved is sent when the pair moves sideways or down
+type PairMoved struct {
+	From, To Position // Piece position, as in PuyoPair.Pos
+}
+
+// PairRotated is sent when the pair rotates, or a big puyo changes color
+type PairRotated struct {
+	Rotate int      // New rotation, as in PuyoPair.Rotate
+	Pos    Position // Piece position after any wall or floor kick
+}
+
+// PairLocked is sent when the pair is placed into the field
+type PairLocked struct {
+	Main, Sub           Position
+	MainColor, SubColor Color
+	Cells               []PieceCell // Every cell of the piece, main and sub first
+}
+
+// GroupPopped is sent for every group removed in a chain step
+type GroupPopped struct {
+	Chain     int // Chain step the group popped in, starting at 1
+	Color     Color
+	Size      int
+	Positions []Position
+}
+
+// ChainStep is sent when a chain step starts popping
+type ChainStep struct {
+	Chain int // Chain number, starting at 1
+	Score int // Points this step adds to the chain
+}
+
+// AllClear is sent when a chain leaves the field empty
+type AllClear struct {
+	Bonus int // Points awarded for the all clear
+}
+
+// FeverStarted is sent when the fever gauge fills and the first pattern is dealt
+type FeverStarted struct {
+	Chain int // Links of the first pattern
+}
+
+// FeverEnded is sent when the fever timer runs out and the player's field returns
+type FeverEnded struct{}
+
+// GarbageQueued is sent when garbage is added to the queue above the field
+type GarbageQueued struct {
+	Count   int // Garbage puyos added
+	Pending int // Garbage puyos now waiting
+}
+
+// GarbageSent is sent for every chain step worth at least one nuisance puyo
+type GarbageSent struct {
+	Count  int // Nuisance puyos sent to the opponent
+	Offset int // Pending garbage cancelled before sending
+}
+
+// GarbageDropped is sent when queued garbage falls onto the field
+type GarbageDropped struct {
+	Count int
+}
+
+// GoalReached is sent when a timed run reaches its goal, just before GameOver
+type GoalReached struct {
+	Frames int // Time taken
+	Score  int
+}
+
+// ObjectiveDone is sent when a mission objective is completed
+type ObjectiveDone struct {
+	Step int // Objectives completed so far
+}
+
+// LevelUp is sent when the level increases
+type LevelUp struct {
+	Level int
+}
+
+// GameOver is sent when the game ends
+type GameOver struct {
+	Score int
+}
+
+func (PairSpawned) event()    {}
+func (PairMoved) event()      {}
+func (PairRotated) event()    {}
+func (PairLocked) event()     {}
+func (GroupPopped) event()    {}
+func (ChainStep) event()      {}
+func (AllClear) event()       {}
+func (FeverStarted) event()   {}
+func (FeverEnded) event()     {}
+func (GarbageQueued) event()  {}
+func (GarbageSent) event()    {}
+func (GarbageDropped) event() {}
+func (GoalReached) event()    {}
+func (ObjectiveDone) event()  {}
+func (LevelUp) event()        {}
+func (GameOver) event()       {}
+
+// EventBus delivers game events to any number of subscribers
+type EventBus struct {
+	subscribers []subscriber
+	nextID      int
+}
+
+// subscriber is a registered event handler
+type subscriber struct {
+	id      int
+	handler func(Event)
+}
+
+// NewEventBus creates an event bus without subscribers
+func NewEventBus() *EventBus {
+	return &EventBus{}
+}
+
+// Subscribe registers a handler for every event
+// Handlers are called in the order they subscribed. The returned function
+// removes the handler.
+func (b *EventBus) Subscribe(handler func(Event)) func() {
+	b.nextID++
+	id := b.nextID
+	b.subscribers = append(b.subscribers, subscriber{id: id, handler: handler})
+
+	return func() {
+		for i, s := range b.subscribers {
+			if s.id == id {
+				b.subscribers = append(b.subscribers[:i:i], b.subscribers[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+// Publish sends an event to every subscriber
+func (b *EventBus) Publish(ev Event) {
+	for _, s := range b.subscribers {
+		s.handler(ev)
+	}
+}
+
+// emit publishes an event on the game's event bus
+func (g *Game) emit(ev Event) {
+	if g.Events != nil {
+		g.Events.Publish(ev)
+	}
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+// recordEvents subscribes to a game and returns the events it receives
+func recordEvents(game *Game) *[]Event {
+	var events []Event
+	game.Events.Subscribe(func(ev Event) {
+		events = append(events, ev)
+	})
+	return &events
+}
+
+func TestEventBusSubscribers(t *testing.T) {
+	bus := NewEventBus()
+
+	var order []string
+	unsubscribeA := bus.Subscribe(func(Event) { order = append(order, "a") })
+	bus.Subscribe(func(Event) { order = append(order, "b") })
+
+	bus.Publish(AllClear{})
+	if len(order) != 2 || order[0] != "a" || order[1] != "b" {
+		t.Errorf("Expected both subscribers in order, got %v", order)
+	}
+
+	order = nil
+	unsubscribeA()
+	unsubscribeA() // Unsubscribing twice is harmless
+	bus.Publish(AllClear{})
+	if len(order) != 1 || order[0] != "b" {
+		t.Errorf("Expected only the remaining subscriber, got %v", order)
+	}
+}
+
+func TestEventBusUnsubscribeDuringPublish(t *testing.T) {
+	bus := NewEventBus()
+
+	calls := 0
+	var unsubscribe func()
+	unsubscribe = bus.Subscribe(func(Event) {
+		calls++
+		unsubscribe()
+	})
+	bus.Subscribe(func(Event) { calls++ })
+
+	bus.Publish(AllClear{})
+	bus.Publish(AllClear{})
+	if calls != 3 {
+		t.Errorf("Expected 3 calls, got %d", calls)
+	}
+}
+
+func TestGameEventsPairLifecycle(t *testing.T) {
+	game := NewGameWithSeed(4, 1)
+	events := recordEvents(game)
+
+	start := game.Current.Pos
+	game.Move(1, 0, 0)
+	game.Move(0, 0, 1)
+	game.HardDrop()
+	game.LockPair()
+	for game.ProcessChainStep() {
+	}
+
+	var moved, rotated, locked, spawned int
+	for _, ev := range *events {
+		switch ev := ev.(type) {
+		case PairMoved:
+			if moved == 0 && (ev.From != start || ev.To != (Position{start.X + 1, start.Y})) {
+				t.Errorf("Unexpected first move %+v", ev)
+			}
+			moved++
+		case PairRotated:
+			if ev.Rotate != 1 {
+				t.Errorf("Expected rotation 1, got %d", ev.Rotate)
+			}
+			rotated++
+		case PairLocked:
+			if game.Field.Grid[ev.Main.Y][ev.Main.X] != ev.MainColor {
+				t.Errorf("Locked main puyo not found at %v", ev.Main)
+			}
+			locked++
+		case PairSpawned:
+			if !reflect.DeepEqual(ev.Pair, *game.Current) {
+				t.Errorf("Spawned pair %+v does not match current pair %+v", ev.Pair, *game.Current)
+			}
+			spawned++
+		}
+	}
+
+	if moved < 2 || rotated != 1 || locked != 1 || spawned != 1 {
+		t.Errorf("Unexpected event counts: moved=%d rotated=%d locked=%d spawned=%d", moved, rotated, locked, spawned)
+	}
+}
+
+func TestNewGameOnBusSeesFirstPair(t *testing.T) {
+	old := NewGameWithSeed(4, 1)
+	events := recordEvents(old)
+
+	// A restarted game keeps the bus and spawns once it is set up
+	game := newGame(old.Rules, 2, old.Events)
+	if len(*events) != 0 {
+		t.Fatalf("Expected no events before start, got %v", *events)
+	}
+	game.start()
+
+	if len(*events) != 1 {
+		t.Fatalf("Expected one event, got %v", *events)
+	}
+	if ev, ok := (*events)[0].(PairSpawned); !ok || !reflect.DeepEqual(ev.Pair, *game.Current) {
+		t.Errorf("Expected the first pair of the new game, got %+v", (*events)[0])
+	}
+}
+
+func TestGameEventsChain(t *testing.T) {
+	game := NewGameWithSeed(4, 1)
+	game.Current = nil
+	events := recordEvents(game)
+
+	// A two-step chain that clears the field
+	for y := FieldHeight - 4; y < FieldHeight; y++ {
+		game.Field.Grid[y][0] = Blue
+	}
+	game.Field.Grid[FieldHeight-1][1] = Red
+	game.Field.Grid[FieldHeight-2][1] = Red
+	game.Field.Grid[FieldHeight-1][2] = Red
+	game.Field.Grid[FieldHeight-5][0] = Red
+	game.State = StateDropping
+
+	for game.ProcessChainStep() {
+	}
+
+	var steps []ChainStep
+	var groups []GroupPopped
+	allClear := false
+	for _, ev := range *events {
+		switch ev := ev.(type) {
+		case ChainStep:
+			steps = append(steps, ev)
+		case GroupPopped:
+			groups = append(groups, ev)
+		case AllClear:
+			allClear = true
+		}
+	}
+
+	if len(steps) != 2 || steps[0].Chain != 1 || steps[1].Chain != 2 {
+		t.Fatalf("Expected chain steps 1 and 2, got %+v", steps)
+	}
+	if steps[0].Score+steps[1].Score != chainScore(2) {
+		t.Errorf("Step scores %d + %d should add up to %d", steps[0].Score, steps[1].Score, chainScore(2))
+	}
+
+	if len(groups) != 2 {
+		t.Fatalf("Expected 2 popped groups, got %+v", groups)
+	}
+	if groups[0].Color != Blue || groups[0].Size != 4 || groups[0].Chain != 1 || len(groups[0].Positions) != 4 {
+		t.Errorf("Unexpected first group %+v", groups[0])
+	}
+	if groups[1].Color != Red || groups[1].Chain != 2 {
+		t.Errorf("Unexpected second group %+v", groups[1])
+	}
+	if groups[0].Positions[0] != (Position{0, FieldHeight - 4}) {
+		t.Errorf("Expected positions sorted from the top, got %v", groups[0].Positions)
+	}
+
+	if !allClear {
+		t.Error("Expected an all clear")
+	}
+}
+
+func TestGameEventsLevelUpAndGameOver(t *testing.T) {
+	game := NewGameWithSeed(4, 1)
+	events := recordEvents(game)
+
+	game.LinesCleared = 9
+	game.ChainCount = 1
+	game.calculateScore()
+
+	game.Field.Grid[0][FieldWidth/2] = Red
+	game.SpawnNewPair()
+
+	var levelUp *LevelUp
+	var over *GameOver
+	for _, ev := range *events {
+		switch ev := ev.(type) {
+		case LevelUp:
+			levelUp = &ev
+		case GameOver:
+			over = &ev
+		case PairSpawned:
+			t.Error("Expected no pair spawned on game over")
+		}
+	}
+
+	if levelUp == nil || levelUp.Level != 2 {
+		t.Errorf("Expected level up to 2, got %+v", levelUp)
+	}
+	if over == nil || over.Score != game.Score {
+		t.Errorf("Expected game over with score %d, got %+v", game.Score, over)
+	}
+}
@@ -0,0 +1,232 @@
+package main
+
+import (
+	_ "embed"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Fever mode timings and limits
+const (
+	FeverGaugeMax    = 7       // Offsets needed to start fever
+	FeverStartFrames = 15 * 60 // Time on the fever timer when fever starts
+	FeverMaxFrames   = 30 * 60 // The timer never holds more than this
+	FeverLinkFrames  = 30      // Time earned per link of a successful chain
+	FeverStartChain  = 4       // Chain length of the first fever pattern
+)
+
+// FeverPattern is a preset chain the player triggers during fever
+type FeverPattern struct {
+	Name    string
+	Chain   int         // Links the pattern fires when triggered
+	Field   *Field      // The pattern without its trigger
+	Trigger []PieceCell // Where the trigger puyos go
+}
+
+//go:embed fever_patterns.txt
+var feverPatternText string
+
+// feverPatterns is the bundled pattern library
+var feverPatterns = mustParseFeverPatterns(feverPatternText)
+
+// ParseFeverPatterns reads a pattern library
+// Each pattern starts with a "= <chain> <name>" line followed by the field
+// in field notation, with the trigger in lowercase. Lines starting with #
+// are comments. Patterns use only the four colors R, G, B and Y, which are
+// shuffled onto the game's palette when the pattern is dealt.
+func ParseFeverPatterns(text string, width, height int) ([]FeverPattern, error) {
+	var patterns []FeverPattern
+	var header string
+	var rows []string
+
+	flush := func() error {
+		if header == "" {
+			return nil
+		}
+		fields := strings.Fields(header)
+		if len(fields) != 2 {
+			return fmt.Errorf("pattern header %q: expected a chain length and a name", header)
+		}
+		chain, err := strconv.Atoi(fields[0])
+		if err != nil || chain < 1 {
+			return fmt.Errorf("pattern %q: bad chain length %q", fields[1], fields[0])
+		}
+		field, trigger, err := ParseFieldNotation(strings.Join(rows, "\n"), width, height)
+		if err != nil {
+			return fmt.Errorf("pattern %q: %v", fields[1], err)
+		}
+		if len(trigger) == 0 {
+			return fmt.Errorf("pattern %q has no trigger", fields[1])
+		}
+		if c, ok := feverPatternColors(field, trigger); !ok {
+			return fmt.Errorf("pattern %q: %q is not one of R, G, B and Y", fields[1], colorLetter(c))
+		}
+		patterns = append(patterns, FeverPattern{Name: fields[1], Chain: chain, Field: field, Trigger: trigger})
+		header, rows = "", nil
+		return nil
+	}
+
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		switch {
+		case line == "" || strings.HasPrefix(line, "#"):
+		case strings.HasPrefix(line, "="):
+			if err := flush(); err != nil {
+				return nil, err
+			}
+			header = strings.TrimSpace(strings.TrimPrefix(line, "="))
+		case header == "":
+			return nil, fmt.Errorf("field row %q before a pattern header", line)
+		default:
+			rows = append(rows, line)
+		}
+	}
+	if err := flush(); err != nil {
+		return nil, err
+	}
+	return patterns, nil
+}
+
+// mustParseFeverPatterns parses the bundled library, which is checked by the tests
+func mustParseFeverPatterns(text string) []FeverPattern {
+	patterns, err := ParseFeverPatterns(text, FieldWidth, FieldHeight)
+	if err != nil {
+		panic(err)
+	}
+	return patterns
+}
+
+// feverChainRange returns the shortest and longest patterns in the library
+func feverChainRange() (int, int) {
+	lo, hi := 0, 0
+	for _, p := range feverPatterns {
+		if lo == 0 || p.Chain < lo {
+			lo = p.Chain
+		}
+		if p.Chain > hi {
+			hi = p.Chain
+		}
+	}
+	return lo, hi
+}
+
+// chargeFever adds offsets to the fever gauge, starting fever when it is full
+func (g *Game) chargeFever(n int) {
+	if !g.Rules.Fever || g.FeverActive {
+		return
+	}
+	g.FeverGauge += n
+	if g.FeverGauge >= FeverGaugeMax {
+		g.FeverGauge = FeverGaugeMax
+		g.startFever()
+	}
+}
+
+// startFever puts the player's field aside and deals the first pattern
+func (g *Game) startFever() {
+	g.NormalField = g.Field
+	g.FeverActive = true
+	g.FeverFrames = FeverStartFrames
+	if g.FeverChain == 0 {
+		g.FeverChain = FeverStartChain
+	}
+	g.loadFeverPattern()
+	g.emit(FeverStarted{Chain: g.FeverChain})
+}
+
+// endFever brings back the player's field and empties the gauge
+func (g *Game) endFever() {
+	if !g.FeverActive {
+		return
+	}
+	g.Field = g.NormalField
+	g.NormalField = nil
+	g.FeverActive = false
+	g.FeverFrames = 0
+	g.FeverGauge = 0
+	g.emit(FeverEnded{})
+}
+
+// feverChainEnded scores a chain fired during fever and deals the next pattern
+// A chain at least as long as the pattern earns time and a longer pattern;
+// a shorter one makes the next pattern shorter.
+func (g *Game) feverChainEnded(chain int) {
+	lo, hi := feverChainRange()
+	if chain >= g.FeverChain {
+		g.FeverFrames = min(g.FeverFrames+chain*FeverLinkFrames, FeverMaxFrames)
+		g.FeverChain = min(g.FeverChain+1, hi)
+	} else {
+		g.FeverChain = max(g.FeverChain-1, lo)
+	}
+	g.loadFeverPattern()
+}
+
+// feverPatternColors checks that a pattern uses only the first four colors
+// Returns the first other color found and false.
+func feverPatternColors(field *Field, trigger []PieceCell) (Color, bool) {
+	for _, row := range field.Grid {
+		for _, c := range row {
+			if c > Yellow {
+				return c, false
+			}
+		}
+	}
+	for _, cell := range trigger {
+		if cell.Color > Yellow {
+			return cell.Color, false
+		}
+	}
+	return Empty, true
+}
+
+// loadFeverPattern replaces the field with a pattern of FeverChain links
+// The pattern's colors are shuffled onto the game's palette.
+func (g *Game) loadFeverPattern() {
+	var choices []FeverPattern
+	for _, p := range feverPatterns {
+		if p.Chain == g.FeverChain {
+			choices = append(choices, p)
+		}
+	}
+	if len(choices) == 0 {
+		return
+	}
+	pattern := choices[g.rand.Intn(len(choices))]
+
+	palette := g.Rules.palette()
+	perm := g.rand.Perm(len(palette))
+	field := pattern.Field.Clone()
+	for y := range field.Grid {
+		for x, c := range field.Grid[y] {
+			if c != Empty {
+				field.Grid[y][x] = palette[perm[int(c-Red)]]
+			}
+		}
+	}
+	g.Field = field
+}
+
+// TickFever counts down the fever timer by one frame of play
+// Returns true when the timer display changes.
+func (g *Game) TickFever() bool {
+	if !g.FeverActive {
+		return false
+	}
+	g.FeverFrames--
+	if g.FeverFrames <= 0 {
+		g.endFever()
+
+		// The falling piece may overlap the restored field
+		if g.Current != nil && !g.CanMove(0, 0, 0) {
+			g.Current.Pos = Position{X: g.Rules.SpawnColumn, Y: 0}
+			g.Current.Rotate = 0
+			if !g.CanMove(0, 0, 0) {
+				g.GameOver = true
+				g.emit(GameOver{Score: g.Score})
+			}
+		}
+		return true
+	}
+	return g.FeverFrames%6 == 0
+}
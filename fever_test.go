@@ -0,0 +1,297 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+// feverGame returns a game with the fever rules
+func feverGame(seed int64) *Game {
+	return NewGameWithRules(RulesByName("fever"), seed)
+}
+
+func TestFeverPatternsFireTheirChain(t *testing.T) {
+	if len(feverPatterns) == 0 {
+		t.Fatal("Expected a bundled pattern library")
+	}
+
+	for _, p := range feverPatterns {
+		game := NewGameWithSeed(4, 1)
+		game.Field = p.Field.Clone()
+
+		// The pattern must be stable before it is triggered
+		if game.hasClearablePuyos() || game.Field.hasFloating() {
+			t.Errorf("%s: pattern pops or floats before the trigger", p.Name)
+			continue
+		}
+		if !game.Field.IsValidPosition(FieldWidth/2, 0) {
+			t.Errorf("%s: pattern blocks the spawn column", p.Name)
+		}
+
+		for _, cell := range p.Trigger {
+			game.Field.Grid[cell.Pos.Y][cell.Pos.X] = cell.Color
+		}
+		runChain(game)
+
+		if game.MaxChain != p.Chain {
+			t.Errorf("%s: expected a %d chain, got %d", p.Name, p.Chain, game.MaxChain)
+		}
+	}
+}
+
+func TestFeverLibraryCoversEveryLength(t *testing.T) {
+	lo, hi := feverChainRange()
+	if lo > FeverStartChain || hi < FeverStartChain {
+		t.Fatalf("Library %d-%d does not include the first pattern length %d", lo, hi, FeverStartChain)
+	}
+
+	have := make(map[int]bool)
+	for _, p := range feverPatterns {
+		have[p.Chain] = true
+	}
+	for n := lo; n <= hi; n++ {
+		if !have[n] {
+			t.Errorf("No %d-chain pattern", n)
+		}
+	}
+}
+
+func TestParseFeverPatterns(t *testing.T) {
+	text := `
+# A comment
+= 1 single
+rrRR..
+`
+	patterns, err := ParseFeverPatterns(text, 6, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(patterns) != 1 || patterns[0].Name != "single" || patterns[0].Chain != 1 || len(patterns[0].Trigger) != 2 {
+		t.Fatalf("Unexpected patterns %+v", patterns)
+	}
+
+	bad := []string{
+		"RRRR..",           // Row before a header
+		"= x name\nRR....", // Bad chain length
+		"= 2\nRR....",      // Missing name
+		"= 2 name\nRRRR..", // No trigger
+		"= 2 name\nRRRR",   // Wrong width
+		"= 2 name\nRRXr..", // Unknown cell
+		"= 2 name\nPPPr..", // Purple is not a pattern color
+		"= 2 name\nRRRo..", // Nor is an orange trigger
+		"= 2 name\nNRRr..", // Nor nuisance
+	}
+	for _, text := range bad {
+		if _, err := ParseFeverPatterns(text, 6, 4); err == nil {
+			t.Errorf("Expected an error for %q", text)
+		}
+	}
+}
+
+func TestFeverGaugeStartsFever(t *testing.T) {
+	game := feverGame(1)
+	events := recordEvents(game)
+	game.Field.Grid[FieldHeight-1][0] = Purple
+	normal := game.Field
+
+	for i := 0; i < FeverGaugeMax-1; i++ {
+		game.chargeFever(1)
+	}
+	if game.FeverActive || game.FeverGauge != FeverGaugeMax-1 {
+		t.Fatalf("Expected the gauge at %d without fever, got %d", FeverGaugeMax-1, game.FeverGauge)
+	}
+
+	game.chargeFever(1)
+	if !game.FeverActive || game.FeverFrames != FeverStartFrames || game.FeverChain != FeverStartChain {
+		t.Fatalf("Expected fever to start: active=%v frames=%d chain=%d", game.FeverActive, game.FeverFrames, game.FeverChain)
+	}
+	if game.NormalField != normal || game.Field == normal {
+		t.Error("Expected the player's field to be put aside for a pattern")
+	}
+
+	started := false
+	for _, ev := range *events {
+		if ev, ok := ev.(FeverStarted); ok && ev.Chain == FeverStartChain {
+			started = true
+		}
+	}
+	if !started {
+		t.Error("Expected a FeverStarted event")
+	}
+}
+
+func TestFeverChainsFillGauge(t *testing.T) {
+	game := feverGame(1)
+	for x := 0; x < 4; x++ {
+		game.Field.Grid[FieldHeight-1][x] = Red
+	}
+
+	runChain(game)
+
+	if game.FeverGauge != 1 {
+		t.Errorf("Expected a chain to fill the gauge by 1, got %d", game.FeverGauge)
+	}
+
+	standard := NewGameWithSeed(4, 1)
+	for x := 0; x < 4; x++ {
+		standard.Field.Grid[FieldHeight-1][x] = Red
+	}
+	runChain(standard)
+	if standard.FeverGauge != 0 {
+		t.Error("Expected no gauge without the fever rules")
+	}
+}
+
+func TestFeverSuccessEarnsTimeAndLongerPattern(t *testing.T) {
+	game := feverGame(2)
+	game.chargeFever(FeverGaugeMax)
+
+	// Fire the pattern with its trigger
+	pattern := findPattern(t, game.Field)
+	for _, cell := range pattern.Trigger {
+		game.Field.Grid[cell.Pos.Y][cell.Pos.X] = patternColor(pattern, game.Field, cell)
+	}
+	frames := game.FeverFrames
+	runChain(game)
+
+	if game.FeverChain != FeverStartChain+1 {
+		t.Errorf("Expected the next pattern to be a %d chain, got %d", FeverStartChain+1, game.FeverChain)
+	}
+	if want := frames + FeverStartChain*FeverLinkFrames; game.FeverFrames != want {
+		t.Errorf("Expected %d frames on the timer, got %d", want, game.FeverFrames)
+	}
+	if game.Field.IsEmpty() {
+		t.Error("Expected the next pattern to be dealt")
+	}
+}
+
+func TestFeverMissShortensPattern(t *testing.T) {
+	game := feverGame(3)
+	game.chargeFever(FeverGaugeMax)
+	game.FeverChain = 6
+	frames := game.FeverFrames
+
+	game.feverChainEnded(2)
+
+	if game.FeverChain != 5 || game.FeverFrames != frames {
+		t.Errorf("Expected a shorter pattern and no time after a miss, got chain %d frames %d", game.FeverChain, game.FeverFrames)
+	}
+}
+
+func TestFeverTimerRestoresField(t *testing.T) {
+	game := feverGame(4)
+	game.Field.Grid[FieldHeight-1][0] = Purple
+	normal := game.Field
+	game.chargeFever(FeverGaugeMax)
+
+	game.FeverFrames = 2
+	game.TickFever()
+	if !game.FeverActive {
+		t.Fatal("Expected fever to continue with time left")
+	}
+	if !game.TickFever() {
+		t.Error("Expected the display to change when fever ends")
+	}
+
+	if game.FeverActive || game.Field != normal || game.NormalField != nil {
+		t.Error("Expected the player's field to be restored")
+	}
+	if game.FeverGauge != 0 {
+		t.Errorf("Expected an empty gauge after fever, got %d", game.FeverGauge)
+	}
+	if game.Field.Grid[FieldHeight-1][0] != Purple {
+		t.Error("Expected the player's puyos to be kept")
+	}
+}
+
+func TestFeverTopOutEndsFever(t *testing.T) {
+	game := feverGame(5)
+	game.chargeFever(FeverGaugeMax)
+	for y := 0; y < FieldHeight; y++ {
+		game.Field.Grid[y][FieldWidth/2] = Color(y%2) + Red
+	}
+
+	game.SpawnNewPair()
+
+	if game.GameOver {
+		t.Error("Expected topping out during fever not to end the game")
+	}
+	if game.FeverActive {
+		t.Error("Expected topping out to end the fever")
+	}
+}
+
+func TestSuspendKeepsFever(t *testing.T) {
+	game := feverGame(6)
+	game.Field.Grid[FieldHeight-1][0] = Purple
+	game.chargeFever(FeverGaugeMax)
+	game.FeverFrames = 123
+
+	data, err := json.Marshal(game.Suspend())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var state SuspendState
+	if err := json.Unmarshal(data, &state); err != nil {
+		t.Fatal(err)
+	}
+	restored := mustRestore(t, &state)
+
+	if !restored.FeverActive || restored.FeverFrames != 123 || restored.FeverChain != game.FeverChain {
+		t.Errorf("Fever state not restored: %+v", state)
+	}
+	if restored.NormalField == nil || restored.NormalField.Grid[FieldHeight-1][0] != Purple {
+		t.Error("Expected the player's field to be restored")
+	}
+	if restored.Field.Notation() != game.Field.Notation() {
+		t.Error("Expected the pattern to be restored")
+	}
+}
+
+func TestFeverRulesValidate(t *testing.T) {
+	r := RulesByName("fever")
+	if err := r.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	r.Colors = 3
+	if r.Validate() == nil {
+		t.Error("Expected fever with 3 colors to be rejected")
+	}
+	r = RulesByName("fever")
+	r.Width = 8
+	if r.Validate() == nil {
+		t.Error("Expected fever on a wide field to be rejected")
+	}
+}
+
+// findPattern returns the library pattern with the same shape as the field
+func findPattern(t *testing.T, f *Field) FeverPattern {
+	t.Helper()
+	for _, p := range feverPatterns {
+		same := true
+		for y := range f.Grid {
+			for x := range f.Grid[y] {
+				if (f.Grid[y][x] == Empty) != (p.Field.Grid[y][x] == Empty) {
+					same = false
+				}
+			}
+		}
+		if same {
+			return p
+		}
+	}
+	t.Fatal("Field is not a library pattern")
+	return FeverPattern{}
+}
+
+// patternColor returns the field's color for a trigger puyo of a recolored pattern
+func patternColor(p FeverPattern, f *Field, trigger PieceCell) Color {
+	for y := range p.Field.Grid {
+		for x, c := range p.Field.Grid[y] {
+			if c == trigger.Color {
+				return f.Grid[y][x]
+			}
+		}
+	}
+	return trigger.Color
+}
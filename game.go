@@ -3,6 +3,7 @@ package main
 import (
 	"fmt"
 	"math/rand"
+	"sort"
 	"time"
 )
 
@@ -16,6 +17,10 @@ const (
 	Blue
 	Yellow
 	Purple
+	Orange // Only used with 6 colors
+
+	MaxColor = Orange
+	Nuisance = MaxColor + 1 // Garbage from the opponent, cleared next to popping groups
 )
 
 // String returns the display character for a color
@@ -31,15 +36,41 @@ func (c Color) String() string {
 		return "🟡"
 	case Purple:
 		return "🟣"
+	case Orange:
+		return "🟠"
+	case Nuisance:
+		return "⚪"
 	default:
 		return "  "
 	}
 }
 
+// Name returns the lowercase English name of a color
+func (c Color) Name() string {
+	switch c {
+	case Red:
+		return "red"
+	case Green:
+		return "green"
+	case Blue:
+		return "blue"
+	case Yellow:
+		return "yellow"
+	case Purple:
+		return "purple"
+	case Orange:
+		return "orange"
+	case Nuisance:
+		return "nuisance"
+	default:
+		return "empty"
+	}
+}
+
 const (
-	FieldWidth  = 6
-	FieldHeight = 12
-	MinChain    = 4 // Minimum puyos to clear
+	FieldWidth  = 6  // Default field width, see Rules
+	FieldHeight = 12 // Default field height, see Rules
+	MinChain    = 4  // Default minimum puyos to clear, see Rules
 )
 
 // Position represents a position on the field
@@ -52,28 +83,56 @@ type Puyo struct {
 	Color Color
 }
 
-// PuyoPair represents a falling pair of puyos
+// PuyoPair represents the falling piece: a pair of puyos, or a larger
+// shape from the rules' drop set (see PieceKind)
 type PuyoPair struct {
 	Main   Puyo
 	Sub    Puyo
-	Pos    Position // Position of main puyo
-	Rotate int      // 0=sub on top, 1=sub on right, 2=sub on bottom, 3=sub on left
+	Extra  []Puyo    `json:",omitempty"` // Cells beyond main and sub
+	Kind   PieceKind `json:",omitempty"` // "" for a pair
+	Pos    Position  // Position of main puyo, or the bottom left of a 2x2 piece
+	Rotate int       // For pairs: 0=sub on top, 1=sub on right, 2=sub on bottom, 3=sub on left
 }
 
 // Field represents the game field
 type Field struct {
-	Grid [FieldHeight][FieldWidth]Color
+	Width  int
+	Height int
+	Grid   [][]Color // Indexed [y][x], row 0 at the top
 }
 
-// NewField creates a new empty field
+// NewField creates a new empty field of the default size
 func NewField() *Field {
-	return &Field{}
+	return NewFieldSize(FieldWidth, FieldHeight)
+}
+
+// NewFieldSize creates a new empty field
+func NewFieldSize(width, height int) *Field {
+	f := &Field{Width: width, Height: height, Grid: make([][]Color, height)}
+	for y := range f.Grid {
+		f.Grid[y] = make([]Color, width)
+	}
+	return f
+}
+
+// Clone returns a copy of the field
+func (f *Field) Clone() *Field {
+	c := NewFieldSize(f.Width, f.Height)
+	for y := range f.Grid {
+		copy(c.Grid[y], f.Grid[y])
+	}
+	return c
+}
+
+// InBounds reports whether (x, y) is inside the field
+func (f *Field) InBounds(x, y int) bool {
+	return x >= 0 && x < f.Width && y >= 0 && y < f.Height
 }
 
 // IsValidPosition checks if a position is valid and empty
 // Allows negative Y (above screen) for spawning puyos
 func (f *Field) IsValidPosition(x, y int) bool {
-	if x < 0 || x >= FieldWidth || y >= FieldHeight {
+	if x < 0 || x >= f.Width || y >= f.Height {
 		return false
 	}
 	// Y < 0 is allowed (spawn area above screen)
@@ -85,25 +144,14 @@ func (f *Field) IsValidPosition(x, y int) bool {
 
 // PlacePuyo places a puyo at the given position
 func (f *Field) PlacePuyo(x, y int, color Color) {
-	if x >= 0 && x < FieldWidth && y >= 0 && y < FieldHeight {
+	if f.InBounds(x, y) {
 		f.Grid[y][x] = color
 	}
 }
 
 // GetSubPosition returns the position of the sub puyo based on rotation
 func (p *PuyoPair) GetSubPosition() Position {
-	switch p.Rotate {
-	case 0: // Top
-		return Position{p.Pos.X, p.Pos.Y - 1}
-	case 1: // Right
-		return Position{p.Pos.X + 1, p.Pos.Y}
-	case 2: // Bottom
-		return Position{p.Pos.X, p.Pos.Y + 1}
-	case 3: // Left
-		return Position{p.Pos.X - 1, p.Pos.Y}
-	default:
-		return Position{p.Pos.X, p.Pos.Y - 1}
-	}
+	return p.cellPositions(p.Pos, p.Rotate)[1]
 }
 
 // GameState represents the current state of the game
@@ -125,18 +173,57 @@ type Game struct {
 	GameOver        bool
 	Paused          bool // Game is paused
 	rand            *rand.Rand
+	source          *countingSource
 	ChainCount      int
 	TotalChains     int
 	LinesCleared    int
 	DropSpeed       time.Duration
 	HighScore       *HighScore
 	State           GameState
-	CurrentChainNum int // Current chain number being displayed
-	GroundFrames    int // Frames spent on ground (lock delay counter)
-	MaxGroundFrames int // Maximum frames allowed on ground (32 for Puyo Puyo Tsu)
-	ColorCount      int // Number of colors (4 or 5)
+	CurrentChainNum int               // Current chain number being displayed
+	GroundFrames    int               // Frames spent on ground (lock delay counter)
+	MaxGroundFrames int               // Maximum frames allowed on ground (32 for Puyo Puyo Tsu)
+	ColorCount      int               // Number of colors, same as Rules.Colors
+	PiecesPlaced    int               // Number of pairs locked into the field
+	PiecesDealt     int               // Number of pieces generated, the position in the drop set
+	MaxChain        int               // Longest chain in this game
+	ChainHistogram  map[int]int       // Number of chains that ended at each length
+	PuyosPopped     [MaxColor + 1]int // Puyos popped, indexed by color
+	Frames          int               // Frames of active play (60 per second)
+	PopFrames       int               // Frames popping puyos flash before they are removed
+	FallFrames      int               // Frames per row for puyos falling after a pop
+	AnimFrame       int               // Frame counter for the current chain animation step
+	Popping         []Position        // Puyos about to pop
+	ChainPos        Position          // Where the chain banner is shown
+	Events          *EventBus         // Receives everything that happens in the game
+	Progression     *Progression      // How the level and speed advance
+	Gravity         int               // Frames per row at the current level, or Gravity20G
+	Rules           Rules             // Field size, colors, pop count and other variant settings
+	FeverGauge      int               // Offsets toward fever, up to FeverGaugeMax
+	FeverActive     bool              // The field holds a fever pattern
+	FeverFrames     int               // Frames left on the fever timer
+	FeverChain      int               // Chain length of the next fever pattern
+	NormalField     *Field            // The player's own field, put aside during fever
+	AllClears       int               // Chains that left the field empty
+	AllClearPending bool              // An all clear's AllClearNuisance is owed with the next attack (versus)
+	Versus          bool              // Garbage is exchanged with an opponent, see LinkVersus
+	Garbage         GarbageQueue      // Nuisance waiting to fall on the field
+	NuisanceScore   int               // Chain points not yet worth a whole nuisance puyo
+	ChainOffset     bool              // The current chain cancelled incoming garbage
+	GarbageFell     bool              // Garbage already fell after the current piece
+	Clock           *MatchClock       // Versus match time, shared with the opponent
+	Conversion      NuisancePolicy    // Turns chain points into nuisance (nil: from the rules)
+	Cleared         bool              // A timed run ended by reaching its goal
+	MissionStep     int               // Objectives of the mission completed
+	MissionPieces   int               // PiecesPlaced when the current objective started
 }
 
+// Default chain animation timings in frames
+const (
+	DefaultPopFrames  = 24
+	DefaultFallFrames = 2
+)
+
 // TogglePause toggles the pause state
 func (g *Game) TogglePause() {
 	// Don't allow pause during chain animation or when game is over
@@ -151,44 +238,137 @@ func NewGame() *Game {
 	return NewGameWithColors(4) // Default to 4 colors
 }
 
-// NewGameWithColors creates a new game with specified number of colors (4 or 5)
+// NewGameWithColors creates a new game with the standard rules and the
+// specified number of colors (MinColors to MaxColors)
 func NewGameWithColors(colorCount int) *Game {
-	if colorCount != 4 && colorCount != 5 {
+	return NewGameWithSeed(colorCount, time.Now().UnixNano())
+}
+
+// NewGameWithSeed creates a new game whose pair sequence is determined by seed
+func NewGameWithSeed(colorCount int, seed int64) *Game {
+	if colorCount < MinColors || colorCount > MaxColors {
 		colorCount = 4 // Default to 4 if invalid
 	}
 
-	source := rand.NewSource(time.Now().UnixNano())
-	r := rand.New(source)
+	rules := DefaultRules()
+	rules.Colors = colorCount
+	return NewGameWithRules(rules, seed)
+}
+
+// NewGameWithRules creates a new game for a rules variant
+// The rules must be valid, see Rules.Validate.
+func NewGameWithRules(rules Rules, seed int64) *Game {
+	g := newGame(rules, seed, NewEventBus())
+	g.start()
+	return g
+}
+
+// newGame creates a game on an event bus without dealing the first pair
+// A restart passes the old game's bus, so its subscribers see every event
+// of the new game once start is called.
+func newGame(rules Rules, seed int64, events *EventBus) *Game {
+	source := newCountingSource(seed)
 
 	g := &Game{
-		Field:           NewField(),
-		rand:            r,
+		Field:           NewFieldSize(rules.Width, rules.Height),
+		Rules:           rules,
+		rand:            rand.New(source),
+		source:          source,
 		Level:           1,
-		DropSpeed:       500 * time.Millisecond,
-		MaxGroundFrames: 32, // Puyo Puyo Tsu specification
-		ColorCount:      colorCount,
+		MaxGroundFrames: rules.LockDelay,
+		ColorCount:      rules.Colors,
+		ChainHistogram:  make(map[int]int),
+		PopFrames:       DefaultPopFrames,
+		FallFrames:      DefaultFallFrames,
+		Events:          events,
+		Progression:     ProgressionByName("standard"),
 	}
+	g.applySpeed()
+	if rules.Goal == GoalMission {
+		g.startMission()
+	}
+	return g
+}
 
+// start deals the first pair
+func (g *Game) start() {
 	g.Next = g.generatePuyoPair()
 	g.SpawnNewPair()
+}
 
-	return g
+// countingSource wraps a rand.Source and counts the values drawn from it,
+// so that the generator state can be saved as (seed, draws) and restored
+type countingSource struct {
+	seed  int64
+	draws int64
+	src   rand.Source
 }
 
-// generatePuyoPair generates a random puyo pair
+// newCountingSource creates a counting source seeded with seed
+func newCountingSource(seed int64) *countingSource {
+	return &countingSource{
+		seed: seed,
+		src:  rand.NewSource(seed),
+	}
+}
+
+// restoreCountingSource recreates a source that has already produced draws values
+func restoreCountingSource(seed, draws int64) *countingSource {
+	s := newCountingSource(seed)
+	for s.draws < draws {
+		s.Int63()
+	}
+	return s
+}
+
+// Int63 returns the next value and counts the draw
+func (s *countingSource) Int63() int64 {
+	s.draws++
+	return s.src.Int63()
+}
+
+// Seed reseeds the source and resets the draw count
+func (s *countingSource) Seed(seed int64) {
+	s.seed = seed
+	s.draws = 0
+	s.src.Seed(seed)
+}
+
+// Seed returns the seed the game's pair sequence was generated from
+func (g *Game) Seed() int64 {
+	return g.source.seed
+}
+
+// generatePuyoPair generates the next random piece from the drop set
 func (g *Game) generatePuyoPair() *PuyoPair {
-	var colors []Color
-	if g.ColorCount == 4 {
-		colors = []Color{Red, Green, Blue, Yellow}
-	} else {
-		colors = []Color{Red, Green, Blue, Yellow, Purple}
+	colors := g.Rules.palette()
+	kind := g.nextPieceKind()
+
+	a := colors[g.rand.Intn(len(colors))]
+	b := a
+	switch kind {
+	case PieceBig:
+		// One color
+	case PieceQuad:
+		// Two different colors; the palette is contiguous, so skip a by value
+		b = colors[g.rand.Intn(len(colors)-1)]
+		if b >= a {
+			b++
+		}
+	default:
+		b = colors[g.rand.Intn(len(colors))]
 	}
-	return &PuyoPair{
-		Main:   Puyo{Color: colors[g.rand.Intn(len(colors))]},
-		Sub:    Puyo{Color: colors[g.rand.Intn(len(colors))]},
-		Pos:    Position{X: FieldWidth / 2, Y: 0}, // Sub puyo will be at Y=-1 (above screen)
-		Rotate: 0,
+
+	p := newPiece(kind, a, b)
+	p.Pos = Position{X: g.Rules.SpawnColumn, Y: 0} // Sub puyo will be at Y=-1 (above screen)
+
+	// Keep wide pieces inside the field
+	for _, cell := range p.Cells() {
+		if over := cell.Pos.X - (g.Field.Width - 1); over > 0 {
+			p.Pos.X -= over
+		}
 	}
+	return p
 }
 
 // SpawnNewPair spawns a new puyo pair
@@ -197,12 +377,28 @@ func (g *Game) SpawnNewPair() {
 	g.Next = g.generatePuyoPair()
 	g.ChainCount = 0
 
+	// Topping out during fever only ends the fever
+	if !g.canSpawn() && g.FeverActive {
+		g.endFever()
+	}
+
 	// Check if spawn position is blocked (game over)
-	subPos := g.Current.GetSubPosition()
-	if !g.Field.IsValidPosition(g.Current.Pos.X, g.Current.Pos.Y) ||
-		!g.Field.IsValidPosition(subPos.X, subPos.Y) {
+	if !g.canSpawn() {
 		g.GameOver = true
+		g.emit(GameOver{Score: g.Score})
+		return
+	}
+	g.emit(PairSpawned{Pair: *g.Current, Next: *g.Next})
+}
+
+// canSpawn reports whether every cell of the current piece is free
+func (g *Game) canSpawn() bool {
+	for _, cell := range g.Current.Cells() {
+		if !g.Field.IsValidPosition(cell.Pos.X, cell.Pos.Y) {
+			return false
+		}
 	}
+	return true
 }
 
 // CanMove checks if the current pair can move to the given position
@@ -211,29 +407,32 @@ func (g *Game) CanMove(dx, dy, rotate int) bool {
 		return false
 	}
 
-	newX := g.Current.Pos.X + dx
-	newY := g.Current.Pos.Y + dy
+	newPos := Position{g.Current.Pos.X + dx, g.Current.Pos.Y + dy}
 	newRotate := (g.Current.Rotate + rotate + 4) % 4
 
-	// Check main puyo
-	if !g.Field.IsValidPosition(newX, newY) {
-		return false
-	}
-
-	// Check sub puyo
-	testPair := &PuyoPair{
-		Pos:    Position{newX, newY},
-		Rotate: newRotate,
+	// Every cell must land on an empty square
+	for _, pos := range g.Current.cellPositions(newPos, newRotate) {
+		if !g.Field.IsValidPosition(pos.X, pos.Y) {
+			return false
+		}
 	}
-	subPos := testPair.GetSubPosition()
-
-	return g.Field.IsValidPosition(subPos.X, subPos.Y)
+	return true
 }
 
 // Move moves the current pair
 func (g *Game) Move(dx, dy, rotate int) bool {
+	// Big puyos change color instead of turning
+	if rotate != 0 && g.Current != nil && g.Current.shape().ColorCycle {
+		if dx != 0 || dy != 0 {
+			return false
+		}
+		g.cycleColor(rotate)
+		return true
+	}
+
 	// Try normal move first
 	if g.CanMove(dx, dy, rotate) {
+		from := g.Current.Pos
 		g.Current.Pos.X += dx
 		g.Current.Pos.Y += dy
 		g.Current.Rotate = (g.Current.Rotate + rotate + 4) % 4
@@ -242,45 +441,60 @@ func (g *Game) Move(dx, dy, rotate int) bool {
 		if dx != 0 || rotate != 0 {
 			g.GroundFrames = 0
 		}
+		if rotate != 0 {
+			g.emit(PairRotated{Rotate: g.Current.Rotate, Pos: g.Current.Pos})
+		} else if dx != 0 || dy != 0 {
+			g.emit(PairMoved{From: from, To: g.Current.Pos})
+		}
 		return true
 	}
 
-	// If rotation failed, try wall kick (shift horizontally)
+	// If rotation failed, try the wall and floor kicks allowed by the rules
 	if rotate != 0 {
-		// Try shifting left
-		if g.CanMove(dx-1, dy, rotate) {
-			g.Current.Pos.X += dx - 1
-			g.Current.Pos.Y += dy
-			g.Current.Rotate = (g.Current.Rotate + rotate + 4) % 4
-			g.GroundFrames = 0
-			return true
+		for _, kick := range g.Rules.kickOffsets() {
+			if g.CanMove(dx+kick.X, dy+kick.Y, rotate) {
+				g.Current.Pos.X += dx + kick.X
+				g.Current.Pos.Y += dy + kick.Y
+				g.Current.Rotate = (g.Current.Rotate + rotate + 4) % 4
+				g.GroundFrames = 0
+				g.emit(PairRotated{Rotate: g.Current.Rotate, Pos: g.Current.Pos})
+				return true
+			}
 		}
+	}
 
-		// Try shifting right
-		if g.CanMove(dx+1, dy, rotate) {
-			g.Current.Pos.X += dx + 1
-			g.Current.Pos.Y += dy
-			g.Current.Rotate = (g.Current.Rotate + rotate + 4) % 4
-			g.GroundFrames = 0
-			return true
-		}
+	return false
+}
 
-		// Try shifting up (for floor kick)
-		if g.CanMove(dx, dy-1, rotate) {
-			g.Current.Pos.X += dx
-			g.Current.Pos.Y += dy - 1
-			g.Current.Rotate = (g.Current.Rotate + rotate + 4) % 4
-			g.GroundFrames = 0
-			return true
+// cycleColor changes the current piece to the next color in the palette
+func (g *Game) cycleColor(dir int) {
+	colors := g.Rules.palette()
+	i := 0
+	for j, c := range colors {
+		if c == g.Current.Main.Color {
+			i = j
 		}
 	}
-
-	return false
+	i = ((i+dir)%len(colors) + len(colors)) % len(colors)
+	g.Current.setColor(colors[i])
+	g.GroundFrames = 0
+	g.emit(PairRotated{Rotate: g.Current.Rotate, Pos: g.Current.Pos})
 }
 
-// Drop drops the current pair by one row
+// Drop drops the current pair by one row, or all the way down at 20G
 // Returns true if the drop was successful, false if the pair is on the ground
 func (g *Game) Drop() bool {
+	if g.Gravity == Gravity20G && g.Current != nil {
+		dropped := false
+		for g.Move(0, 1, 0) {
+			dropped = true
+		}
+		if dropped {
+			g.GroundFrames = 0
+		}
+		return dropped
+	}
+
 	if g.Move(0, 1, 0) {
 		// Successfully moved down, reset ground timer
 		g.GroundFrames = 0
@@ -300,6 +514,26 @@ func (g *Game) IsOnGround() bool {
 	return !g.CanMove(0, 1, 0)
 }
 
+// GhostPositions returns where the main and sub puyo will come to rest
+// if the current pair is dropped and locked now
+func (g *Game) GhostPositions() (main, sub Position, ok bool) {
+	if g.Current == nil {
+		return Position{}, Position{}, false
+	}
+
+	cells := g.GhostCells()
+	return cells[0].Pos, cells[1].Pos, true
+}
+
+// columnTop returns the lowest empty row of a column
+func (g *Game) columnTop(x int) int {
+	y := g.Field.Height - 1
+	for y >= 0 && g.Field.Grid[y][x] != Empty {
+		y--
+	}
+	return y
+}
+
 // ShouldLock checks if the pair should be locked based on ground time
 func (g *Game) ShouldLock() bool {
 	return g.GroundFrames >= g.MaxGroundFrames
@@ -320,27 +554,45 @@ func (g *Game) LockPair() {
 		return
 	}
 
-	// Place both puyos
-	g.Field.PlacePuyo(g.Current.Pos.X, g.Current.Pos.Y, g.Current.Main.Color)
+	// Place every cell
+	cells := g.Current.Cells()
+	for _, cell := range cells {
+		g.Field.PlacePuyo(cell.Pos.X, cell.Pos.Y, cell.Color)
+	}
 
-	subPos := g.Current.GetSubPosition()
-	g.Field.PlacePuyo(subPos.X, subPos.Y, g.Current.Sub.Color)
+	locked := PairLocked{
+		Main:      cells[0].Pos,
+		Sub:       cells[1].Pos,
+		Cells:     cells,
+		MainColor: g.Current.Main.Color,
+		SubColor:  g.Current.Sub.Color,
+	}
 
 	// Clear the current pair so it doesn't interfere
 	g.Current = nil
+	g.PiecesPlaced++
+	g.emit(locked)
+	g.updateLevel()
 
 	// Reset ground timer
 	g.GroundFrames = 0
+	g.ChainOffset = false
+	g.GarbageFell = false
 
 	// Apply gravity first
 	g.State = StateDropping
 	g.ChainCount = 0
 	g.CurrentChainNum = 0
+	g.AnimFrame = 0
 }
 
 // ProcessChainStep processes one step of the chain animation
 // Returns true if there are more steps to process
 func (g *Game) ProcessChainStep() bool {
+	if g.GameOver {
+		return false
+	}
+
 	switch g.State {
 	case StateDropping:
 		// Apply gravity
@@ -351,12 +603,43 @@ func (g *Game) ProcessChainStep() bool {
 			g.State = StateClearing
 			g.ChainCount++
 			g.CurrentChainNum = g.ChainCount
+			score := g.markPopping()
+			g.Score += score
+			g.emit(ChainStep{Chain: g.ChainCount, Score: score})
+			g.attack(score)
+			g.checkGoal()
 			return true
 		} else {
+			// A piece locked without a chain lets pending garbage fall
+			if g.ChainCount == 0 && !g.GarbageFell && !g.FeverActive && g.Garbage.Pending > 0 {
+				g.GarbageFell = true
+				g.dropGarbage()
+				return true
+			}
+
 			// No more chains, calculate final score
+			if g.ChainCount > 0 && g.Field.IsEmpty() {
+				g.allClear()
+				if g.GameOver {
+					return false
+				}
+			}
 			g.calculateScore()
+
+			// Offsets fill the fever gauge. In single play there is no
+			// incoming garbage, so every chain counts as an offset.
+			if g.ChainCount > 0 && g.Rules.Fever {
+				if g.FeverActive {
+					g.feverChainEnded(g.ChainCount)
+				} else if g.ChainOffset || !g.Versus {
+					g.chargeFever(1)
+				}
+			}
 			g.State = StateNormal
 			g.SpawnNewPair()
+			if g.Rules.Goal == GoalMission {
+				g.checkSettled()
+			}
 			return false
 		}
 
@@ -366,6 +649,7 @@ func (g *Game) ProcessChainStep() bool {
 		if cleared {
 			g.TotalChains++
 		}
+		g.Popping = nil
 		g.State = StateDropping
 		return true
 
@@ -374,12 +658,141 @@ func (g *Game) ProcessChainStep() bool {
 	}
 }
 
+// UpdateChain advances the chain animation by one frame
+// Popping groups flash for PopFrames frames before they are removed, and
+// falling puyos move down one row every FallFrames frames. Everything is
+// counted in frames so a chain always takes the same time.
+// Returns true while the chain is still running.
+func (g *Game) UpdateChain() bool {
+	switch g.State {
+	case StateDropping:
+		if g.Field.hasFloating() {
+			g.AnimFrame++
+			if g.AnimFrame >= g.FallFrames {
+				g.AnimFrame = 0
+				g.Field.fallOneRow()
+			}
+			return true
+		}
+		// Everything has landed
+		g.AnimFrame = 0
+		return g.ProcessChainStep()
+
+	case StateClearing:
+		g.AnimFrame++
+		if g.AnimFrame < g.PopFrames {
+			return true
+		}
+		g.AnimFrame = 0
+		return g.ProcessChainStep()
+
+	default:
+		return false
+	}
+}
+
+// markPopping records the puyos about to pop and where to show the chain banner
+// Returns the points for the chain step.
+func (g *Game) markPopping() int {
+	g.Popping = g.Popping[:0]
+	visited := make(map[Position]bool)
+	var sizes []int
+	colors := make(map[Color]bool)
+
+	for y := 0; y < g.Field.Height; y++ {
+		for x := 0; x < g.Field.Width; x++ {
+			pos := Position{x, y}
+			if g.Field.Grid[y][x] != Empty && !visited[pos] {
+				group := g.findConnectedGroup(x, y, g.Field.Grid[y][x], make(map[Position]bool))
+
+				// Mark as visited
+				for p := range group {
+					visited[p] = true
+				}
+
+				if len(group) >= g.Rules.PopCount {
+					for p := range group {
+						g.Popping = append(g.Popping, p)
+					}
+					sizes = append(sizes, len(group))
+					colors[g.Field.Grid[y][x]] = true
+				}
+			}
+		}
+	}
+
+	// The banner goes next to the topmost popping puyo
+	sortPositions(g.Popping)
+	if len(g.Popping) > 0 {
+		g.ChainPos = g.Popping[0]
+	}
+
+	// Nuisance next to a popping group blinks with it
+	g.Popping = append(g.Popping, g.Field.adjacentNuisance(g.Popping)...)
+
+	return g.Rules.stepScore(g.ChainCount, sizes, len(colors))
+}
+
+// IsPopping reports whether the puyo at (x, y) is about to pop
+func (g *Game) IsPopping(x, y int) bool {
+	if g.State != StateClearing {
+		return false
+	}
+	for _, p := range g.Popping {
+		if p.X == x && p.Y == y {
+			return true
+		}
+	}
+	return false
+}
+
+// IsEmpty reports whether the field has no puyos
+func (f *Field) IsEmpty() bool {
+	for y := 0; y < f.Height; y++ {
+		for x := 0; x < f.Width; x++ {
+			if f.Grid[y][x] != Empty {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// hasFloating reports whether any puyo has an empty cell below it
+func (f *Field) hasFloating() bool {
+	for x := 0; x < f.Width; x++ {
+		for y := f.Height - 2; y >= 0; y-- {
+			if f.Grid[y][x] != Empty && f.Grid[y+1][x] == Empty {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// fallOneRow moves every floating puyo down by one row
+// Returns true if anything moved
+func (f *Field) fallOneRow() bool {
+	moved := false
+	for x := 0; x < f.Width; x++ {
+		// Bottom-up, so each puyo moves at most once
+		for y := f.Height - 2; y >= 0; y-- {
+			if f.Grid[y][x] != Empty && f.Grid[y+1][x] == Empty {
+				f.Grid[y+1][x] = f.Grid[y][x]
+				f.Grid[y][x] = Empty
+				moved = true
+			}
+		}
+	}
+	return moved
+}
+
 // hasClearablePuyos checks if there are any puyos that can be cleared
 func (g *Game) hasClearablePuyos() bool {
 	visited := make(map[Position]bool)
 
-	for y := 0; y < FieldHeight; y++ {
-		for x := 0; x < FieldWidth; x++ {
+	for y := 0; y < g.Field.Height; y++ {
+		for x := 0; x < g.Field.Width; x++ {
 			pos := Position{x, y}
 			if g.Field.Grid[y][x] != Empty && !visited[pos] {
 				group := g.findConnectedGroup(x, y, g.Field.Grid[y][x], make(map[Position]bool))
@@ -390,7 +803,7 @@ func (g *Game) hasClearablePuyos() bool {
 				}
 
 				// Check if group is large enough to clear
-				if len(group) >= MinChain {
+				if len(group) >= g.Rules.PopCount {
 					return true
 				}
 			}
@@ -400,53 +813,68 @@ func (g *Game) hasClearablePuyos() bool {
 	return false
 }
 
-// calculateScore calculates the score based on chains
+// allClear awards the all-clear bonus
+// In solo play the bonus is Rules.AllClearBonus points, which can reach a
+// score goal; in versus the next attack carries AllClearNuisance extra
+// nuisance puyos instead.
+func (g *Game) allClear() {
+	g.AllClears++
+	g.AllClearPending = true
+	bonus := 0
+	if !g.Versus {
+		bonus = g.Rules.AllClearBonus
+		g.Score += bonus
+	}
+	g.emit(AllClear{Bonus: bonus})
+	if bonus > 0 {
+		g.checkGoal()
+	}
+}
+
+// calculateScore records a finished chain; each step was scored as it popped
 func (g *Game) calculateScore() {
 	if g.ChainCount > 0 {
-		chainBonus := 1
-		for i := 1; i < g.ChainCount; i++ {
-			chainBonus *= 2
-		}
-		g.Score += 100 * chainBonus * g.ChainCount
 		g.LinesCleared += g.ChainCount
 
-		// Level up every 10 clears
-		newLevel := (g.LinesCleared / 10) + 1
-		if newLevel > g.Level {
-			g.Level = newLevel
-			// Increase speed with level (max speed at level 20)
-			if g.Level < 20 {
-				g.DropSpeed = time.Duration(float64(500*time.Millisecond) / (1.0 + float64(g.Level)*0.1))
-			} else {
-				g.DropSpeed = 100 * time.Millisecond
-			}
+		// Record chain length for statistics
+		if g.ChainHistogram == nil {
+			g.ChainHistogram = make(map[int]int)
 		}
+		g.ChainHistogram[g.ChainCount]++
+		if g.ChainCount > g.MaxChain {
+			g.MaxChain = g.ChainCount
+		}
+
+		g.updateLevel()
+	}
+}
+
+// chainScore returns the points for a whole chain of the given length
+// The chain bonus doubles with each step: 100 x 2^(n-1) x n.
+func chainScore(chain int) int {
+	if chain <= 0 {
+		return 0
+	}
+	chainBonus := 1
+	for i := 1; i < chain; i++ {
+		chainBonus *= 2
 	}
+	return 100 * chainBonus * chain
 }
 
 // applyGravity makes puyos fall down
 func (g *Game) applyGravity() {
-	for x := 0; x < FieldWidth; x++ {
-		writeY := FieldHeight - 1
-		for y := FieldHeight - 1; y >= 0; y-- {
-			if g.Field.Grid[y][x] != Empty {
-				if writeY != y {
-					g.Field.Grid[writeY][x] = g.Field.Grid[y][x]
-					g.Field.Grid[y][x] = Empty
-				}
-				writeY--
-			}
-		}
-	}
+	g.Field.settle()
 }
 
 // clearPuyos clears connected puyos of the same color
 func (g *Game) clearPuyos() bool {
 	visited := make(map[Position]bool)
 	cleared := false
+	var removed []Position
 
-	for y := 0; y < FieldHeight; y++ {
-		for x := 0; x < FieldWidth; x++ {
+	for y := 0; y < g.Field.Height; y++ {
+		for x := 0; x < g.Field.Width; x++ {
 			pos := Position{x, y}
 			if g.Field.Grid[y][x] != Empty && !visited[pos] {
 				group := g.findConnectedGroup(x, y, g.Field.Grid[y][x], make(map[Position]bool))
@@ -457,70 +885,137 @@ func (g *Game) clearPuyos() bool {
 				}
 
 				// Clear if group is large enough
-				if len(group) >= MinChain {
+				if len(group) >= g.Rules.PopCount {
+					color := g.Field.Grid[y][x]
+					g.PuyosPopped[color] += len(group)
+					popped := GroupPopped{
+						Chain: g.ChainCount,
+						Color: color,
+						Size:  len(group),
+					}
 					for p := range group {
 						g.Field.Grid[p.Y][p.X] = Empty
+						popped.Positions = append(popped.Positions, p)
 					}
+					removed = append(removed, popped.Positions...)
+					sortPositions(popped.Positions)
+					g.emit(popped)
 					cleared = true
 				}
 			}
 		}
 	}
 
+	// Nuisance is cleared by groups popping next to it
+	for _, p := range g.Field.adjacentNuisance(removed) {
+		g.Field.Grid[p.Y][p.X] = Empty
+	}
+
 	return cleared
 }
 
+// sortPositions sorts positions top to bottom, then left to right
+func sortPositions(positions []Position) {
+	sort.Slice(positions, func(i, j int) bool {
+		if positions[i].Y != positions[j].Y {
+			return positions[i].Y < positions[j].Y
+		}
+		return positions[i].X < positions[j].X
+	})
+}
+
 // findConnectedGroup finds all connected puyos of the same color
 func (g *Game) findConnectedGroup(x, y int, color Color, visited map[Position]bool) map[Position]bool {
 	pos := Position{x, y}
 
-	if x < 0 || x >= FieldWidth || y < 0 || y >= FieldHeight {
+	if x < 0 || x >= g.Field.Width || y < 0 || y >= g.Field.Height {
 		return visited
 	}
 
-	if g.Field.Grid[y][x] != color || visited[pos] {
+	// Nuisance never forms groups of its own
+	if g.Field.Grid[y][x] != color || color == Nuisance || visited[pos] {
 		return visited
 	}
 
 	visited[pos] = true
 
 	// Check all 4 directions
-	g.findConnectedGroup(x+1, y, color, visited)
-	g.findConnectedGroup(x-1, y, color, visited)
-	g.findConnectedGroup(x, y+1, color, visited)
-	g.findConnectedGroup(x, y-1, color, visited)
+	for _, d := range directions {
+		g.findConnectedGroup(x+d.dx, y+d.dy, color, visited)
+	}
 
 	return visited
 }
 
+// Link is a bit set of the directions in which a puyo touches
+// another puyo of the same color
+type Link int
+
+const (
+	LinkUp Link = 1 << iota
+	LinkRight
+	LinkDown
+	LinkLeft
+)
+
+// directions lists the four neighbors puyos connect through
+var directions = [4]struct {
+	dx, dy int
+	link   Link
+}{
+	{0, -1, LinkUp},
+	{1, 0, LinkRight},
+	{0, 1, LinkDown},
+	{-1, 0, LinkLeft},
+}
+
+// Links returns the directions in which the puyo at (x, y) is connected
+// to a neighbor of the same color
+func (f *Field) Links(x, y int) Link {
+	if x < 0 || x >= f.Width || y < 0 || y >= f.Height {
+		return 0
+	}
+	color := f.Grid[y][x]
+	if color == Empty || color == Nuisance {
+		return 0
+	}
+
+	var links Link
+	for _, d := range directions {
+		nx, ny := x+d.dx, y+d.dy
+		if nx >= 0 && nx < f.Width && ny >= 0 && ny < f.Height && f.Grid[ny][nx] == color {
+			links |= d.link
+		}
+	}
+	return links
+}
+
 // Display prints the current game state (for debugging)
 func (g *Game) Display() {
 	fmt.Println("Score:", g.Score, "Level:", g.Level, "Chains:", g.TotalChains)
 
 	// Create a copy of the field to overlay the current pair
-	display := g.Field.Grid
+	display := g.Field.Clone().Grid
 
 	if g.Current != nil {
-		subPos := g.Current.GetSubPosition()
-		if subPos.Y >= 0 && subPos.Y < FieldHeight && subPos.X >= 0 && subPos.X < FieldWidth {
-			display[subPos.Y][subPos.X] = g.Current.Sub.Color
-		}
-		if g.Current.Pos.Y >= 0 && g.Current.Pos.Y < FieldHeight {
-			display[g.Current.Pos.Y][g.Current.Pos.X] = g.Current.Main.Color
+		for _, cell := range g.Current.Cells() {
+			if g.Field.InBounds(cell.Pos.X, cell.Pos.Y) {
+				display[cell.Pos.Y][cell.Pos.X] = cell.Color
+			}
 		}
 	}
 
 	// Print field
-	for y := 0; y < FieldHeight; y++ {
+	for y := 0; y < g.Field.Height; y++ {
 		fmt.Print("|")
-		for x := 0; x < FieldWidth; x++ {
+		for x := 0; x < g.Field.Width; x++ {
 			fmt.Print(Color(display[y][x]).String())
 		}
 		fmt.Println("|")
 	}
 
 	// Print bottom border
-	for i := 0; i < FieldWidth*2+2; i++ {
+	for i := 0; i < g.Field.Width*2+2; i++ {
 		fmt.Print("=")
 	}
 	fmt.Println()
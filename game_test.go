@@ -562,15 +562,22 @@ func TestColorCount5(t *testing.T) {
 
 func TestInvalidColorCount(t *testing.T) {
 	// Test that invalid color counts default to 4
-	game := NewGameWithColors(3)
+	game := NewGameWithColors(2)
 	if game.ColorCount != 4 {
 		t.Errorf("Expected invalid color count to default to 4, got %d", game.ColorCount)
 	}
 
-	game = NewGameWithColors(6)
+	game = NewGameWithColors(7)
 	if game.ColorCount != 4 {
 		t.Errorf("Expected invalid color count to default to 4, got %d", game.ColorCount)
 	}
+
+	// 3 to 6 colors are allowed
+	for colors := MinColors; colors <= MaxColors; colors++ {
+		if game := NewGameWithColors(colors); game.ColorCount != colors {
+			t.Errorf("Expected %d colors, got %d", colors, game.ColorCount)
+		}
+	}
 }
 
 func TestDropAndLock(t *testing.T) {
@@ -608,3 +615,109 @@ func TestDropAndLock(t *testing.T) {
 
 	t.Error("Did not lock after 35 drops")
 }
+
+func TestFieldLinks(t *testing.T) {
+	field := NewField()
+
+	// An L-shape of red next to a single blue
+	field.Grid[FieldHeight-1][0] = Red
+	field.Grid[FieldHeight-1][1] = Red
+	field.Grid[FieldHeight-2][0] = Red
+	field.Grid[FieldHeight-1][2] = Blue
+
+	tests := []struct {
+		x, y     int
+		expected Link
+	}{
+		{0, FieldHeight - 1, LinkUp | LinkRight},
+		{1, FieldHeight - 1, LinkLeft},
+		{0, FieldHeight - 2, LinkDown},
+		{2, FieldHeight - 1, 0},  // Different color neighbor
+		{3, FieldHeight - 1, 0},  // Empty cell
+		{-1, FieldHeight - 1, 0}, // Out of bounds
+		{FieldWidth, 0, 0},       // Out of bounds
+	}
+
+	for _, tt := range tests {
+		if got := field.Links(tt.x, tt.y); got != tt.expected {
+			t.Errorf("Links(%d, %d) = %v, want %v", tt.x, tt.y, got, tt.expected)
+		}
+	}
+}
+
+func TestUpdateChainFallsOneRowPerStep(t *testing.T) {
+	game := NewGame()
+	game.Current = nil
+	game.FallFrames = 3
+	game.Field.Grid[0][0] = Red
+	game.State = StateDropping
+
+	for row := 1; row <= 3; row++ {
+		for i := 0; i < game.FallFrames; i++ {
+			if !game.UpdateChain() {
+				t.Fatalf("Chain ended while the puyo was still falling")
+			}
+		}
+		if game.Field.Grid[row][0] != Red {
+			t.Fatalf("Expected puyo at row %d after %d frames", row, row*game.FallFrames)
+		}
+	}
+}
+
+func TestUpdateChainPopTiming(t *testing.T) {
+	game := NewGame()
+	game.Current = nil
+	game.PopFrames = 5
+	for y := FieldHeight - 4; y < FieldHeight; y++ {
+		game.Field.Grid[y][2] = Green
+	}
+	game.State = StateDropping
+
+	// Landed puyos pop immediately
+	game.UpdateChain()
+	if game.State != StateClearing || game.CurrentChainNum != 1 {
+		t.Fatalf("Expected first chain step, got state %v chain %d", game.State, game.CurrentChainNum)
+	}
+	if len(game.Popping) != 4 || !game.IsPopping(2, FieldHeight-1) || game.IsPopping(1, FieldHeight-1) {
+		t.Errorf("Unexpected popping cells %v", game.Popping)
+	}
+	if game.ChainPos != (Position{2, FieldHeight - 4}) {
+		t.Errorf("Expected chain banner at the top of the group, got %v", game.ChainPos)
+	}
+
+	// The group stays on the field until PopFrames have passed
+	for i := 0; i < game.PopFrames-1; i++ {
+		game.UpdateChain()
+		if game.Field.Grid[FieldHeight-1][2] != Green {
+			t.Fatalf("Group removed after only %d frames", i+1)
+		}
+	}
+	game.UpdateChain()
+	if game.Field.Grid[FieldHeight-1][2] != Empty || len(game.Popping) != 0 {
+		t.Error("Expected group removed after PopFrames")
+	}
+}
+
+func TestFallOneRow(t *testing.T) {
+	field := NewField()
+	field.Grid[0][0] = Red
+	field.Grid[1][0] = Blue
+	field.Grid[FieldHeight-1][1] = Green
+
+	if !field.fallOneRow() {
+		t.Fatal("Expected floating puyos to move")
+	}
+	if field.Grid[1][0] != Red || field.Grid[2][0] != Blue || field.Grid[0][0] != Empty {
+		t.Error("Expected the column to move down exactly one row")
+	}
+	if field.Grid[FieldHeight-1][1] != Green {
+		t.Error("Landed puyo should not move")
+	}
+
+	for field.hasFloating() {
+		field.fallOneRow()
+	}
+	if field.fallOneRow() {
+		t.Error("Expected nothing to move once everything has landed")
+	}
+}
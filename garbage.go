@@ -0,0 +1,170 @@
+package main
+
+// Garbage and offsetting for versus play
+//
+// Chains send nuisance puyos to the opponent: every target point of chain
+// score is worth one nuisance puyo (see margin.go). Garbage on its way to a
+// player waits in their GarbageQueue, shown as a tray of icons over the
+// field. A chain first cancels the player's own pending garbage (offsetting) and only
+// sends what is left. Pending garbage falls when the player locks a piece
+// without starting a chain, at most MaxGarbageDrop puyos at a time.
+
+// MaxGarbageDrop is the most nuisance puyos falling at once, five rows on a standard field
+const MaxGarbageDrop = 30
+
+// GarbageQueue holds the nuisance puyos waiting to fall on a field
+type GarbageQueue struct {
+	Pending int `json:"pending"`
+}
+
+// Add queues n more nuisance puyos
+func (q *GarbageQueue) Add(n int) {
+	if n > 0 {
+		q.Pending += n
+	}
+}
+
+// Offset cancels pending garbage with n outgoing nuisance puyos
+// Returns how many were cancelled.
+func (q *GarbageQueue) Offset(n int) int {
+	cancelled := min(max(n, 0), q.Pending)
+	q.Pending -= cancelled
+	return cancelled
+}
+
+// Take removes up to limit pending puyos so they can fall
+func (q *GarbageQueue) Take(limit int) int {
+	n := min(q.Pending, limit)
+	q.Pending -= n
+	return n
+}
+
+// GarbageIcon is one kind of icon in the garbage tray
+type GarbageIcon struct {
+	Name  string
+	Units int    // Nuisance puyos the icon stands for
+	Glyph string // One or two columns wide
+	ASCII string // Used with the ASCII border
+}
+
+// garbageIcons lists the tray icons from the largest, as in Tsu
+var garbageIcons = []GarbageIcon{
+	{Name: "comet", Units: 720, Glyph: "☄", ASCII: "@"},
+	{Name: "crown", Units: 360, Glyph: "♛", ASCII: "W"},
+	{Name: "moon", Units: 180, Glyph: "☾", ASCII: "C"},
+	{Name: "star", Units: 30, Glyph: "★", ASCII: "*"},
+	{Name: "rock", Units: 6, Glyph: "●", ASCII: "O"},
+	{Name: "small", Units: 1, Glyph: "•", ASCII: "o"},
+}
+
+// Tray returns the icons for the pending garbage, largest first
+// At most width icons are returned, one per field column.
+func (q *GarbageQueue) Tray(width int) []GarbageIcon {
+	var tray []GarbageIcon
+	n := q.Pending
+	for _, icon := range garbageIcons {
+		for n >= icon.Units && len(tray) < width {
+			tray = append(tray, icon)
+			n -= icon.Units
+		}
+	}
+	return tray
+}
+
+// ReceiveGarbage queues nuisance puyos sent by the opponent
+func (g *Game) ReceiveGarbage(n int) {
+	if n <= 0 {
+		return
+	}
+	g.Garbage.Add(n)
+	g.emit(GarbageQueued{Count: n, Pending: g.Garbage.Pending})
+}
+
+// attack turns the points of a chain step into nuisance puyos
+// They cancel the player's own pending garbage first; the rest is sent.
+// A pending all clear adds AllClearNuisance to the attack.
+func (g *Game) attack(score int) {
+	n, rest := g.conversion().Convert(g.NuisanceScore+score, g.matchFrames())
+	g.NuisanceScore = rest
+	if g.AllClearPending {
+		n += AllClearNuisance
+		g.AllClearPending = false
+	}
+	if n == 0 {
+		return
+	}
+
+	offset := g.Garbage.Offset(n)
+	if offset > 0 {
+		g.ChainOffset = true
+	}
+	g.emit(GarbageSent{Count: n - offset, Offset: offset})
+}
+
+// dropGarbage lets up to MaxGarbageDrop pending puyos fall onto the field
+// Whole rows fall first and the rest land in random columns. The puyos are
+// placed at the top of the field and fall like any other puyo; anything
+// that does not fit is lost.
+func (g *Game) dropGarbage() {
+	n := g.Garbage.Take(MaxGarbageDrop)
+	if n == 0 {
+		return
+	}
+
+	width := g.Field.Width
+	counts := make([]int, width)
+	for x := range counts {
+		counts[x] = n / width
+	}
+	for _, x := range g.rand.Perm(width)[:n%width] {
+		counts[x]++
+	}
+
+	for x, count := range counts {
+		for y := 0; y < count && y < g.Field.Height; y++ {
+			if g.Field.Grid[y][x] == Empty {
+				g.Field.Grid[y][x] = Nuisance
+			}
+		}
+	}
+	g.State = StateDropping
+	g.emit(GarbageDropped{Count: n})
+}
+
+// adjacentNuisance returns the nuisance puyos next to any of the positions
+func (f *Field) adjacentNuisance(positions []Position) []Position {
+	seen := make(map[Position]bool)
+	var found []Position
+	for _, p := range positions {
+		for _, d := range directions {
+			n := Position{p.X + d.dx, p.Y + d.dy}
+			if f.InBounds(n.X, n.Y) && f.Grid[n.Y][n.X] == Nuisance && !seen[n] {
+				seen[n] = true
+				found = append(found, n)
+			}
+		}
+	}
+	return found
+}
+
+// LinkVersus connects two games so each one's attacks reach the other
+// The games share a new match clock for margin time. The returned function
+// disconnects them.
+func LinkVersus(a, b *Game) func() {
+	a.Versus, b.Versus = true, true
+	clock := &MatchClock{}
+	a.Clock, b.Clock = clock, clock
+	send := func(to *Game) func(Event) {
+		return func(ev Event) {
+			if sent, ok := ev.(GarbageSent); ok {
+				to.ReceiveGarbage(sent.Count)
+			}
+		}
+	}
+	stopA := a.Events.Subscribe(send(b))
+	stopB := b.Events.Subscribe(send(a))
+	return func() {
+		stopA()
+		stopB()
+	}
+}
@@ -0,0 +1,301 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+// lockWithoutChain locks a piece that pops nothing and resolves the field
+func lockWithoutChain(g *Game) {
+	g.Current = newPiece(PiecePair, Red, Green)
+	g.Current.Pos = Position{0, 1}
+	g.HardDrop()
+	g.LockPair()
+	for g.ProcessChainStep() {
+	}
+}
+
+// popFour puts a group of four red puyos on the bottom row
+func popFour(g *Game) {
+	for x := 0; x < 4; x++ {
+		g.Field.Grid[g.Field.Height-1][x] = Red
+	}
+}
+
+// countColor counts the puyos of a color on the field
+func countColor(f *Field, c Color) int {
+	n := 0
+	for y := range f.Grid {
+		for x := range f.Grid[y] {
+			if f.Grid[y][x] == c {
+				n++
+			}
+		}
+	}
+	return n
+}
+
+func TestGarbageQueueOffset(t *testing.T) {
+	var q GarbageQueue
+	q.Add(10)
+	q.Add(-3)
+
+	if got := q.Offset(4); got != 4 || q.Pending != 6 {
+		t.Errorf("Expected 4 cancelled and 6 pending, got %d and %d", got, q.Pending)
+	}
+	if got := q.Offset(20); got != 6 || q.Pending != 0 {
+		t.Errorf("Expected the rest cancelled, got %d and %d pending", got, q.Pending)
+	}
+	q.Add(40)
+	if got := q.Take(MaxGarbageDrop); got != 30 || q.Pending != 10 {
+		t.Errorf("Expected 30 taken and 10 pending, got %d and %d", got, q.Pending)
+	}
+}
+
+func TestGarbageTray(t *testing.T) {
+	q := GarbageQueue{Pending: 720 + 180 + 30 + 2*6 + 1}
+	var names []string
+	for _, icon := range q.Tray(FieldWidth) {
+		names = append(names, icon.Name)
+	}
+	want := []string{"comet", "moon", "star", "rock", "rock", "small"}
+	if len(names) != len(want) {
+		t.Fatalf("Expected %v, got %v", want, names)
+	}
+	for i := range want {
+		if names[i] != want[i] {
+			t.Fatalf("Expected %v, got %v", want, names)
+		}
+	}
+
+	if tray := (&GarbageQueue{Pending: 5}).Tray(3); len(tray) != 3 {
+		t.Errorf("Expected the tray to be cut to the field width, got %d icons", len(tray))
+	}
+}
+
+func TestChainOffsetsIncomingGarbage(t *testing.T) {
+	game := NewGameWithSeed(4, 1)
+	events := recordEvents(game)
+	game.ReceiveGarbage(5)
+	popFour(game)
+
+	runChain(game)
+
+	// 100 points is one nuisance puyo, with 30 points carried over
+	if game.Garbage.Pending != 4 || game.NuisanceScore != 30 {
+		t.Errorf("Expected 4 pending and 30 points left, got %d and %d", game.Garbage.Pending, game.NuisanceScore)
+	}
+	if !game.ChainOffset {
+		t.Error("Expected the chain to count as an offset")
+	}
+
+	found := false
+	for _, ev := range *events {
+		if sent, ok := ev.(GarbageSent); ok {
+			found = sent.Count == 0 && sent.Offset == 1
+		}
+	}
+	if !found {
+		t.Error("Expected a GarbageSent event with everything offset")
+	}
+}
+
+func TestLinkVersusSendsGarbage(t *testing.T) {
+	a := NewGameWithSeed(4, 1)
+	b := NewGameWithSeed(4, 2)
+	unlink := LinkVersus(a, b)
+	events := recordEvents(b)
+
+	a.NuisanceScore = 40 // With 100 more points, two nuisance puyos
+	popFour(a)
+	runChain(a)
+
+	if b.Garbage.Pending != 2 {
+		t.Fatalf("Expected 2 nuisance puyos sent, got %d", b.Garbage.Pending)
+	}
+	if len(*events) != 1 || (*events)[0] != (GarbageQueued{Count: 2, Pending: 2}) {
+		t.Errorf("Expected a GarbageQueued event, got %v", *events)
+	}
+
+	unlink()
+	popFour(a)
+	runChain(a)
+	if b.Garbage.Pending != 2 {
+		t.Error("Expected no garbage after unlinking")
+	}
+}
+
+func TestAllClearAddsNuisance(t *testing.T) {
+	game := NewGameWithSeed(4, 1)
+	game.AllClearPending = true
+	game.ReceiveGarbage(10)
+	events := recordEvents(game)
+	popFour(game)
+	game.Field.Grid[FieldHeight-1][5] = Blue
+
+	runChain(game)
+
+	if game.AllClearPending {
+		t.Error("Expected the all clear to be used by the attack")
+	}
+	for _, ev := range *events {
+		if sent, ok := ev.(GarbageSent); ok && (sent.Offset != 10 || sent.Count != AllClearNuisance+1-10) {
+			t.Errorf("Unexpected attack %+v", sent)
+		}
+	}
+}
+
+func TestGarbageFallsAfterLockWithoutChain(t *testing.T) {
+	game := NewGameWithSeed(4, 1)
+	events := recordEvents(game)
+	game.ReceiveGarbage(8)
+
+	lockWithoutChain(game)
+
+	if n := countColor(game.Field, Nuisance); n != 8 {
+		t.Errorf("Expected 8 nuisance puyos on the field, got %d", n)
+	}
+	if game.Garbage.Pending != 0 {
+		t.Errorf("Expected no garbage left, got %d", game.Garbage.Pending)
+	}
+	if game.Field.hasFloating() {
+		t.Error("Expected the garbage to land")
+	}
+	if game.State != StateNormal || game.Current == nil {
+		t.Error("Expected the next piece after the garbage")
+	}
+
+	dropped := false
+	for _, ev := range *events {
+		if ev == (GarbageDropped{Count: 8}) {
+			dropped = true
+		}
+	}
+	if !dropped {
+		t.Error("Expected a GarbageDropped event")
+	}
+
+	// A full row falls in every column
+	for x := 0; x < FieldWidth; x++ {
+		found := false
+		for y := 0; y < FieldHeight; y++ {
+			found = found || game.Field.Grid[y][x] == Nuisance
+		}
+		if !found {
+			t.Errorf("Expected nuisance in column %d", x)
+		}
+	}
+}
+
+func TestGarbageFallsAtMostLimit(t *testing.T) {
+	game := NewGameWithSeed(4, 1)
+	game.ReceiveGarbage(40)
+
+	lockWithoutChain(game)
+
+	if n := countColor(game.Field, Nuisance); n != MaxGarbageDrop {
+		t.Errorf("Expected %d nuisance puyos, got %d", MaxGarbageDrop, n)
+	}
+	if game.Garbage.Pending != 10 {
+		t.Errorf("Expected 10 left for the next piece, got %d", game.Garbage.Pending)
+	}
+}
+
+func TestGarbageWaitsDuringChain(t *testing.T) {
+	game := NewGameWithSeed(4, 1)
+	game.ReceiveGarbage(20)
+	for x := 1; x < 4; x++ {
+		game.Field.Grid[FieldHeight-1][x] = Red
+	}
+
+	// The pair completes the group of four
+	game.Current = newPiece(PiecePair, Red, Green)
+	game.Current.Pos = Position{0, 1}
+	game.HardDrop()
+	game.LockPair()
+	for game.ProcessChainStep() {
+	}
+
+	if n := countColor(game.Field, Nuisance); n != 0 {
+		t.Errorf("Expected no garbage to fall after a chain, got %d", n)
+	}
+	if game.Garbage.Pending != 19 {
+		t.Errorf("Expected 19 pending after the offset, got %d", game.Garbage.Pending)
+	}
+}
+
+func TestNuisanceClearedNextToGroup(t *testing.T) {
+	game := NewGameWithSeed(4, 1)
+	field, _, err := ParseFieldNotation(`
+N.....
+RRRRN.
+NNNNNN
+`, FieldWidth, FieldHeight)
+	if err != nil {
+		t.Fatal(err)
+	}
+	game.Field = field
+
+	runChain(game)
+
+	// The group takes the nuisance above, below and beside it, but not
+	// the puyos that only touch other nuisance
+	if n := countColor(game.Field, Nuisance); n != 2 {
+		t.Errorf("Expected 2 nuisance puyos left, got %d:\n%s", n, game.Field.Notation())
+	}
+	if game.MaxChain != 1 {
+		t.Errorf("Expected a single chain step, got %d", game.MaxChain)
+	}
+}
+
+func TestNuisanceDoesNotLink(t *testing.T) {
+	f := NewField()
+	f.Grid[FieldHeight-1][0] = Nuisance
+	f.Grid[FieldHeight-1][1] = Nuisance
+	if f.Links(0, FieldHeight-1) != 0 {
+		t.Error("Expected nuisance not to be drawn joined")
+	}
+}
+
+func TestFeverFillsOnOffsetsInVersus(t *testing.T) {
+	a := feverGame(1)
+	b := feverGame(2)
+	LinkVersus(a, b)
+
+	// Every chain is worth at least one nuisance puyo
+	a.NuisanceScore = DefaultTargetPoint - 1
+	popFour(a)
+	runChain(a)
+	if a.FeverGauge != 0 {
+		t.Errorf("Expected no fever charge without an offset, got %d", a.FeverGauge)
+	}
+
+	a.ReceiveGarbage(3)
+	a.NuisanceScore = DefaultTargetPoint - 1
+	popFour(a)
+	runChain(a)
+	if a.FeverGauge != 1 {
+		t.Errorf("Expected an offset to charge the gauge, got %d", a.FeverGauge)
+	}
+}
+
+func TestSuspendKeepsGarbage(t *testing.T) {
+	game := NewGameWithSeed(4, 1)
+	game.Versus = true
+	game.ReceiveGarbage(42)
+	game.NuisanceScore = 55
+
+	data, err := json.Marshal(game.Suspend())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var state SuspendState
+	if err := json.Unmarshal(data, &state); err != nil {
+		t.Fatal(err)
+	}
+	restored := mustRestore(t, &state)
+
+	if !restored.Versus || restored.Garbage.Pending != 42 || restored.NuisanceScore != 55 {
+		t.Errorf("Garbage not restored: %+v", state)
+	}
+}
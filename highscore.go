@@ -8,13 +8,15 @@ import (
 
 // HighScore represents a high score record
 type HighScore struct {
-	Score  int `json:"score"`
-	Level  int `json:"level"`
-	Chains int `json:"chains"`
+	Score     int `json:"score"`
+	Level     int `json:"level"`
+	Chains    int `json:"chains"`
+	AllClears int `json:"all_clears"`
 }
 
-// getHighScorePath returns the path to the high score file
-func getHighScorePath() (string, error) {
+// getConfigPath returns the path to a file in the ~/.puyo directory,
+// creating the directory if needed
+func getConfigPath(name string) (string, error) {
 	homeDir, err := os.UserHomeDir()
 	if err != nil {
 		return "", err
@@ -25,7 +27,12 @@ func getHighScorePath() (string, error) {
 		return "", err
 	}
 
-	return filepath.Join(configDir, "highscore.json"), nil
+	return filepath.Join(configDir, name), nil
+}
+
+// getHighScorePath returns the path to the high score file
+func getHighScorePath() (string, error) {
+	return getConfigPath("highscore.json")
 }
 
 // LoadHighScore loads the high score from disk
@@ -77,9 +84,10 @@ func UpdateHighScore(game *Game) (*HighScore, bool, error) {
 
 	if isNew {
 		newHS := &HighScore{
-			Score:  game.Score,
-			Level:  game.Level,
-			Chains: game.TotalChains,
+			Score:     game.Score,
+			Level:     game.Level,
+			Chains:    game.TotalChains,
+			AllClears: game.AllClears,
 		}
 
 		if err := SaveHighScore(newHS); err != nil {
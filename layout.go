@@ -0,0 +1,169 @@
+package main
+
+import (
+	"github.com/mattn/go-runewidth"
+	"strings"
+)
+
+// LayoutMode selects how the game screen is arranged
+type LayoutMode int
+
+const (
+	LayoutNormal  LayoutMode = iota // Title and score above the field, next and controls beside it
+	LayoutCompact                   // Score and next beside the field, no title or controls
+	LayoutDouble                    // Like normal, with every cell drawn twice as large
+)
+
+// Layout sizes
+const (
+	headerHeight   = 7  // Title, blank line, score lines and high score above the field
+	sideGap        = 3  // Columns between the field and the side panel
+	sideWidth      = 24 // Next pair and controls
+	compactGap     = 2
+	compactWidth   = 16 // Score lines and next pair
+	layoutMargin   = 1  // Minimum space kept around the content
+	doubleCellW    = 4
+	doubleCellH    = 2
+	normalCellW    = 2
+	normalCellH    = 1
+	compactInfoRow = 6 // Rows from the top of the compact side panel to the next pair
+)
+
+// Layout holds the screen positions of the game UI, computed from the screen size
+type Layout struct {
+	Mode         LayoutMode
+	Cols, Rows   int // Field size in cells
+	CellW, CellH int // Screen columns and rows per field cell
+	FieldX       int // Top-left corner of the field border
+	FieldY       int
+	InfoX        int // Score lines
+	InfoY        int
+	NextX        int // Next pair
+	NextY        int
+	ControlsY    int // Controls list below the next pair
+	TooSmall     bool
+	MinWidth     int // Smallest screen that fits the compact layout
+	MinHeight    int
+}
+
+// computeLayout arranges the game UI for a cols x rows field on a
+// width x height screen
+// The largest mode that fits is used, and the content is centered.
+func computeLayout(width, height, cols, rows int) Layout {
+	minW, minH := layoutSize(LayoutCompact, cols, rows)
+	l := Layout{
+		Cols:      cols,
+		Rows:      rows,
+		MinWidth:  minW + 2*layoutMargin,
+		MinHeight: minH + 2*layoutMargin,
+	}
+
+	mode, ok := LayoutCompact, false
+	for _, m := range []LayoutMode{LayoutDouble, LayoutNormal, LayoutCompact} {
+		w, h := layoutSize(m, cols, rows)
+		if w+2*layoutMargin <= width && h+2*layoutMargin <= height {
+			mode, ok = m, true
+			break
+		}
+	}
+	if !ok {
+		l.TooSmall = true
+		return l
+	}
+
+	contentW, contentH := layoutSize(mode, cols, rows)
+	left := (width - contentW) / 2
+	top := (height - contentH) / 2
+
+	l.Mode = mode
+	l.CellW, l.CellH = normalCellW, normalCellH
+	if mode == LayoutDouble {
+		l.CellW, l.CellH = doubleCellW, doubleCellH
+	}
+	fieldW, _ := l.fieldSize()
+
+	switch mode {
+	case LayoutCompact:
+		l.FieldX, l.FieldY = left, top
+		l.InfoX = left + fieldW + compactGap
+		l.InfoY = top + 1
+		l.NextX = l.InfoX
+		l.NextY = l.InfoY + compactInfoRow
+	default:
+		l.InfoX, l.InfoY = left, top+2
+		l.FieldX, l.FieldY = left, top+headerHeight
+		l.NextX = left + fieldW + sideGap
+		l.NextY = l.FieldY + 2
+		l.ControlsY = l.NextY + 2*l.CellH + 3
+	}
+
+	return l
+}
+
+// layoutSize returns the screen columns and rows a layout mode needs for
+// a cols x rows field
+func layoutSize(mode LayoutMode, cols, rows int) (int, int) {
+	l := Layout{Cols: cols, Rows: rows, CellW: normalCellW, CellH: normalCellH}
+	if mode == LayoutDouble {
+		l.CellW, l.CellH = doubleCellW, doubleCellH
+	}
+	fieldW, fieldH := l.fieldSize()
+
+	// The side panel may be taller than a small field
+	if mode == LayoutCompact {
+		return fieldW + compactGap + compactWidth, max(fieldH, compactInfoRow+4)
+	}
+	return fieldW + sideGap + sideWidth, headerHeight + max(fieldH, sidePanelHeight(l.CellH))
+}
+
+// sidePanelHeight returns the rows from the top of the field to the end of the controls
+func sidePanelHeight(cellH int) int {
+	return 2 + 2*cellH + 3 + 7
+}
+
+// fieldSize returns the size of the field including its border
+func (l Layout) fieldSize() (int, int) {
+	return l.Cols*l.CellW + 2, l.Rows*l.CellH + 2
+}
+
+// CellX returns the screen column of field column x
+func (l Layout) CellX(x int) int {
+	return l.FieldX + 1 + x*l.CellW
+}
+
+// CellY returns the screen row of field row y
+func (l Layout) CellY(y int) int {
+	return l.FieldY + 1 + y*l.CellH
+}
+
+// scaleCell widens a two-column cell to the layout's cell width
+// Every character is repeated, so wide runes (emoji) stay whole.
+func (l Layout) scaleCell(text string) string {
+	n := l.CellW / normalCellW
+	if n <= 1 {
+		return text
+	}
+
+	var b strings.Builder
+	var cluster []rune
+	flush := func() {
+		for i := 0; i < n; i++ {
+			b.WriteString(string(cluster))
+		}
+		cluster = cluster[:0]
+	}
+	for _, r := range text {
+		if runewidth.RuneWidth(r) == 0 && len(cluster) > 0 {
+			cluster = append(cluster, r)
+			continue
+		}
+		if len(cluster) > 0 {
+			flush()
+		}
+		cluster = append(cluster, r)
+	}
+	if len(cluster) > 0 {
+		flush()
+	}
+	return b.String()
+}
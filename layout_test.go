@@ -0,0 +1,143 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestComputeLayoutModes(t *testing.T) {
+	tests := []struct {
+		width, height int
+		expected      LayoutMode
+		tooSmall      bool
+	}{
+		{120, 50, LayoutDouble, false},
+		{80, 30, LayoutNormal, false},
+		{80, 24, LayoutNormal, false},
+		{40, 20, LayoutCompact, false},
+		{80, 16, LayoutCompact, false},
+		{30, 20, LayoutCompact, true},
+		{80, 10, LayoutCompact, true},
+	}
+
+	for _, tt := range tests {
+		l := computeLayout(tt.width, tt.height, FieldWidth, FieldHeight)
+		if l.TooSmall != tt.tooSmall {
+			t.Errorf("computeLayout(%d, %d).TooSmall = %v, want %v", tt.width, tt.height, l.TooSmall, tt.tooSmall)
+			continue
+		}
+		if !l.TooSmall && l.Mode != tt.expected {
+			t.Errorf("computeLayout(%d, %d).Mode = %v, want %v", tt.width, tt.height, l.Mode, tt.expected)
+		}
+	}
+}
+
+func TestComputeLayoutCentered(t *testing.T) {
+	for _, size := range [][2]int{{80, 24}, {200, 60}, {44, 18}} {
+		width, height := size[0], size[1]
+		l := computeLayout(width, height, FieldWidth, FieldHeight)
+		contentW, contentH := layoutSize(l.Mode, FieldWidth, FieldHeight)
+
+		left := l.FieldX
+		top := l.FieldY
+		if l.Mode != LayoutCompact {
+			top -= headerHeight
+		}
+		if d := (width - contentW) - 2*left; d < 0 || d > 1 {
+			t.Errorf("%dx%d: content not centered horizontally (left %d, width %d)", width, height, left, contentW)
+		}
+		if d := (height - contentH) - 2*top; d < 0 || d > 1 {
+			t.Errorf("%dx%d: content not centered vertically (top %d, height %d)", width, height, top, contentH)
+		}
+	}
+}
+
+func TestComputeLayoutFits(t *testing.T) {
+	for width := 20; width <= 140; width += 3 {
+		for height := 8; height <= 60; height += 2 {
+			l := computeLayout(width, height, FieldWidth, FieldHeight)
+			if l.TooSmall {
+				continue
+			}
+			fieldW, fieldH := l.fieldSize()
+			if l.FieldX < 0 || l.FieldY < 0 || l.FieldX+fieldW > width || l.FieldY+fieldH > height {
+				t.Fatalf("%dx%d: field at (%d, %d) does not fit", width, height, l.FieldX, l.FieldY)
+			}
+			panelW := sideWidth
+			if l.Mode == LayoutCompact {
+				panelW = compactWidth
+			}
+			if l.InfoY < 0 || l.NextX+panelW > width {
+				t.Fatalf("%dx%d: side panel does not fit", width, height)
+			}
+		}
+	}
+}
+
+func TestScaleCell(t *testing.T) {
+	double := Layout{Cols: FieldWidth, Rows: FieldHeight, CellW: doubleCellW, CellH: doubleCellH}
+	normal := Layout{Cols: FieldWidth, Rows: FieldHeight, CellW: normalCellW, CellH: normalCellH}
+
+	tests := []struct {
+		layout   Layout
+		text     string
+		expected string
+	}{
+		{normal, "● ", "● "},
+		{double, "● ", "●●  "},
+		{double, "██", "████"},
+		{double, Red.String(), Red.String() + Red.String()},
+	}
+
+	for _, tt := range tests {
+		if got := tt.layout.scaleCell(tt.text); got != tt.expected {
+			t.Errorf("scaleCell(%q) = %q, want %q", tt.text, got, tt.expected)
+		}
+	}
+}
+
+func TestDrawDoubleLayout(t *testing.T) {
+	screen := newTestScreen(t)
+	screen.SetSize(120, 50)
+
+	game := NewGame()
+	game.Field.Grid[FieldHeight-1][0] = Red
+
+	ui := newUIWithScreen(screen, game)
+	ui.Settings.Connected = false
+	ui.Draw()
+
+	l := ui.layout()
+	if l.Mode != LayoutDouble {
+		t.Fatalf("Expected double layout, got %v", l.Mode)
+	}
+
+	// A puyo fills two rows of two glyphs
+	for row := 0; row < l.CellH; row++ {
+		for col := 0; col < 2; col++ {
+			if got := cellRune(screen, l.CellX(0)+col, l.CellY(FieldHeight-1)+row); got != '●' {
+				t.Errorf("Cell (%d, %d): expected puyo glyph, got %q", col, row, got)
+			}
+		}
+	}
+	if got := cellRune(screen, l.CellX(FieldWidth), l.CellY(0)); got != '│' {
+		t.Errorf("Expected right border, got %q", got)
+	}
+}
+
+func TestDrawTooSmall(t *testing.T) {
+	defer SetLanguage(fallbackLanguage)
+	SetLanguage("en")
+
+	screen := newTestScreen(t)
+	screen.SetSize(20, 10)
+
+	ui := newUIWithScreen(screen, NewGame())
+	ui.Draw()
+
+	text := T("game.too_small")
+	for i, r := range text {
+		if got := cellRune(screen, i, 0); got != r {
+			t.Fatalf("Expected too small message, got %q at column %d", got, i)
+		}
+	}
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+)
+
+// LeaderboardSize is the number of runs kept on each leaderboard
+const LeaderboardSize = 10
+
+// LeaderboardEntry is one finished timed run
+type LeaderboardEntry struct {
+	Player   string    `json:"player"`
+	Frames   int       `json:"frames"` // Time taken, or the time limit for ultra
+	Score    int       `json:"score"`
+	MaxChain int       `json:"max_chain"`
+	Date     time.Time `json:"date"`
+}
+
+// Leaderboards holds the best runs of every timed mode, keyed by Rules.LeaderboardKey
+type Leaderboards struct {
+	Boards map[string][]LeaderboardEntry `json:"boards"`
+}
+
+// NewLeaderboards creates empty leaderboards
+func NewLeaderboards() *Leaderboards {
+	return &Leaderboards{Boards: make(map[string][]LeaderboardEntry)}
+}
+
+// Add ranks a run on the leaderboard for its rules
+// Sprints are ranked by time and ultra by score. Returns the run's place,
+// starting at 1, or 0 if it did not make the board.
+func (l *Leaderboards) Add(rules Rules, entry LeaderboardEntry) int {
+	key := rules.LeaderboardKey()
+	if key == "" {
+		return 0
+	}
+	if l.Boards == nil {
+		l.Boards = make(map[string][]LeaderboardEntry)
+	}
+
+	better := func(a, b LeaderboardEntry) bool {
+		if rules.Goal == GoalUltra {
+			return a.Score > b.Score
+		}
+		return a.Frames < b.Frames
+	}
+
+	board := append(l.Boards[key], entry)
+	sort.SliceStable(board, func(i, j int) bool { return better(board[i], board[j]) })
+
+	rank := 0
+	for i, e := range board {
+		if e == entry {
+			rank = i + 1
+			break
+		}
+	}
+	if len(board) > LeaderboardSize {
+		board = board[:LeaderboardSize]
+	}
+	l.Boards[key] = board
+
+	if rank > LeaderboardSize {
+		return 0
+	}
+	return rank
+}
+
+// Board returns the ranked runs for a rules variant
+func (l *Leaderboards) Board(rules Rules) []LeaderboardEntry {
+	return l.Boards[rules.LeaderboardKey()]
+}
+
+// getLeaderboardsPath returns the path to the leaderboards file
+func getLeaderboardsPath() (string, error) {
+	return getConfigPath("leaderboards.json")
+}
+
+// LoadLeaderboards loads the leaderboards from disk
+// A corrupt file is an error, so recording a run never wipes the boards.
+func LoadLeaderboards() (*Leaderboards, error) {
+	path, err := getLeaderboardsPath()
+	if err != nil {
+		return NewLeaderboards(), nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return NewLeaderboards(), nil
+		}
+		return nil, err
+	}
+
+	l := NewLeaderboards()
+	if err := json.Unmarshal(data, l); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if l.Boards == nil {
+		l.Boards = make(map[string][]LeaderboardEntry)
+	}
+	return l, nil
+}
+
+// SaveLeaderboards saves the leaderboards to disk
+func SaveLeaderboards(l *Leaderboards) error {
+	path, err := getLeaderboardsPath()
+	if err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(l, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, data, 0644)
+}
+
+// RecordRun adds a timed run that reached its goal to the stored leaderboards
+// Returns the run's place, or 0 if it was not ranked.
+func RecordRun(player string, game *Game) (int, error) {
+	if !game.Cleared || game.Rules.LeaderboardKey() == "" {
+		return 0, nil
+	}
+
+	l, err := LoadLeaderboards()
+	if err != nil {
+		return 0, err
+	}
+
+	rank := l.Add(game.Rules, LeaderboardEntry{
+		Player:   player,
+		Frames:   game.Frames,
+		Score:    game.Score,
+		MaxChain: game.MaxChain,
+		Date:     time.Now().Truncate(time.Second),
+	})
+	if rank == 0 {
+		return 0, nil
+	}
+
+	return rank, SaveLeaderboards(l)
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"os"
+	"testing"
+)
+
+func TestLeaderboardRanksSprintsByTime(t *testing.T) {
+	rules := RulesByName("sprint-chain")
+	l := NewLeaderboards()
+
+	if rank := l.Add(rules, LeaderboardEntry{Player: "a", Frames: 600}); rank != 1 {
+		t.Errorf("Expected the first run to rank 1, got %d", rank)
+	}
+	if rank := l.Add(rules, LeaderboardEntry{Player: "b", Frames: 900}); rank != 2 {
+		t.Errorf("Expected a slower run to rank 2, got %d", rank)
+	}
+	if rank := l.Add(rules, LeaderboardEntry{Player: "c", Frames: 300}); rank != 1 {
+		t.Errorf("Expected a faster run to rank 1, got %d", rank)
+	}
+
+	board := l.Board(rules)
+	if len(board) != 3 || board[0].Player != "c" || board[2].Player != "b" {
+		t.Errorf("Unexpected board %+v", board)
+	}
+	if len(l.Board(RulesByName("sprint-score"))) != 0 {
+		t.Error("Expected each mode to have its own board")
+	}
+}
+
+func TestLeaderboardRanksUltraByScore(t *testing.T) {
+	rules := RulesByName("ultra")
+	l := NewLeaderboards()
+	l.Add(rules, LeaderboardEntry{Player: "a", Score: 1000})
+	if rank := l.Add(rules, LeaderboardEntry{Player: "b", Score: 5000}); rank != 1 {
+		t.Errorf("Expected the higher score to rank 1, got %d", rank)
+	}
+}
+
+func TestLeaderboardSizeLimit(t *testing.T) {
+	rules := RulesByName("sprint-chain")
+	l := NewLeaderboards()
+	for i := 0; i < LeaderboardSize; i++ {
+		l.Add(rules, LeaderboardEntry{Frames: 100 + i})
+	}
+
+	if rank := l.Add(rules, LeaderboardEntry{Frames: 1000}); rank != 0 {
+		t.Errorf("Expected a run slower than the whole board not to rank, got %d", rank)
+	}
+	if len(l.Board(rules)) != LeaderboardSize {
+		t.Errorf("Expected %d runs kept, got %d", LeaderboardSize, len(l.Board(rules)))
+	}
+}
+
+func TestRecordRun(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	game := NewGameWithRules(RulesByName("sprint-chain"), 1)
+	game.Frames = 1234
+	game.Score = 5000
+
+	// Runs that did not reach the goal are not ranked
+	if rank, err := RecordRun("alice", game); err != nil || rank != 0 {
+		t.Fatalf("Expected no rank for an unfinished run, got %d (%v)", rank, err)
+	}
+
+	game.Cleared = true
+	if rank, err := RecordRun("alice", game); err != nil || rank != 1 {
+		t.Fatalf("Expected rank 1, got %d (%v)", rank, err)
+	}
+
+	l, err := LoadLeaderboards()
+	if err != nil {
+		t.Fatal(err)
+	}
+	board := l.Board(game.Rules)
+	if len(board) != 1 || board[0].Player != "alice" || board[0].Frames != 1234 || board[0].Score != 5000 {
+		t.Errorf("Unexpected stored board %+v", board)
+	}
+}
+
+func TestRecordRunKeepsCorruptFile(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	path, _ := getLeaderboardsPath()
+	if err := os.WriteFile(path, []byte(`{"boards": `), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	game := NewGameWithRules(RulesByName("sprint-chain"), 1)
+	game.Cleared = true
+	if _, err := RecordRun("alice", game); err == nil {
+		t.Error("Expected an error for a corrupt leaderboards file")
+	}
+	if data, _ := os.ReadFile(path); string(data) != `{"boards": ` {
+		t.Errorf("Expected the corrupt file to be left alone, got %q", data)
+	}
+}
@@ -3,9 +3,59 @@ package main
 import (
 	"fmt"
 	"log"
+	"os"
+	"time"
 )
 
 func main() {
+	os.Exit(runCLI(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// loadSettings loads the settings and everything they refer to
+// Problems are logged and the defaults used instead.
+func loadSettings() *Settings {
+	settings, err := LoadSettings()
+	if err != nil {
+		log.Printf("Warning: Could not load settings: %v", err)
+		settings = DefaultSettings()
+	}
+	SetLanguage(settings.Language)
+
+	// Load user themes from ~/.puyo/themes
+	if err := LoadUserThemes(); err != nil {
+		log.Printf("Warning: %v", err)
+	}
+
+	// Load user missions from ~/.puyo/missions
+	if err := LoadUserMissions(); err != nil {
+		log.Printf("Warning: %v", err)
+	}
+	return settings
+}
+
+// playFromMenu shows the main menu and plays the chosen game
+func playFromMenu(settings *Settings) int {
+	choice := showMainMenu(settings)
+	if choice.Action == MenuQuit {
+		return ExitOK
+	}
+
+	// Remember the mode for the next launch
+	newGame := choice.Action == MenuPlay && choice.Mission == "" && choice.ColorCount != 0
+	if newGame && (choice.ColorCount != settings.ColorCount || choice.Rules != settings.Rules) {
+		settings.ColorCount = choice.ColorCount
+		settings.Rules = choice.Rules
+		if err := SaveSettings(settings); err != nil {
+			log.Printf("Warning: Could not save settings: %v", err)
+		}
+	}
+	return playGame(settings, choice, time.Now().UnixNano())
+}
+
+// playGame runs the chosen game until the player quits, then records and
+// prints the result
+// Returns the exit code for the command.
+func playGame(settings *Settings, choice MenuResult, seed int64) int {
 	// Load high score
 	highScore, err := LoadHighScore()
 	if err != nil {
@@ -13,44 +63,209 @@ func main() {
 		highScore = &HighScore{}
 	}
 
-	// Show color selection menu
-	colorCount := showColorSelectionMenu()
-
-	// Create new game with selected color count
-	game := NewGameWithColors(colorCount)
+	var game *Game
+	if choice.Action == MenuContinue {
+		game, err = LoadSuspend()
+		if err != nil {
+			log.Printf("Warning: Could not load suspended game: %v", err)
+		}
+		if err := ClearSuspend(); err != nil {
+			log.Printf("Warning: Could not remove suspended game: %v", err)
+		}
+	}
+	if game == nil && choice.Mission != "" {
+		// Missions bring their own rules and field
+		if m := MissionByID(choice.Mission); m != nil {
+			game = NewGameWithRules(m.GameRules(), seed)
+			game.ApplySettings(settings)
+		}
+	}
+	if game == nil {
+		// Create new game with the selected rules and color count
+		colorCount := choice.ColorCount
+		if colorCount == 0 {
+			colorCount = 4
+		}
+		rules := RulesByName(choice.Rules)
+		rules.Colors = colorCount
+		game = NewGameWithRules(rules, seed)
+		game.ApplySettings(settings)
+	}
 	game.HighScore = highScore
 
 	// Create UI
 	ui, err := NewUI(game)
 	if err != nil {
-		log.Fatalf("Failed to initialize UI: %v", err)
+		log.Printf("Failed to initialize UI: %v", err)
+		return ExitError
 	}
 	defer ui.Close()
+	ui.Settings = settings
+
+	// Sound effects
+	sound, closeSound, err := OpenSound(settings, ui.screen.Beep)
+	if err != nil {
+		log.Printf("Warning: %v", err)
+	}
+	defer func() {
+		if err := closeSound(); err != nil {
+			log.Printf("Warning: Could not close sound output: %v", err)
+		}
+	}()
+	if sound != nil {
+		game.Events.Subscribe(SoundHandler(sound))
+	}
+
+	// Results go to stderr when stdout carries the PCM stream
+	out := os.Stdout
+	if pcm, ok := sound.(*PCMPlayer); ok && pcm.w == os.Stdout {
+		out = os.Stderr
+	}
+
+	// Analyze every game when the report is turned on
+	var analyzer *Analyzer
+	if settings.Report {
+		analyzer = NewAnalyzer(game)
+		ui.OnGameStart = analyzer.Reset
+	}
+
+	// Record games finished before a restart
+	player := currentPlayerName()
+	ui.OnGameEnd = func(g *Game) {
+		recordStats(player, g)
+		if analyzer != nil && g.PiecesPlaced > 0 {
+			saveReport(analyzer.Report())
+		}
+		if g.Rules.Goal == GoalMission {
+			recordMission(g)
+		} else if g.Rules.Timed() {
+			recordRun(player, g)
+		} else if _, _, err := UpdateHighScore(g); err != nil {
+			log.Printf("Warning: Could not save high score: %v", err)
+		}
+	}
 
 	// Run game
 	ui.Run()
 
-	// Save high score if game is over
-	if game.GameOver {
-		newHS, isNew, err := UpdateHighScore(game)
-		if err != nil {
-			log.Printf("Warning: Could not save high score: %v", err)
-		} else if isNew {
-			fmt.Printf("\n🎉 New High Score! Score: %d, Level: %d, Chains: %d\n", newHS.Score, newHS.Level, newHS.Chains)
+	// The UI replaces the game on restart
+	game = ui.game
+
+	// Suspend an unfinished game so it can be continued from the menu
+	if !game.GameOver {
+		if err := SaveSuspend(game); err != nil {
+			log.Printf("Warning: Could not suspend game: %v", err)
+		}
+		return ExitOK
+	}
+	recordStats(player, game)
+	if analyzer != nil && game.PiecesPlaced > 0 {
+		report := analyzer.Report()
+		report.WriteText(out)
+		if path := saveReport(report); path != "" {
+			fmt.Fprintf(out, "%s\n", T("report.saved", path))
+		}
+	}
+
+	// Missions keep their own records
+	if game.Rules.Goal == GoalMission {
+		best := recordMission(game)
+		if game.Cleared {
+			fmt.Fprintf(out, "\n%s\n", T("result.mission", formatFrames(game.Frames)))
 		} else {
-			fmt.Printf("\nGame Over! Score: %d, Level: %d, Chains: %d\n", game.Score, game.Level, game.TotalChains)
-			fmt.Printf("High Score: %d\n", newHS.Score)
+			fmt.Fprintf(out, "\n%s\n", T("result.failed"))
 		}
+		if best {
+			fmt.Fprintf(out, "%s\n", T("result.best_time"))
+		}
+		return clearedExitCode(game)
+	}
+
+	// Timed runs go on their mode's leaderboard instead of the high score
+	if game.Rules.Timed() {
+		rank := recordRun(player, game)
+		switch {
+		case game.Cleared && game.Rules.Goal == GoalUltra:
+			fmt.Fprintf(out, "\n%s\n", T("result.time_up", game.Score))
+		case game.Cleared:
+			fmt.Fprintf(out, "\n%s\n", T("result.finish", formatFrames(game.Frames), game.Score))
+		default:
+			fmt.Fprintf(out, "\n%s\n", T("result.game_over", game.Score, game.Level, game.TotalChains))
+		}
+		if rank > 0 {
+			fmt.Fprintf(out, "%s\n", T("result.rank", rank))
+		}
+		return clearedExitCode(game)
+	}
+
+	// Save high score
+	newHS, isNew, err := UpdateHighScore(game)
+	if err != nil {
+		log.Printf("Warning: Could not save high score: %v", err)
+	} else if isNew {
+		fmt.Fprintf(out, "\n%s\n", T("result.new_high", newHS.Score, newHS.Level, newHS.Chains))
+	} else {
+		fmt.Fprintf(out, "\n%s\n", T("result.game_over", game.Score, game.Level, game.TotalChains))
+		fmt.Fprintf(out, "%s\n", T("result.high_score", newHS.Score))
+	}
+	return ExitOK
+}
+
+// clearedExitCode returns the exit code for a finished mission or timed run
+func clearedExitCode(game *Game) int {
+	if !game.Cleared {
+		return ExitNotCleared
+	}
+	return ExitOK
+}
+
+// recordStats adds a game to the player's lifetime statistics
+func recordStats(player string, game *Game) {
+	// Skip games that were quit before anything was placed
+	if game.PiecesPlaced == 0 {
+		return
+	}
+	if _, err := UpdateStats(player, game); err != nil {
+		log.Printf("Warning: Could not save stats: %v", err)
+	}
+}
+
+// recordRun adds a finished timed run to its leaderboard and returns its place
+func recordRun(player string, game *Game) int {
+	rank, err := RecordRun(player, game)
+	if err != nil {
+		log.Printf("Warning: Could not save leaderboard: %v", err)
+	}
+	return rank
+}
+
+// recordMission adds a finished mission to the mission records
+// Returns true if it set a new best time.
+func recordMission(game *Game) bool {
+	best, err := RecordMission(game)
+	if err != nil {
+		log.Printf("Warning: Could not save mission records: %v", err)
+	}
+	return best
+}
+
+// saveReport saves an analysis report and returns its path, "" on failure
+func saveReport(report *Report) string {
+	path, err := SaveReport(report)
+	if err != nil {
+		log.Printf("Warning: Could not save analysis report: %v", err)
+		return ""
 	}
+	return path
 }
 
-func showColorSelectionMenu() int {
+func showMainMenu(settings *Settings) MenuResult {
 	screen, err := NewScreen()
 	if err != nil {
 		log.Printf("Warning: Could not initialize screen for menu: %v", err)
-		return 4 // Default to 4 colors
+		return MenuResult{Action: MenuPlay, ColorCount: settings.ColorCount}
 	}
 	defer screen.Close()
 
-	return screen.ShowMenu()
+	return screen.ShowMenu(HasSuspend(), settings)
 }
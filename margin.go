@@ -0,0 +1,107 @@
+package main
+
+// Margin time
+//
+// In long versus matches the target point, the chain points worth one
+// nuisance puyo, drops as the match clock runs, so the same chains send more
+// and more garbage until someone tops out. How points turn into nuisance is
+// the game's Conversion policy; the rules build a MarginTime policy, which
+// can be replaced with any other conversion.
+
+// MatchClock counts the frames of a versus match
+// Both players of a match share one clock, which the versus loop ticks once
+// per frame of play through TickMatch.
+type MatchClock struct {
+	Frames int `json:"frames"`
+}
+
+// Tick advances the clock by one frame
+func (c *MatchClock) Tick() {
+	c.Frames++
+}
+
+// TickMatch counts one frame of a versus match: both players' play time and
+// their shared match clock, once
+// The clock stops while the match is paused or over. Returns true when
+// either timer display changes.
+func TickMatch(a, b *Game) bool {
+	redraw := a.TickTimer()
+	redraw = b.TickTimer() || redraw
+	if a.Clock != nil && !a.GameOver && !b.GameOver && !a.Paused && !b.Paused {
+		a.Clock.Tick()
+	}
+	return redraw
+}
+
+// NuisancePolicy converts chain points into nuisance puyos
+type NuisancePolicy interface {
+	// TargetPoint returns the points worth one nuisance puyo at a match time
+	TargetPoint(frames int) int
+	// Convert returns the nuisance puyos for points at a match time and
+	// the points left over for the next chain step
+	Convert(points, frames int) (nuisance, rest int)
+}
+
+// MarginTime is the Tsu conversion: one nuisance puyo per Target points,
+// with the target dropping to three quarters at Start and then every
+// Interval frames, down to 1
+// A zero Start keeps the target fixed.
+type MarginTime struct {
+	Target   int // Points per nuisance puyo before margin time
+	Start    int // Frames of match time before the target starts to drop
+	Interval int // Frames between drops
+}
+
+// TargetPoint returns the points worth one nuisance puyo at a match time
+func (m MarginTime) TargetPoint(frames int) int {
+	target := max(m.Target, 1)
+	if m.Start <= 0 || frames < m.Start {
+		return target
+	}
+
+	steps := 1
+	if m.Interval > 0 {
+		steps += (frames - m.Start) / m.Interval
+	}
+	for i := 0; i < steps && target > 1; i++ {
+		target = max(target*3/4, 1)
+	}
+	return target
+}
+
+// Convert returns the nuisance puyos for points at a match time and the rest
+func (m MarginTime) Convert(points, frames int) (int, int) {
+	target := m.TargetPoint(frames)
+	return points / target, points % target
+}
+
+// nuisancePolicy returns the conversion the rules describe
+func (r Rules) nuisancePolicy() NuisancePolicy {
+	return MarginTime{
+		Target:   r.TargetPoint,
+		Start:    r.MarginTime * 60,
+		Interval: r.MarginInterval * 60,
+	}
+}
+
+// matchFrames returns the match time margin time is measured in
+// Without a shared match clock, the game's own play time is used.
+func (g *Game) matchFrames() int {
+	if g.Clock != nil {
+		return g.Clock.Frames
+	}
+	return g.Frames
+}
+
+// TargetPoint returns the points currently worth one nuisance puyo
+func (g *Game) TargetPoint() int {
+	return g.conversion().TargetPoint(g.matchFrames())
+}
+
+// conversion returns the game's conversion policy, falling back to the rules
+func (g *Game) conversion() NuisancePolicy {
+	if g.Conversion == nil {
+		g.Conversion = g.Rules.nuisancePolicy()
+	}
+	return g.Conversion
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+// fixedPolicy sends a fixed number of nuisance puyos for any chain step
+type fixedPolicy struct{ n int }
+
+func (p fixedPolicy) TargetPoint(int) int         { return 1 }
+func (p fixedPolicy) Convert(int, int) (int, int) { return p.n, 0 }
+
+func TestMarginTimeSchedule(t *testing.T) {
+	m := MarginTime{Target: 70, Start: 96 * 60, Interval: 16 * 60}
+	tests := []struct {
+		seconds int
+		want    int
+	}{
+		{0, 70},
+		{95, 70},
+		{96, 52},
+		{111, 52},
+		{112, 39},
+		{128, 29},
+		{600, 1},
+	}
+	for _, tt := range tests {
+		if got := m.TargetPoint(tt.seconds * 60); got != tt.want {
+			t.Errorf("At %d seconds: expected target %d, got %d", tt.seconds, tt.want, got)
+		}
+	}
+
+	if got := (MarginTime{Target: 70}).TargetPoint(3600 * 60); got != 70 {
+		t.Errorf("Expected a fixed target without margin time, got %d", got)
+	}
+
+	if n, rest := m.Convert(150, 0); n != 2 || rest != 10 {
+		t.Errorf("Expected 2 nuisance and 10 points left, got %d and %d", n, rest)
+	}
+	if n, rest := m.Convert(150, 96*60); n != 2 || rest != 46 {
+		t.Errorf("Expected 2 nuisance and 46 points left in margin time, got %d and %d", n, rest)
+	}
+}
+
+func TestMarginTimeSharesMatchClock(t *testing.T) {
+	a := NewGameWithSeed(4, 1)
+	b := NewGameWithSeed(4, 2)
+	LinkVersus(a, b)
+	if a.Clock == nil || a.Clock != b.Clock {
+		t.Fatal("Expected the players to share a match clock")
+	}
+
+	for i := 0; i < 112*60; i++ {
+		a.Clock.Tick()
+	}
+	if a.TargetPoint() != 39 || b.TargetPoint() != 39 {
+		t.Errorf("Expected both targets at 39, got %d and %d", a.TargetPoint(), b.TargetPoint())
+	}
+
+	// 100 points at a target of 39
+	popFour(a)
+	runChain(a)
+	if b.Garbage.Pending != 2 || a.NuisanceScore != 22 {
+		t.Errorf("Expected 2 nuisance sent and 22 points left, got %d and %d", b.Garbage.Pending, a.NuisanceScore)
+	}
+}
+
+func TestTickMatchTicksClockOnce(t *testing.T) {
+	a := NewGameWithSeed(4, 1)
+	b := NewGameWithSeed(4, 2)
+	LinkVersus(a, b)
+
+	TickMatch(a, b)
+	if a.Clock.Frames != 1 {
+		t.Errorf("Expected one frame of match time, got %d", a.Clock.Frames)
+	}
+	if a.Frames != 1 || b.Frames != 1 {
+		t.Errorf("Expected each player to play one frame, got %d and %d", a.Frames, b.Frames)
+	}
+
+	// Ticking a player alone leaves the match clock alone
+	a.TickTimer()
+	if a.Clock.Frames != 1 {
+		t.Errorf("Expected TickTimer not to tick the match clock, got %d", a.Clock.Frames)
+	}
+
+	b.GameOver = true
+	TickMatch(a, b)
+	if a.Clock.Frames != 1 {
+		t.Errorf("Expected the clock to stop when the match is over, got %d", a.Clock.Frames)
+	}
+}
+
+func TestConversionPolicyReplaceable(t *testing.T) {
+	a := NewGameWithSeed(4, 1)
+	b := NewGameWithSeed(4, 2)
+	LinkVersus(a, b)
+	a.Conversion = fixedPolicy{n: 7}
+
+	popFour(a)
+	runChain(a)
+
+	if b.Garbage.Pending != 7 {
+		t.Errorf("Expected the replaced policy to send 7, got %d", b.Garbage.Pending)
+	}
+}
+
+func TestMarginTimeRulesValidate(t *testing.T) {
+	r := DefaultRules()
+	r.TargetPoint = 0
+	if r.Validate() == nil {
+		t.Error("Expected a zero target point to be rejected")
+	}
+
+	r = DefaultRules()
+	r.MarginInterval = 0
+	if r.Validate() == nil {
+		t.Error("Expected margin time without an interval to be rejected")
+	}
+	r.MarginTime = 0
+	if err := r.Validate(); err != nil {
+		t.Errorf("Expected margin time off to need no interval: %v", err)
+	}
+}
+
+func TestSuspendKeepsMatchClock(t *testing.T) {
+	a := NewGameWithSeed(4, 1)
+	LinkVersus(a, NewGameWithSeed(4, 2))
+	a.Clock.Frames = 6000
+
+	data, err := json.Marshal(a.Suspend())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var state SuspendState
+	if err := json.Unmarshal(data, &state); err != nil {
+		t.Fatal(err)
+	}
+	restored := mustRestore(t, &state)
+
+	if restored.Clock == nil || restored.Clock.Frames != 6000 {
+		t.Errorf("Expected the match clock to be restored, got %+v", restored.Clock)
+	}
+
+	// Rules without a nuisance conversion cannot be played
+	state.Rules.TargetPoint, state.Rules.MarginTime, state.Rules.MarginInterval = 0, 0, 0
+	if _, err := state.Restore(); err == nil {
+		t.Error("Expected rules without a target point to be rejected")
+	}
+}
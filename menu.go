@@ -1,12 +1,15 @@
 package main
 
 import (
+	"fmt"
 	"github.com/gdamore/tcell/v2"
+	"time"
 )
 
 // Screen represents the menu screen
 type Screen struct {
 	screen tcell.Screen
+	theme  *Theme // Theme for puyo glyphs on the stats page
 }
 
 // NewScreen creates a new menu screen
@@ -25,6 +28,7 @@ func NewScreen() (*Screen, error) {
 
 	return &Screen{
 		screen: screen,
+		theme:  ThemeByName("classic"),
 	}, nil
 }
 
@@ -33,68 +37,611 @@ func (s *Screen) Close() {
 	s.screen.Fini()
 }
 
-// drawText draws text at the given position
-func (s *Screen) drawText(x, y int, text string, style tcell.Style) {
-	for i, r := range text {
-		s.screen.SetContent(x+i, y, r, nil, style)
+// drawText draws text at the given position and returns the column after it
+func (s *Screen) drawText(x, y int, text string, style tcell.Style) int {
+	return drawText(s.screen, x, y, text, style)
+}
+
+// MenuAction is the action chosen in the main menu
+type MenuAction int
+
+const (
+	MenuPlay     MenuAction = iota // Start a new game
+	MenuContinue                   // Resume the suspended game
+	MenuQuit                       // Exit the program
+)
+
+// MenuResult is the choice made in the menu
+type MenuResult struct {
+	Action     MenuAction
+	ColorCount int    // Number of colors for a new game
+	Rules      string // Rules preset for a new game
+	Mission    string // Mission id for a new mission game
+}
+
+// menuItem is an entry of the main menu
+type menuItem int
+
+const (
+	itemContinue menuItem = iota
+	itemPlay
+	itemModes
+	itemMissions
+	itemSettings
+	itemRecords
+	itemReplays
+	itemQuit
+)
+
+// drawList draws a title and a list of options with the selected one highlighted
+func (s *Screen) drawList(title string, options []string, selected int, help string) {
+	s.screen.Clear()
+
+	titleStyle := tcell.StyleDefault.Bold(true).Foreground(tcell.ColorYellow)
+	normalStyle := tcell.StyleDefault
+	selectedStyle := tcell.StyleDefault.Reverse(true).Bold(true)
+
+	// Title
+	s.drawText(10, 3, "Terminal Puyo", titleStyle)
+
+	// Menu title
+	s.drawText(10, 6, title, normalStyle)
+
+	// Options
+	for i, option := range options {
+		style := normalStyle
+		prefix := "  "
+		if i == selected {
+			style = selectedStyle
+			prefix = "▶ "
+		}
+		s.drawText(12, 8+i, prefix+option, style)
 	}
+
+	// Instructions
+	instructionStyle := tcell.StyleDefault.Foreground(tcell.ColorGray)
+	s.drawText(10, 9+len(options), help, instructionStyle)
+
+	s.screen.Show()
 }
 
-// ShowMenu displays the color selection menu and returns the selected color count
-func (s *Screen) ShowMenu() int {
-	selected := 0 // 0 = 4 colors, 1 = 5 colors
-	options := []string{"4色", "5色"}
+// selectFrom shows a list of options and returns the chosen index
+// Returns false if the list was cancelled with Esc or Q
+func (s *Screen) selectFrom(title string, options []string, selected int) (int, bool) {
+	for {
+		s.drawList(title, options, selected, T("menu.help"))
 
+		// Handle input
+		ev, ok := s.screen.PollEvent().(*tcell.EventKey)
+		if !ok {
+			continue
+		}
+		switch ev.Key() {
+		case tcell.KeyUp:
+			selected = (selected - 1 + len(options)) % len(options)
+		case tcell.KeyDown:
+			selected = (selected + 1) % len(options)
+		case tcell.KeyEnter:
+			return selected, true
+		case tcell.KeyEscape:
+			return selected, false
+		}
+		switch ev.Rune() {
+		case 'q', 'Q':
+			return selected, false
+		}
+	}
+}
+
+// ShowMenu displays the main menu and returns the selected action
+// When canContinue is set, a "continue" option for the suspended game is offered first.
+// Changes made on the settings page are saved to the config file.
+func (s *Screen) ShowMenu(canContinue bool, settings *Settings) MenuResult {
+	items := []menuItem{itemPlay, itemModes, itemMissions, itemSettings, itemRecords, itemReplays, itemQuit}
+	if canContinue {
+		items = append([]menuItem{itemContinue}, items...)
+	}
+
+	selected := 0
 	for {
-		s.screen.Clear()
+		s.theme = ThemeByName(settings.Theme)
 
-		titleStyle := tcell.StyleDefault.Bold(true).Foreground(tcell.ColorYellow)
-		normalStyle := tcell.StyleDefault
-		selectedStyle := tcell.StyleDefault.Reverse(true).Bold(true)
+		options := make([]string, len(items))
+		for i, item := range items {
+			options[i] = s.menuLabel(item, settings)
+		}
 
-		// Title
-		s.drawText(10, 3, "Terminal Puyo", titleStyle)
+		choice, ok := s.selectFrom(T("menu.title"), options, selected)
+		if !ok {
+			return MenuResult{Action: MenuQuit}
+		}
+		selected = choice
 
-		// Menu title
-		s.drawText(10, 6, "色数を選択してください:", normalStyle)
+		switch items[choice] {
+		case itemContinue:
+			return MenuResult{Action: MenuContinue}
+		case itemPlay:
+			return MenuResult{Action: MenuPlay, ColorCount: settings.ColorCount, Rules: settings.Rules}
+		case itemModes:
+			if s.showModes(settings) {
+				return MenuResult{Action: MenuPlay, ColorCount: settings.ColorCount, Rules: settings.Rules}
+			}
+		case itemMissions:
+			if id, ok := s.showMissions(); ok {
+				return MenuResult{Action: MenuPlay, Mission: id}
+			}
+		case itemSettings:
+			s.ShowSettings(settings)
+		case itemRecords:
+			s.showRecords()
+		case itemReplays:
+			s.showMessage(T("replays.title"), T("replays.none"))
+		case itemQuit:
+			return MenuResult{Action: MenuQuit}
+		}
+	}
+}
+
+// menuLabel returns the label of a main menu item
+func (s *Screen) menuLabel(item menuItem, settings *Settings) string {
+	switch item {
+	case itemContinue:
+		return T("menu.continue")
+	case itemPlay:
+		return T("menu.play", settings.ColorCount)
+	case itemModes:
+		return T("menu.modes")
+	case itemMissions:
+		return T("menu.missions")
+	case itemSettings:
+		return T("menu.settings")
+	case itemRecords:
+		return T("menu.records")
+	case itemReplays:
+		return T("menu.replays")
+	default:
+		return T("menu.quit")
+	}
+}
+
+// showModes lets the player pick a mode and color count
+// Returns true if a game should be started. The choice is remembered in settings.
+func (s *Screen) showModes(settings *Settings) bool {
+	modes := []struct {
+		rules  string
+		colors int
+	}{
+		{"standard", 4},
+		{"standard", 5},
+		{"standard", 6},
+		{"wide", 4},
+		{"casual", 4},
+		{"kids", 3},
+		{"tsu", 4},
+		{"mixed", 4},
+		{"fever", 4},
+		{"sprint-chain", 4},
+		{"sprint-score", 4},
+		{"ultra", 4},
+	}
+
+	options := make([]string, len(modes))
+	selected := 0
+	for i, mode := range modes {
+		if mode.rules == "standard" {
+			options[i] = T("modes.endless", mode.colors)
+		} else {
+			options[i] = T("rules." + mode.rules)
+		}
+		if mode.rules == settings.Rules && mode.colors == settings.ColorCount {
+			selected = i
+		}
+	}
+
+	choice, ok := s.selectFrom(T("modes.title"), options, selected)
+	if !ok {
+		return false
+	}
+
+	settings.Rules = modes[choice].rules
+	settings.ColorCount = modes[choice].colors
+	if err := SaveSettings(settings); err != nil {
+		s.showMessage(T("settings.title"), T("settings.save_error", err))
+	}
+	return true
+}
+
+// showMissions lets the player pick a mission
+// Completed missions are marked with their best time.
+func (s *Screen) showMissions() (string, bool) {
+	records, err := LoadMissionRecords()
+	if err != nil {
+		records = NewMissionRecords()
+	}
+
+	list := Missions()
+	options := make([]string, len(list))
+	for i, m := range list {
+		options[i] = m.Title()
+		if rec := records.Missions[m.ID]; rec.Completed {
+			options[i] = T("missions.cleared", m.Title(), formatFrames(rec.BestFrames))
+		}
+	}
+
+	choice, ok := s.selectFrom(T("missions.title"), options, 0)
+	if !ok {
+		return "", false
+	}
+	return list[choice].ID, true
+}
+
+// showMessage displays a message until a key is pressed
+func (s *Screen) showMessage(title, message string) {
+	s.screen.Clear()
+
+	titleStyle := tcell.StyleDefault.Bold(true).Foreground(tcell.ColorYellow)
+	instructionStyle := tcell.StyleDefault.Foreground(tcell.ColorGray)
+
+	s.drawText(10, 3, title, titleStyle)
+	s.drawText(10, 6, message, tcell.StyleDefault)
+	s.drawText(10, 8, T("menu.press_any_key"), instructionStyle)
+	s.screen.Show()
+
+	s.waitKey()
+}
+
+// waitKey blocks until a key is pressed
+func (s *Screen) waitKey() {
+	for {
+		if _, ok := s.screen.PollEvent().(*tcell.EventKey); ok {
+			return
+		}
+	}
+}
+
+// showRecords loads the high score and the current player's stats and shows them
+func (s *Screen) showRecords() {
+	player := currentPlayerName()
+	stats, err := LoadStats(player)
+	if err != nil {
+		stats = NewStats()
+	}
+	highScore, err := LoadHighScore()
+	if err != nil {
+		highScore = &HighScore{}
+	}
+	s.ShowStats(player, stats, highScore)
 
-		// Options
-		for i, option := range options {
+	boards, err := LoadLeaderboards()
+	if err != nil {
+		boards = NewLeaderboards()
+	}
+	s.ShowLeaderboards(boards)
+}
+
+// leaderboardRows is the number of runs shown for each mode on the records page
+const leaderboardRows = 5
+
+// ShowLeaderboards displays the best runs of each timed mode until a key is pressed
+func (s *Screen) ShowLeaderboards(boards *Leaderboards) {
+	s.screen.Clear()
+
+	titleStyle := tcell.StyleDefault.Bold(true).Foreground(tcell.ColorYellow)
+	headerStyle := tcell.StyleDefault.Foreground(tcell.ColorYellow)
+	normalStyle := tcell.StyleDefault
+
+	s.drawText(10, 1, T("board.title"), titleStyle)
+
+	y := 3
+	for _, name := range []string{"sprint-chain", "sprint-score", "ultra"} {
+		rules := RulesByName(name)
+		s.drawText(10, y, T("rules."+name), headerStyle)
+		y++
+
+		board := boards.Board(rules)
+		if len(board) == 0 {
+			s.drawText(12, y, T("board.empty"), normalStyle)
+			y++
+		}
+		for i, entry := range board {
+			if i == leaderboardRows {
+				break
+			}
+			if rules.Goal == GoalUltra {
+				s.drawText(12, y, T("board.score_row", i+1, entry.Score, entry.Player), normalStyle)
+			} else {
+				s.drawText(12, y, T("board.time_row", i+1, formatFrames(entry.Frames), entry.Player), normalStyle)
+			}
+			y++
+		}
+		y++
+	}
+
+	instructionStyle := tcell.StyleDefault.Foreground(tcell.ColorGray)
+	s.drawText(10, y, T("menu.press_any_key"), instructionStyle)
+
+	s.screen.Show()
+
+	s.waitKey()
+}
+
+// statsLines returns the summary lines of the records page
+func statsLines(stats *Stats, highScore *HighScore) []string {
+	return []string{
+		T("stats.high_score", highScore.Score, highScore.Level, highScore.Chains, highScore.AllClears),
+		T("stats.games", stats.GamesPlayed),
+		T("stats.time", stats.TotalTime().Round(time.Second)),
+		T("stats.pieces", stats.PiecesPlaced),
+		T("stats.average", stats.AverageScore()),
+		T("stats.best_chain", stats.BestChain),
+		T("stats.all_clears", stats.AllClears),
+		T("stats.pps", stats.PiecesPerSecond()),
+	}
+}
+
+// ShowStats displays the high score and lifetime statistics until a key is pressed
+func (s *Screen) ShowStats(player string, stats *Stats, highScore *HighScore) {
+	s.screen.Clear()
+
+	titleStyle := tcell.StyleDefault.Bold(true).Foreground(tcell.ColorYellow)
+	headerStyle := tcell.StyleDefault.Foreground(tcell.ColorYellow)
+	normalStyle := tcell.StyleDefault
+
+	s.drawText(10, 1, T("stats.title", player), titleStyle)
+
+	y := 3
+	for _, line := range statsLines(stats, highScore) {
+		s.drawText(10, y, line, normalStyle)
+		y++
+	}
+
+	// Chain length histogram
+	y++
+	s.drawText(10, y, T("stats.histogram"), headerStyle)
+	y++
+	for _, length := range stats.chainLengths() {
+		s.drawText(12, y, T("stats.histogram_row", length, stats.ChainHistogram[length]), normalStyle)
+		y++
+	}
+
+	// Puyos popped per color
+	y++
+	s.drawText(10, y, T("stats.popped"), headerStyle)
+	y++
+	for c := Red; c <= MaxColor; c++ {
+		glyph, style := s.theme.Look(c)
+		x := s.drawText(12, y, glyph, style)
+		s.drawText(x+1, y, T("stats.popped_row", T("color."+c.Name()), stats.PuyosPopped[c.Name()]), normalStyle)
+		y++
+	}
+
+	instructionStyle := tcell.StyleDefault.Foreground(tcell.ColorGray)
+	s.drawText(10, y+1, T("menu.press_any_key"), instructionStyle)
+
+	s.screen.Show()
+
+	s.waitKey()
+}
+
+// settingRow is an editable line of the settings page
+type settingRow struct {
+	label  string
+	value  string
+	adjust func(delta int) // Called with -1/+1 for ←/→
+	action Action          // Key binding edited by Enter, if set
+}
+
+// settingRows builds the rows of the settings page
+func settingRows(settings *Settings) []settingRow {
+	onOff := func(b bool) string {
+		if b {
+			return T("settings.on")
+		}
+		return T("settings.off")
+	}
+	cycle := func(list []string, current string, delta int) string {
+		i := 0
+		for j, item := range list {
+			if item == current {
+				i = j
+			}
+		}
+		return list[(i+delta+len(list))%len(list)]
+	}
+
+	rows := []settingRow{
+		{
+			label:  T("settings.lock_delay"),
+			value:  T("settings.frames", settings.LockDelay),
+			adjust: func(d int) { settings.LockDelay = clamp(settings.LockDelay+d*4, MinLockDelay, MaxLockDelay) },
+		},
+		{
+			label:  T("settings.start"),
+			value:  fmt.Sprintf("%d", settings.StartLevel),
+			adjust: func(d int) { settings.StartLevel = clamp(settings.StartLevel+d, 1, MaxStartLevel) },
+		},
+		{
+			label:  T("settings.ghost"),
+			value:  onOff(settings.Ghost),
+			adjust: func(int) { settings.Ghost = !settings.Ghost },
+		},
+		{
+			label:  T("settings.progression"),
+			value:  T("progression." + settings.Progression),
+			adjust: func(d int) { settings.Progression = cycle(ProgressionNames, settings.Progression, d) },
+		},
+		{
+			label:  T("settings.fall_speed"),
+			value:  T("settings.per_row", settings.FallSpeed),
+			adjust: func(d int) { settings.FallSpeed = clamp(settings.FallSpeed+d, 1, MaxFallSpeed) },
+		},
+		{
+			label:  T("settings.all_clear"),
+			value:  T("settings.points", settings.AllClear),
+			adjust: func(d int) { settings.AllClear = clamp(settings.AllClear+d*300, 0, MaxAllClear) },
+		},
+		{
+			label:  T("settings.das"),
+			value:  T("settings.frames", settings.DAS),
+			adjust: func(d int) { settings.DAS = clamp(settings.DAS+d, 0, MaxDAS) },
+		},
+		{
+			label:  T("settings.arr"),
+			value:  T("settings.frames", settings.ARR),
+			adjust: func(d int) { settings.ARR = clamp(settings.ARR+d, 0, MaxARR) },
+		},
+		{
+			label:  T("settings.connected"),
+			value:  onOff(settings.Connected),
+			adjust: func(int) { settings.Connected = !settings.Connected },
+		},
+		{
+			label:  T("settings.emoji"),
+			value:  onOff(settings.Emoji),
+			adjust: func(int) { settings.Emoji = !settings.Emoji },
+		},
+		{
+			label:  T("settings.report"),
+			value:  onOff(settings.Report),
+			adjust: func(int) { settings.Report = !settings.Report },
+		},
+		{
+			label:  T("settings.sound"),
+			value:  T("sound." + settings.Sound),
+			adjust: func(d int) { settings.Sound = cycle(SoundModes, settings.Sound, d) },
+		},
+		{
+			label:  T("settings.theme"),
+			value:  settings.Theme,
+			adjust: func(d int) { settings.Theme = cycle(ThemeNames(), settings.Theme, d) },
+		},
+		{
+			label: T("settings.language"),
+			value: settings.Language,
+			adjust: func(d int) {
+				settings.Language = cycle(Languages, settings.Language, d)
+				SetLanguage(settings.Language)
+			},
+		},
+	}
+
+	for _, action := range Actions {
+		rows = append(rows, settingRow{
+			label:  T("settings.key", T("action."+string(action))),
+			value:  settings.Keys[action],
+			action: action,
+		})
+	}
+
+	return rows
+}
+
+// scrollTop returns the first row to show in a window of visible rows
+// The window moves as little as possible to keep the selected row in view.
+func scrollTop(top, selected, count, visible int) int {
+	if selected < top {
+		top = selected
+	}
+	if selected >= top+visible {
+		top = selected - visible + 1
+	}
+	return clamp(top, 0, max(count-visible, 0))
+}
+
+// ShowSettings displays the settings page and saves changes when it is closed
+// Rows that do not fit the terminal scroll into view as they are selected.
+func (s *Screen) ShowSettings(settings *Settings) {
+	titleStyle := tcell.StyleDefault.Bold(true).Foreground(tcell.ColorYellow)
+	normalStyle := tcell.StyleDefault
+	selectedStyle := tcell.StyleDefault.Reverse(true).Bold(true)
+	instructionStyle := tcell.StyleDefault.Foreground(tcell.ColorGray)
+
+	selected, top := 0, 0
+	waitingForKey := false
+	notice := "" // Shown instead of the help until the next key
+
+	for {
+		rows := settingRows(settings)
+
+		// The title takes three lines and the help two
+		_, height := s.screen.Size()
+		visible := min(len(rows), max(height-5, 1))
+		top = scrollTop(top, selected, len(rows), visible)
+
+		s.screen.Clear()
+		s.drawText(10, 1, T("settings.title"), titleStyle)
+
+		for i := top; i < top+visible; i++ {
+			row := rows[i]
 			style := normalStyle
 			prefix := "  "
 			if i == selected {
 				style = selectedStyle
 				prefix = "▶ "
 			}
-			s.drawText(12, 8+i, prefix+option, style)
+			value := row.value
+			if i == selected && waitingForKey {
+				value = T("settings.press_key")
+			}
+			y := 3 + i - top
+			s.drawText(12, y, prefix+row.label, style)
+			s.drawText(36, y, value, style)
 		}
 
-		// Instructions
-		instructionStyle := tcell.StyleDefault.Foreground(tcell.ColorGray)
-		s.drawText(10, 12, "↑↓: 選択  Enter: 決定", instructionStyle)
+		// Arrows show that there are more rows above or below
+		if top > 0 {
+			s.drawText(10, 3, "▲", instructionStyle)
+		}
+		if top+visible < len(rows) {
+			s.drawText(10, 2+visible, "▼", instructionStyle)
+		}
 
+		if notice != "" {
+			s.drawText(10, 4+visible, notice, tcell.StyleDefault.Foreground(tcell.ColorRed))
+		} else {
+			s.drawText(10, 4+visible, T("settings.help"), instructionStyle)
+		}
 		s.screen.Show()
 
-		// Handle input
-		ev := s.screen.PollEvent()
-		switch ev := ev.(type) {
-		case *tcell.EventKey:
-			switch ev.Key() {
-			case tcell.KeyUp:
-				selected = (selected - 1 + len(options)) % len(options)
-			case tcell.KeyDown:
-				selected = (selected + 1) % len(options)
-			case tcell.KeyEnter:
-				// Return color count (4 or 5)
-				return selected + 4
-			case tcell.KeyEscape:
-				// Default to 4 colors on escape
-				return 4
+		ev, ok := s.screen.PollEvent().(*tcell.EventKey)
+		if !ok {
+			continue
+		}
+		notice = ""
+
+		// Bind the next key pressed to the selected action
+		if waitingForKey {
+			waitingForKey = false
+			if ev.Key() != tcell.KeyEscape {
+				if name := keyName(ev); name != "" && !settings.Keys.Bind(rows[selected].action, name) {
+					notice = T("settings.reserved", keyLabel(name))
+				}
+			}
+			continue
+		}
+
+		switch ev.Key() {
+		case tcell.KeyUp:
+			selected = (selected - 1 + len(rows)) % len(rows)
+		case tcell.KeyDown:
+			selected = (selected + 1) % len(rows)
+		case tcell.KeyLeft:
+			if rows[selected].adjust != nil {
+				rows[selected].adjust(-1)
+			}
+		case tcell.KeyRight:
+			if rows[selected].adjust != nil {
+				rows[selected].adjust(1)
+			}
+		case tcell.KeyEnter:
+			if rows[selected].action != "" {
+				waitingForKey = true
 			}
-			switch ev.Rune() {
-			case 'q', 'Q':
-				return 4
+		case tcell.KeyEscape:
+			if err := SaveSettings(settings); err != nil {
+				s.showMessage(T("settings.title"), T("settings.save_error", err))
 			}
+			return
 		}
 	}
 }
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"github.com/gdamore/tcell/v2"
+	"strings"
+	"testing"
+)
+
+func TestScrollTop(t *testing.T) {
+	tests := []struct {
+		top, selected, count, visible int
+		want                          int
+	}{
+		{0, 5, 22, 19, 0},   // Already in view
+		{0, 20, 22, 19, 2},  // Scrolls down to the selected row
+		{3, 1, 22, 19, 1},   // Scrolls up to the selected row
+		{3, 0, 10, 19, 0},   // Everything fits
+		{10, 21, 22, 19, 3}, // Never past the last row
+	}
+	for _, tt := range tests {
+		if got := scrollTop(tt.top, tt.selected, tt.count, tt.visible); got != tt.want {
+			t.Errorf("scrollTop(%d, %d, %d, %d) = %d, want %d", tt.top, tt.selected, tt.count, tt.visible, got, tt.want)
+		}
+	}
+}
+
+func TestSettingsScrollOnSmallTerminal(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	defer SetLanguage(fallbackLanguage)
+	SetLanguage("en")
+	screen := newTestScreen(t)
+	screen.SetSize(80, 24)
+	s := &Screen{screen: screen, theme: ThemeByName("classic")}
+
+	// Select the last row, then leave the page
+	settings := DefaultSettings()
+	rows := settingRows(settings)
+	go func() {
+		for i := 0; i < len(rows)-1; i++ {
+			screen.InjectKey(tcell.KeyDown, 0, tcell.ModNone)
+		}
+		screen.InjectKey(tcell.KeyEscape, 0, tcell.ModNone)
+	}()
+	s.ShowSettings(settings)
+
+	if !screenContains(screen, "▶ "+rows[len(rows)-1].label) {
+		t.Error("Expected the selected last row on screen")
+	}
+	if !screenContains(screen, strings.Fields(T("settings.help"))[0]) {
+		t.Error("Expected the help on screen")
+	}
+	if screenContains(screen, rows[0].label) {
+		t.Error("Expected the first row to scroll out of view")
+	}
+}
@@ -0,0 +1,363 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// messages holds the user-visible strings for each supported language
+// Entries may contain fmt verbs, which are filled in by T
+var messages = map[string]map[string]string{
+	"en": {
+		"game.score":           "Score: %d",
+		"game.level":           "Level: %d",
+		"game.chains":          "Chains: %d",
+		"game.colors":          "Colors: %d",
+		"game.high_score":      "High Score: %d",
+		"game.next":            "Next:",
+		"game.chain":           "%d CHAIN!",
+		"game.controls":        "Controls:",
+		"game.move":            "%s: Move",
+		"game.drop":            "%s: Drop",
+		"game.hard_drop":       "%s: Hard drop",
+		"game.rotate":          "%s: Rotate",
+		"game.pause":           "%s: Pause",
+		"game.quit":            "%s: Quit",
+		"game.paused":          "PAUSED",
+		"game.resume_hint":     "Press %s to resume",
+		"game.over":            "GAME OVER!",
+		"game.restart_hint":    "Press R to restart",
+		"game.quit_hint":       "Press %s to quit",
+		"game.all_clear":       "ALL CLEAR!",
+		"game.all_clear_bonus": "+%d",
+		"game.target_point":    "Target: %d",
+		"game.fever_gauge":     "Fever %s",
+		"game.fever_time":      "FEVER! %.1fs",
+		"game.time":            "Time: %s",
+		"game.goal_chain":      "Goal: %d-chain",
+		"game.goal_score":      "Goal: %d points",
+		"game.finish":          "FINISH!",
+		"game.time_up":         "TIME UP!",
+		"game.failed":          "FAILED!",
+		"game.too_small":       "Terminal too small",
+		"game.too_small_size":  "Resize to at least %dx%d",
+		"result.new_high":      "🎉 New High Score! Score: %d, Level: %d, Chains: %d",
+		"result.game_over":     "Game Over! Score: %d, Level: %d, Chains: %d",
+		"result.high_score":    "High Score: %d",
+		"result.finish":        "Finished in %s! Score: %d",
+		"result.time_up":       "Time up! Score: %d",
+		"result.rank":          "Rank %d on the leaderboard",
+		"result.mission":       "Mission complete in %s!",
+		"result.failed":        "Mission failed",
+		"result.best_time":     "New best time!",
+		"report.title":         "=== Game analysis ===",
+		"report.summary":       "Placements: %d, average %s per placement",
+		"report.biggest":       "Biggest chain: %d-chain on placement %d (%d points)",
+		"report.timeline":      "Timeline (#, time, chain fired, potential left):",
+		"report.potential":     "%d-chain (%d×%s in column %d)",
+		"report.missed":        "Missed opportunities:",
+		"report.missed_item":   "Placement %d: %s was available, fired %d",
+		"report.no_missed":     "No missed opportunities",
+		"report.saved":         "Report saved to %s",
+		"field.link":           "Link %d: %d puyos, %d colors, %d points",
+		"field.result":         "%d-chain, %d points, %d puyos cleared",
+		"field.after":          "Field after the chain:",
+		"field.potential":      "Chain potential: %s",
+		"menu.title":           "Menu",
+		"menu.help":            "↑↓: Select  Enter: OK  Esc: Back",
+		"menu.continue":        "Continue",
+		"menu.play":            "Play (%d colors)",
+		"menu.modes":           "Modes",
+		"menu.missions":        "Missions",
+		"menu.settings":        "Settings",
+		"menu.records":         "Records",
+		"menu.replays":         "Replays",
+		"menu.quit":            "Quit",
+		"menu.press_any_key":   "Press any key to return",
+		"modes.title":          "Select a mode:",
+		"missions.title":       "Select a mission:",
+		"missions.cleared":     "%s  ✔ %s",
+		"modes.endless":        "Endless, %d colors",
+		"rules.wide":           "Wide field, 8 columns",
+		"rules.casual":         "Casual, pop 3",
+		"rules.kids":           "Kids, 3 colors",
+		"rules.tsu":            "Tsu scoring",
+		"rules.mixed":          "Mixed pieces (Fever drop set)",
+		"rules.fever":          "Fever",
+		"rules.sprint-chain":   "Sprint: 10-chain",
+		"rules.sprint-score":   "Sprint: 100,000 points",
+		"rules.ultra":          "Ultra: 2 minutes",
+		"replays.title":        "Replays",
+		"replays.none":         "No saved replays",
+		"settings.title":       "Settings",
+		"settings.save_error":  "Could not save settings: %v",
+		"settings.help":        "↑↓: Select  ←→: Change  Enter: Bind key  Esc: Save and back",
+		"settings.press_key":   "Press a key...",
+		"settings.reserved":    "%s is used by the game and cannot be bound",
+		"settings.lock_delay":  "Lock delay",
+		"settings.start":       "Starting level",
+		"settings.ghost":       "Ghost",
+		"settings.progression": "Speed curve",
+		"settings.all_clear":   "All clear bonus",
+		"settings.points":      "%d points",
+		"settings.fall_speed":  "Fall speed",
+		"settings.per_row":     "%d frames/row",
+		"settings.das":         "DAS",
+		"settings.arr":         "ARR",
+		"settings.connected":   "Connect puyos",
+		"settings.emoji":       "Emoji puyos",
+		"settings.report":      "Analysis report",
+		"settings.sound":       "Sound",
+		"settings.theme":       "Theme",
+		"settings.language":    "Language",
+		"settings.key":         "Key: %s",
+		"settings.frames":      "%d frames",
+		"settings.on":          "ON",
+		"settings.off":         "OFF",
+		"progression.standard": "Standard",
+		"progression.tsu":      "Tsu endless",
+		"progression.marathon": "Marathon",
+		"sound.bell":           "Bell",
+		"sound.pcm":            "PCM output",
+		"sound.off":            "OFF",
+		"action.left":          "Move left",
+		"action.right":         "Move right",
+		"action.soft_drop":     "Soft drop",
+		"action.hard_drop":     "Hard drop",
+		"action.rotate_ccw":    "Rotate left",
+		"action.rotate_cw":     "Rotate right",
+		"action.pause":         "Pause",
+		"action.quit":          "Quit",
+		"stats.title":          "Stats - %s",
+		"stats.high_score":     "High score: %d (level %d, %d chains, %d all clears)",
+		"stats.games":          "Games played: %d",
+		"stats.time":           "Time played: %s",
+		"stats.pieces":         "Pieces placed: %d",
+		"stats.average":        "Average score: %d",
+		"stats.best_chain":     "Best chain: %d",
+		"stats.all_clears":     "All clears: %d",
+		"stats.pps":            "Pieces per second: %.2f",
+		"stats.histogram":      "Chain lengths:",
+		"stats.histogram_row":  "%2d-chain: %d",
+		"stats.popped":         "Puyos popped:",
+		"stats.popped_row":     "%s: %d",
+		"board.title":          "Leaderboards",
+		"board.empty":          "No runs yet",
+		"board.time_row":       "%2d. %s  %s",
+		"board.score_row":      "%2d. %d  %s",
+		"mission.step":         "%d/%d %s",
+		"mission.pop":          "Pop %d %s at once",
+		"mission.chain":        "Fire a %d-chain",
+		"mission.trigger":      "Chain at column %d, height %d",
+		"mission.survive":      "Survive %d nuisance",
+		"mission.pieces":       "%s in %d pieces",
+		"color.red":            "Red",
+		"color.green":          "Green",
+		"color.blue":           "Blue",
+		"color.yellow":         "Yellow",
+		"color.purple":         "Purple",
+		"color.orange":         "Orange",
+	},
+	"ja": {
+		"game.score":           "スコア: %d",
+		"game.level":           "レベル: %d",
+		"game.chains":          "連鎖数: %d",
+		"game.colors":          "色数: %d",
+		"game.high_score":      "ハイスコア: %d",
+		"game.next":            "ネクスト:",
+		"game.chain":           "%d連鎖!",
+		"game.controls":        "操作:",
+		"game.move":            "%s: 移動",
+		"game.drop":            "%s: 落下",
+		"game.hard_drop":       "%s: 即落下",
+		"game.rotate":          "%s: 回転",
+		"game.pause":           "%s: 一時停止",
+		"game.quit":            "%s: 中断",
+		"game.paused":          "一時停止中",
+		"game.resume_hint":     "%sで再開",
+		"game.over":            "ゲームオーバー!",
+		"game.restart_hint":    "Rでリスタート",
+		"game.quit_hint":       "%sで終了",
+		"game.all_clear":       "全消し！",
+		"game.all_clear_bonus": "+%d",
+		"game.target_point":    "レート: %d",
+		"game.fever_gauge":     "フィーバー %s",
+		"game.fever_time":      "フィーバー！ 残り%.1f秒",
+		"game.time":            "タイム: %s",
+		"game.goal_chain":      "目標: %d連鎖",
+		"game.goal_score":      "目標: %d点",
+		"game.finish":          "クリア！",
+		"game.time_up":         "タイムアップ！",
+		"game.failed":          "失敗…",
+		"game.too_small":       "端末が小さすぎます",
+		"game.too_small_size":  "%dx%d 以上に広げてください",
+		"result.new_high":      "🎉 ハイスコア更新! スコア: %d, レベル: %d, 連鎖数: %d",
+		"result.game_over":     "ゲームオーバー! スコア: %d, レベル: %d, 連鎖数: %d",
+		"result.high_score":    "ハイスコア: %d",
+		"result.finish":        "クリアタイム %s！ スコア: %d",
+		"result.time_up":       "タイムアップ！ スコア: %d",
+		"result.rank":          "ランキング %d位",
+		"result.mission":       "ミッションクリア！ タイム %s",
+		"result.failed":        "ミッション失敗",
+		"result.best_time":     "ベストタイム更新！",
+		"report.title":         "=== ゲーム分析 ===",
+		"report.summary":       "設置数: %d, 1手あたり平均 %s",
+		"report.biggest":       "最大連鎖: %d手目の%d連鎖 (%d点)",
+		"report.timeline":      "タイムライン (手数, 時間, 発火した連鎖, 残った連鎖力):",
+		"report.potential":     "%d連鎖 (%d個の%sを%d列目に)",
+		"report.missed":        "見逃した連鎖:",
+		"report.missed_item":   "%d手目: %sが可能だったが%d連鎖で発火",
+		"report.no_missed":     "見逃した連鎖はありません",
+		"report.saved":         "分析レポートを保存しました: %s",
+		"field.link":           "%d連鎖目: %d個, %d色, %d点",
+		"field.result":         "%d連鎖, %d点, %d個消去",
+		"field.after":          "連鎖後の盤面:",
+		"field.potential":      "連鎖ポテンシャル: %s",
+		"menu.title":           "メニュー",
+		"menu.help":            "↑↓: 選択  Enter: 決定  Esc: 戻る",
+		"menu.continue":        "つづきから",
+		"menu.play":            "プレイ (%d色)",
+		"menu.modes":           "モード",
+		"menu.missions":        "ミッション",
+		"menu.settings":        "設定",
+		"menu.records":         "記録",
+		"menu.replays":         "リプレイ",
+		"menu.quit":            "終了",
+		"menu.press_any_key":   "何かキーを押すと戻ります",
+		"modes.title":          "モードを選択してください:",
+		"missions.title":       "ミッションを選択してください:",
+		"missions.cleared":     "%s  ✔ %s",
+		"modes.endless":        "耐久 %d色",
+		"rules.wide":           "ワイド 8列",
+		"rules.casual":         "カジュアル 3個消し",
+		"rules.kids":           "キッズ 3色",
+		"rules.tsu":            "通ルール得点",
+		"rules.mixed":          "いろいろな組ぷよ（フィーバーの配ぷよ）",
+		"rules.fever":          "フィーバー",
+		"rules.sprint-chain":   "スプリント: 10連鎖",
+		"rules.sprint-score":   "スプリント: 10万点",
+		"rules.ultra":          "ウルトラ: 2分",
+		"replays.title":        "リプレイ",
+		"replays.none":         "保存されたリプレイはありません",
+		"settings.title":       "設定",
+		"settings.save_error":  "設定を保存できませんでした: %v",
+		"settings.help":        "↑↓: 選択  ←→: 変更  Enter: キー設定  Esc: 保存して戻る",
+		"settings.press_key":   "キーを押してください...",
+		"settings.reserved":    "%sはゲームで使うため設定できません",
+		"settings.lock_delay":  "設置猶予",
+		"settings.start":       "開始レベル",
+		"settings.ghost":       "ゴースト",
+		"settings.progression": "速度カーブ",
+		"settings.all_clear":   "全消しボーナス",
+		"settings.points":      "%d点",
+		"settings.fall_speed":  "落下速度",
+		"settings.per_row":     "%dフレーム/段",
+		"settings.das":         "DAS",
+		"settings.arr":         "ARR",
+		"settings.connected":   "ぷよをつなげて表示",
+		"settings.emoji":       "絵文字ぷよ",
+		"settings.report":      "分析レポート",
+		"settings.sound":       "サウンド",
+		"settings.theme":       "テーマ",
+		"settings.language":    "言語",
+		"settings.key":         "キー: %s",
+		"settings.frames":      "%dフレーム",
+		"settings.on":          "ON",
+		"settings.off":         "OFF",
+		"progression.standard": "標準",
+		"progression.tsu":      "通エンドレス",
+		"progression.marathon": "マラソン",
+		"sound.bell":           "ベル",
+		"sound.pcm":            "PCM出力",
+		"sound.off":            "OFF",
+		"action.left":          "左移動",
+		"action.right":         "右移動",
+		"action.soft_drop":     "ソフトドロップ",
+		"action.hard_drop":     "ハードドロップ",
+		"action.rotate_ccw":    "左回転",
+		"action.rotate_cw":     "右回転",
+		"action.pause":         "一時停止",
+		"action.quit":          "中断",
+		"stats.title":          "統計 - %s",
+		"stats.high_score":     "ハイスコア: %d (レベル %d, 連鎖数 %d, 全消し %d)",
+		"stats.games":          "プレイ回数: %d",
+		"stats.time":           "プレイ時間: %s",
+		"stats.pieces":         "設置数: %d",
+		"stats.average":        "平均スコア: %d",
+		"stats.best_chain":     "最大連鎖: %d",
+		"stats.all_clears":     "全消し: %d",
+		"stats.pps":            "設置速度: %.2f 個/秒",
+		"stats.histogram":      "連鎖数の分布:",
+		"stats.histogram_row":  "%2d連鎖: %d",
+		"stats.popped":         "消したぷよ:",
+		"stats.popped_row":     "%s: %d",
+		"board.title":          "ランキング",
+		"board.empty":          "記録なし",
+		"board.time_row":       "%2d. %s  %s",
+		"board.score_row":      "%2d. %d点  %s",
+		"mission.step":         "%d/%d %s",
+		"mission.pop":          "%[2]sを一度に%[1]d個消す",
+		"mission.chain":        "%d連鎖する",
+		"mission.trigger":      "%d列目が%d段で連鎖",
+		"mission.survive":      "おじゃま%d個に耐える",
+		"mission.pieces":       "%s（%d手以内）",
+		"color.red":            "赤",
+		"color.green":          "緑",
+		"color.blue":           "青",
+		"color.yellow":         "黄",
+		"color.purple":         "紫",
+		"color.orange":         "オレンジ",
+	},
+}
+
+// fallbackLanguage is used for messages missing from the current language
+const fallbackLanguage = "en"
+
+// currentLanguage is the language used by T
+var currentLanguage = fallbackLanguage
+
+// SetLanguage selects the language for user-visible strings
+// "auto" picks the language from the environment (LC_ALL, LC_MESSAGES, LANG)
+func SetLanguage(setting string) {
+	if _, ok := messages[setting]; ok {
+		currentLanguage = setting
+		return
+	}
+	currentLanguage = detectLanguage()
+}
+
+// detectLanguage returns the language of the user's locale
+func detectLanguage() string {
+	for _, name := range []string{"LC_ALL", "LC_MESSAGES", "LANG"} {
+		value := os.Getenv(name)
+		if value == "" {
+			continue
+		}
+		// Locale names look like "ja_JP.UTF-8"
+		lang := strings.ToLower(value)
+		if i := strings.IndexAny(lang, "_.@"); i >= 0 {
+			lang = lang[:i]
+		}
+		if _, ok := messages[lang]; ok {
+			return lang
+		}
+		return fallbackLanguage
+	}
+	return fallbackLanguage
+}
+
+// T returns the message for key in the current language, formatted with args
+func T(key string, args ...interface{}) string {
+	msg, ok := messages[currentLanguage][key]
+	if !ok {
+		msg, ok = messages[fallbackLanguage][key]
+		if !ok {
+			msg = key
+		}
+	}
+	if len(args) == 0 {
+		return msg
+	}
+	return fmt.Sprintf(msg, args...)
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestMessageCatalogsComplete(t *testing.T) {
+	for lang, catalog := range messages {
+		for key := range messages[fallbackLanguage] {
+			if _, ok := catalog[key]; !ok {
+				t.Errorf("Language %q is missing message %q", lang, key)
+			}
+		}
+		for key := range catalog {
+			if _, ok := messages[fallbackLanguage][key]; !ok {
+				t.Errorf("Language %q has message %q not in %q", lang, key, fallbackLanguage)
+			}
+		}
+	}
+}
+
+func TestDetectLanguage(t *testing.T) {
+	tests := []struct {
+		lcAll, lang string
+		expected    string
+	}{
+		{"", "ja_JP.UTF-8", "ja"},
+		{"", "en_US.UTF-8", "en"},
+		{"ja_JP.UTF-8", "en_US.UTF-8", "ja"},
+		{"C", "ja_JP.UTF-8", "en"},
+		{"", "", "en"},
+	}
+
+	for _, tt := range tests {
+		t.Setenv("LC_ALL", tt.lcAll)
+		t.Setenv("LC_MESSAGES", "")
+		t.Setenv("LANG", tt.lang)
+		if got := detectLanguage(); got != tt.expected {
+			t.Errorf("detectLanguage() with LC_ALL=%q LANG=%q = %q, want %q", tt.lcAll, tt.lang, got, tt.expected)
+		}
+	}
+}
+
+func TestT(t *testing.T) {
+	defer SetLanguage(fallbackLanguage)
+
+	SetLanguage("ja")
+	if got := T("game.score", 100); got != "スコア: 100" {
+		t.Errorf("Expected Japanese score, got %q", got)
+	}
+
+	SetLanguage("en")
+	if got := T("game.score", 100); got != "Score: 100" {
+		t.Errorf("Expected English score, got %q", got)
+	}
+
+	// Unknown keys are shown as-is
+	if got := T("no.such.key"); got != "no.such.key" {
+		t.Errorf("Expected key for unknown message, got %q", got)
+	}
+}
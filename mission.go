@@ -0,0 +1,530 @@
+package main
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Missions
+//
+// A mission is a run through a list of objectives, completed one after
+// another, on its own starting field and with its own time limit. Missions
+// are data: the bundled ones are in missions.json and more can be added as
+// ~/.puyo/missions/*.json in the same format. Every file is checked against
+// the schema below before its missions are offered.
+
+// ObjectiveType selects what an objective asks for
+type ObjectiveType string
+
+const (
+	ObjectivePop     ObjectiveType = "pop"     // Pop Count puyos of Color in one chain step
+	ObjectiveChain   ObjectiveType = "chain"   // Fire a chain of Count links
+	ObjectiveTrigger ObjectiveType = "trigger" // Start a chain while Column holds Height puyos
+	ObjectiveSurvive ObjectiveType = "survive" // Survive Count nuisance puyos, queued when the objective starts
+)
+
+// Objective is one step of a mission
+type Objective struct {
+	Type   ObjectiveType `json:"type"`
+	Count  int           `json:"count,omitempty"`  // Puyos, links or nuisance, by type
+	Color  string        `json:"color,omitempty"`  // Color name for pop objectives
+	Column int           `json:"column,omitempty"` // Trigger column, counted from 1 at the left
+	Height int           `json:"height,omitempty"` // Puyos the trigger column must hold
+	Pieces int           `json:"pieces,omitempty"` // Pieces allowed for the objective (0 = any)
+}
+
+// Mission is a list of objectives on a starting field
+type Mission struct {
+	ID         string            `json:"id"`
+	Name       map[string]string `json:"name"`             // Name by language code
+	Rules      string            `json:"rules,omitempty"`  // Rules preset ("" for standard)
+	Colors     int               `json:"colors,omitempty"` // Palette size (0 keeps the preset's)
+	Field      []string          `json:"field,omitempty"`  // Starting field in field notation, bottom-aligned
+	TimeLimit  int               `json:"time_limit"`       // Seconds to complete every objective (0 = no limit)
+	Objectives []Objective       `json:"objectives"`
+
+	start *Field // Parsed starting field
+}
+
+// missionFile is the on-disk format of a mission file
+//
+//	{
+//	  "missions": [
+//	    {
+//	      "id": "green-8",
+//	      "name": {"en": "Eight greens", "ja": "緑を8個"},
+//	      "field": ["GG.GG.", "GRGBY.", "RYGRB."],
+//	      "time_limit": 60,
+//	      "objectives": [{"type": "pop", "count": 8, "color": "green"}]
+//	    }
+//	  ]
+//	}
+type missionFile struct {
+	Missions []*Mission `json:"missions"`
+}
+
+// ParseMissions parses and validates a mission file
+// Unknown fields are rejected so that typos do not silently change a mission.
+func ParseMissions(data []byte) ([]*Mission, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	var f missionFile
+	if err := dec.Decode(&f); err != nil {
+		return nil, err
+	}
+	if len(f.Missions) == 0 {
+		return nil, fmt.Errorf("no missions")
+	}
+
+	seen := make(map[string]bool)
+	for _, m := range f.Missions {
+		if err := m.validate(); err != nil {
+			return nil, err
+		}
+		if seen[m.ID] {
+			return nil, fmt.Errorf("duplicate mission %q", m.ID)
+		}
+		seen[m.ID] = true
+	}
+	return f.Missions, nil
+}
+
+// validate checks a mission and parses its starting field
+func (m *Mission) validate() error {
+	if m.ID == "" {
+		return fmt.Errorf("mission has no id")
+	}
+	if len(m.Name) == 0 {
+		return fmt.Errorf("mission %q has no name", m.ID)
+	}
+	if _, ok := rulesPresets[m.Rules]; m.Rules != "" && !ok {
+		return fmt.Errorf("mission %q: unknown rules %q", m.ID, m.Rules)
+	}
+	if m.Colors != 0 && (m.Colors < MinColors || m.Colors > MaxColors) {
+		return fmt.Errorf("mission %q: colors must be %d to %d, got %d", m.ID, MinColors, MaxColors, m.Colors)
+	}
+	if m.TimeLimit < 0 {
+		return fmt.Errorf("mission %q: time limit must not be negative, got %d", m.ID, m.TimeLimit)
+	}
+	if len(m.Objectives) == 0 {
+		return fmt.Errorf("mission %q has no objectives", m.ID)
+	}
+
+	rules := m.baseRules()
+	if err := rules.Validate(); err != nil {
+		return fmt.Errorf("mission %q: %v", m.ID, err)
+	}
+
+	field, triggers, err := ParseFieldNotation(strings.Join(m.Field, "\n"), rules.Width, rules.Height)
+	if err != nil {
+		return fmt.Errorf("mission %q: %v", m.ID, err)
+	}
+	if len(triggers) > 0 {
+		return fmt.Errorf("mission %q: the field has trigger marks, which missions do not use", m.ID)
+	}
+	m.start = field
+
+	for i, o := range m.Objectives {
+		if err := o.validate(rules); err != nil {
+			return fmt.Errorf("mission %q objective %d: %v", m.ID, i+1, err)
+		}
+	}
+	return nil
+}
+
+// validate checks that an objective has exactly the settings its type uses
+func (o Objective) validate(rules Rules) error {
+	if o.Pieces < 0 {
+		return fmt.Errorf("pieces must not be negative, got %d", o.Pieces)
+	}
+
+	switch o.Type {
+	case ObjectivePop, ObjectiveChain, ObjectiveSurvive:
+		if o.Count < 1 {
+			return fmt.Errorf("%s needs a count of at least 1, got %d", o.Type, o.Count)
+		}
+		if o.Column != 0 || o.Height != 0 {
+			return fmt.Errorf("column and height are only used by trigger objectives")
+		}
+	case ObjectiveTrigger:
+		if o.Count != 0 {
+			return fmt.Errorf("trigger objectives have no count")
+		}
+		if o.Column < 1 || o.Column > rules.Width {
+			return fmt.Errorf("column must be 1 to %d, got %d", rules.Width, o.Column)
+		}
+		if o.Height < 1 || o.Height > rules.Height {
+			return fmt.Errorf("height must be 1 to %d, got %d", rules.Height, o.Height)
+		}
+	default:
+		return fmt.Errorf("unknown objective type %q", o.Type)
+	}
+
+	if o.Type != ObjectivePop {
+		if o.Color != "" {
+			return fmt.Errorf("color is only used by pop objectives")
+		}
+		return nil
+	}
+	for _, c := range rules.palette() {
+		if c.Name() == o.Color {
+			return nil
+		}
+	}
+	return fmt.Errorf("color %q is not in the %d-color palette", o.Color, rules.Colors)
+}
+
+// baseRules returns the mission's preset with its palette size
+func (m *Mission) baseRules() Rules {
+	rules := RulesByName(m.Rules)
+	if m.Colors != 0 {
+		rules.Colors = m.Colors
+	}
+	return rules
+}
+
+// GameRules returns the rules a game of the mission is played with
+func (m *Mission) GameRules() Rules {
+	rules := m.baseRules()
+	rules.Goal = GoalMission
+	rules.Mission = m.ID
+	rules.TimeLimit = m.TimeLimit * 60
+	return rules
+}
+
+// Title returns the mission's name in the current language
+func (m *Mission) Title() string {
+	for _, lang := range []string{currentLanguage, fallbackLanguage} {
+		if name, ok := m.Name[lang]; ok {
+			return name
+		}
+	}
+	// Any name is better than none
+	langs := make([]string, 0, len(m.Name))
+	for lang := range m.Name {
+		langs = append(langs, lang)
+	}
+	sort.Strings(langs)
+	return m.Name[langs[0]]
+}
+
+// Describe returns the objective as shown during play
+func (o Objective) Describe() string {
+	var text string
+	switch o.Type {
+	case ObjectivePop:
+		text = T("mission.pop", o.Count, T("color."+o.Color))
+	case ObjectiveChain:
+		text = T("mission.chain", o.Count)
+	case ObjectiveTrigger:
+		text = T("mission.trigger", o.Column, o.Height)
+	case ObjectiveSurvive:
+		text = T("mission.survive", o.Count)
+	}
+	if o.Pieces > 0 {
+		text = T("mission.pieces", text, o.Pieces)
+	}
+	return text
+}
+
+//go:embed missions.json
+var missionData []byte
+
+// missions holds the available missions in menu order
+var missions []*Mission
+
+// Rules.Validate looks missions up, so the bundled ones are parsed at init
+func init() {
+	missions = mustParseMissions(missionData)
+}
+
+// mustParseMissions parses the bundled missions, which are checked by the tests
+func mustParseMissions(data []byte) []*Mission {
+	list, err := ParseMissions(data)
+	if err != nil {
+		panic(err)
+	}
+	return list
+}
+
+// Missions returns the available missions in menu order
+func Missions() []*Mission {
+	return missions
+}
+
+// MissionByID returns a mission, or nil if there is none with the id
+func MissionByID(id string) *Mission {
+	for _, m := range missions {
+		if m.ID == id {
+			return m
+		}
+	}
+	return nil
+}
+
+// registerMission adds a mission, replacing one with the same id
+func registerMission(m *Mission) {
+	for i, old := range missions {
+		if old.ID == m.ID {
+			missions[i] = m
+			return
+		}
+	}
+	missions = append(missions, m)
+}
+
+// getMissionsDir returns the directory user missions are loaded from
+func getMissionsDir() (string, error) {
+	return getConfigPath("missions")
+}
+
+// LoadUserMissions adds the missions in ~/.puyo/missions/*.json
+// Invalid files are skipped and reported in the returned error.
+func LoadUserMissions() error {
+	dir, err := getMissionsDir()
+	if err != nil {
+		return err
+	}
+
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return err
+	}
+	sort.Strings(paths)
+
+	var problems []string
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			problems = append(problems, err.Error())
+			continue
+		}
+		list, err := ParseMissions(data)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %v", filepath.Base(path), err))
+			continue
+		}
+		for _, m := range list {
+			registerMission(m)
+		}
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("could not load missions: %s", strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// Mission returns the mission being played, or nil
+func (g *Game) Mission() *Mission {
+	if g.Rules.Goal != GoalMission {
+		return nil
+	}
+	return MissionByID(g.Rules.Mission)
+}
+
+// Objective returns the current objective of a mission, or nil
+func (g *Game) Objective() *Objective {
+	m := g.Mission()
+	if m == nil || g.MissionStep >= len(m.Objectives) {
+		return nil
+	}
+	return &m.Objectives[g.MissionStep]
+}
+
+// startMission sets up the mission's starting field and first objective
+func (g *Game) startMission() {
+	m := g.Mission()
+	if m == nil {
+		return
+	}
+	if m.start != nil && m.start.Width == g.Field.Width && m.start.Height == g.Field.Height {
+		g.Field = m.start.Clone()
+	}
+	g.startObjective()
+}
+
+// startObjective starts counting pieces for the current objective
+// A survive objective queues its nuisance puyos.
+func (g *Game) startObjective() {
+	g.MissionPieces = g.PiecesPlaced
+	if o := g.Objective(); o != nil && o.Type == ObjectiveSurvive {
+		g.ReceiveGarbage(o.Count)
+	}
+}
+
+// completeObjective moves on to the next objective, finishing the mission after the last
+func (g *Game) completeObjective() {
+	g.MissionStep++
+	g.emit(ObjectiveDone{Step: g.MissionStep})
+	if g.Objective() == nil {
+		g.finishRun(true)
+		return
+	}
+	g.startObjective()
+}
+
+// checkObjective checks the current objective when a chain step starts popping
+// The popping puyos are still on the field, so the trigger column's height
+// is measured as it was when the chain started.
+func (g *Game) checkObjective() {
+	o := g.Objective()
+	if o == nil {
+		return
+	}
+
+	switch o.Type {
+	case ObjectivePop:
+		popped := 0
+		for _, p := range g.Popping {
+			if g.Field.Grid[p.Y][p.X].Name() == o.Color {
+				popped++
+			}
+		}
+		if popped >= o.Count {
+			g.completeObjective()
+		}
+	case ObjectiveChain:
+		if g.ChainCount >= o.Count {
+			g.completeObjective()
+		}
+	case ObjectiveTrigger:
+		if g.ChainCount == 1 && g.Field.columnHeight(o.Column-1) >= o.Height {
+			g.completeObjective()
+		}
+	}
+}
+
+// checkSettled checks the current objective once the field has settled and
+// the next piece has spawned
+// Surviving means every queued nuisance puyo has fallen or been offset
+// without topping out. An objective that used up its pieces fails the mission.
+func (g *Game) checkSettled() {
+	o := g.Objective()
+	if o == nil || g.GameOver {
+		return
+	}
+	if o.Type == ObjectiveSurvive && g.Garbage.Pending == 0 {
+		g.completeObjective()
+		return
+	}
+	if o.Pieces > 0 && g.PiecesPlaced-g.MissionPieces >= o.Pieces {
+		g.finishRun(false)
+	}
+}
+
+// columnHeight returns the number of puyos in a column
+func (f *Field) columnHeight(x int) int {
+	height := 0
+	for y := 0; y < f.Height; y++ {
+		if f.Grid[y][x] != Empty {
+			height++
+		}
+	}
+	return height
+}
+
+// MissionRecord is the best result of one mission
+type MissionRecord struct {
+	Completed  bool      `json:"completed"`
+	BestFrames int       `json:"best_frames,omitempty"` // Fastest completion
+	Attempts   int       `json:"attempts"`
+	Date       time.Time `json:"date,omitempty"` // When the best time was set
+}
+
+// MissionRecords holds the results of every mission, keyed by mission id
+type MissionRecords struct {
+	Missions map[string]MissionRecord `json:"missions"`
+}
+
+// NewMissionRecords creates empty mission records
+func NewMissionRecords() *MissionRecords {
+	return &MissionRecords{Missions: make(map[string]MissionRecord)}
+}
+
+// Add records a finished attempt at a mission
+// Returns true if it completed the mission faster than before.
+func (r *MissionRecords) Add(id string, completed bool, frames int, date time.Time) bool {
+	if r.Missions == nil {
+		r.Missions = make(map[string]MissionRecord)
+	}
+	rec := r.Missions[id]
+	rec.Attempts++
+
+	best := completed && (!rec.Completed || frames < rec.BestFrames)
+	if best {
+		rec.Completed = true
+		rec.BestFrames = frames
+		rec.Date = date
+	}
+	r.Missions[id] = rec
+	return best
+}
+
+// getMissionRecordsPath returns the path to the mission records file
+func getMissionRecordsPath() (string, error) {
+	return getConfigPath("mission_records.json")
+}
+
+// LoadMissionRecords loads the mission records from disk
+// A corrupt file is an error, so recording a mission never wipes the others.
+func LoadMissionRecords() (*MissionRecords, error) {
+	path, err := getMissionRecordsPath()
+	if err != nil {
+		return NewMissionRecords(), nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return NewMissionRecords(), nil
+		}
+		return nil, err
+	}
+
+	r := NewMissionRecords()
+	if err := json.Unmarshal(data, r); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if r.Missions == nil {
+		r.Missions = make(map[string]MissionRecord)
+	}
+	return r, nil
+}
+
+// SaveMissionRecords saves the mission records to disk
+func SaveMissionRecords(r *MissionRecords) error {
+	path, err := getMissionRecordsPath()
+	if err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, data, 0644)
+}
+
+// RecordMission adds a finished mission game to the stored records
+// Returns true if it set a new best time.
+func RecordMission(game *Game) (bool, error) {
+	if !game.GameOver || game.Mission() == nil {
+		return false, nil
+	}
+
+	r, err := LoadMissionRecords()
+	if err != nil {
+		return false, err
+	}
+	best := r.Add(game.Rules.Mission, game.Cleared, game.Frames, time.Now().Truncate(time.Second))
+	return best, SaveMissionRecords(r)
+}
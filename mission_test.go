@@ -0,0 +1,326 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// missionGame starts a bundled mission
+func missionGame(t *testing.T, id string) *Game {
+	t.Helper()
+	m := MissionByID(id)
+	if m == nil {
+		t.Fatalf("Missing bundled mission %q", id)
+	}
+	return NewGameWithRules(m.GameRules(), 1)
+}
+
+func TestBundledMissionsValid(t *testing.T) {
+	if len(Missions()) == 0 {
+		t.Fatal("Expected bundled missions")
+	}
+	for _, m := range Missions() {
+		rules := m.GameRules()
+		if err := rules.Validate(); err != nil {
+			t.Errorf("%s: %v", m.ID, err)
+		}
+		if m.Title() == "" {
+			t.Errorf("%s: missing title", m.ID)
+		}
+		for _, o := range m.Objectives {
+			if o.Describe() == "" {
+				t.Errorf("%s: objective %q has no description", m.ID, o.Type)
+			}
+		}
+
+		game := NewGameWithRules(rules, 1)
+		if game.Field.Notation() != m.start.Notation() {
+			t.Errorf("%s: expected the game to start on the mission's field", m.ID)
+		}
+	}
+}
+
+func TestParseMissionsRejectsBadSchema(t *testing.T) {
+	tests := map[string]string{
+		"unknown field":     `{"missions": [{"id": "a", "name": {"en": "A"}, "objectives": [{"type": "chain", "count": 2}], "colour": 4}]}`,
+		"no missions":       `{"missions": []}`,
+		"no id":             `{"missions": [{"name": {"en": "A"}, "objectives": [{"type": "chain", "count": 2}]}]}`,
+		"no name":           `{"missions": [{"id": "a", "objectives": [{"type": "chain", "count": 2}]}]}`,
+		"no objectives":     `{"missions": [{"id": "a", "name": {"en": "A"}}]}`,
+		"unknown rules":     `{"missions": [{"id": "a", "name": {"en": "A"}, "rules": "tetris", "objectives": [{"type": "chain", "count": 2}]}]}`,
+		"bad colors":        `{"missions": [{"id": "a", "name": {"en": "A"}, "colors": 9, "objectives": [{"type": "chain", "count": 2}]}]}`,
+		"negative time":     `{"missions": [{"id": "a", "name": {"en": "A"}, "time_limit": -1, "objectives": [{"type": "chain", "count": 2}]}]}`,
+		"unknown type":      `{"missions": [{"id": "a", "name": {"en": "A"}, "objectives": [{"type": "dance"}]}]}`,
+		"missing count":     `{"missions": [{"id": "a", "name": {"en": "A"}, "objectives": [{"type": "chain"}]}]}`,
+		"color off palette": `{"missions": [{"id": "a", "name": {"en": "A"}, "colors": 3, "objectives": [{"type": "pop", "count": 4, "color": "purple"}]}]}`,
+		"color on chain":    `{"missions": [{"id": "a", "name": {"en": "A"}, "objectives": [{"type": "chain", "count": 2, "color": "red"}]}]}`,
+		"column outside":    `{"missions": [{"id": "a", "name": {"en": "A"}, "objectives": [{"type": "trigger", "column": 7, "height": 3}]}]}`,
+		"trigger count":     `{"missions": [{"id": "a", "name": {"en": "A"}, "objectives": [{"type": "trigger", "column": 3, "height": 3, "count": 2}]}]}`,
+		"negative pieces":   `{"missions": [{"id": "a", "name": {"en": "A"}, "objectives": [{"type": "chain", "count": 2, "pieces": -1}]}]}`,
+		"bad field":         `{"missions": [{"id": "a", "name": {"en": "A"}, "field": ["RGB"], "objectives": [{"type": "chain", "count": 2}]}]}`,
+		"trigger marks":     `{"missions": [{"id": "a", "name": {"en": "A"}, "field": ["r....."], "objectives": [{"type": "chain", "count": 2}]}]}`,
+		"duplicate id": `{"missions": [
+			{"id": "a", "name": {"en": "A"}, "objectives": [{"type": "chain", "count": 2}]},
+			{"id": "a", "name": {"en": "B"}, "objectives": [{"type": "chain", "count": 3}]}]}`,
+	}
+	for name, data := range tests {
+		if _, err := ParseMissions([]byte(data)); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestPopObjective(t *testing.T) {
+	game := missionGame(t, "green-8")
+	events := recordEvents(game)
+
+	// A single green in the gap joins three groups into eight
+	game.Field.Grid[FieldHeight-3][2] = Green
+	runChain(game)
+
+	if !game.Cleared || !game.GameOver || game.MissionStep != 1 {
+		t.Fatalf("Expected the mission to be complete, cleared=%v step=%d", game.Cleared, game.MissionStep)
+	}
+	done := false
+	for _, ev := range *events {
+		if ev == (ObjectiveDone{Step: 1}) {
+			done = true
+		}
+	}
+	if !done {
+		t.Error("Expected an ObjectiveDone event")
+	}
+}
+
+func TestPopObjectiveNeedsColor(t *testing.T) {
+	game := missionGame(t, "green-8")
+
+	// Four reds are not eight greens
+	popFour(game)
+	runChain(game)
+
+	if game.Cleared || game.MissionStep != 0 {
+		t.Error("Expected popping another color not to count")
+	}
+}
+
+func TestChainObjective(t *testing.T) {
+	game := missionGame(t, "three-chain")
+	game.Field.Grid[FieldHeight-3][2] = Green
+	game.Field.Grid[FieldHeight-2][2] = Green
+	runChain(game)
+
+	if game.MaxChain != 3 || !game.Cleared {
+		t.Errorf("Expected the 3-chain to complete the mission, got a %d-chain", game.MaxChain)
+	}
+}
+
+func TestObjectivePieceLimit(t *testing.T) {
+	game := missionGame(t, "three-chain")
+	for i := 0; i < 5; i++ {
+		lockWithoutChain(game)
+	}
+	if game.GameOver {
+		t.Fatal("Expected the mission to continue with pieces left")
+	}
+
+	lockWithoutChain(game)
+	if !game.GameOver || game.Cleared {
+		t.Error("Expected the mission to fail after its sixth piece")
+	}
+}
+
+func TestTriggerObjective(t *testing.T) {
+	game := missionGame(t, "tall-trigger")
+
+	// Firing the reds with the column one short does not count
+	short := game.Field.Clone()
+	game.Field.Grid[FieldHeight-1][3] = Red
+	runChain(game)
+	if game.MissionStep != 0 {
+		t.Fatal("Expected a chain with a short column not to count")
+	}
+
+	game = missionGame(t, "tall-trigger")
+	game.Field = short
+	game.Field.Grid[FieldHeight-11][2] = Yellow
+	game.Field.Grid[FieldHeight-1][3] = Red
+	runChain(game)
+	if !game.Cleared {
+		t.Error("Expected a chain with the column at height 11 to complete the mission")
+	}
+}
+
+func TestSurviveObjective(t *testing.T) {
+	game := missionGame(t, "survive-30")
+	if game.Garbage.Pending != 30 {
+		t.Fatalf("Expected 30 nuisance puyos queued at the start, got %d", game.Garbage.Pending)
+	}
+
+	lockWithoutChain(game)
+
+	if countColor(game.Field, Nuisance) != 30 {
+		t.Errorf("Expected 30 nuisance puyos on the field, got %d", countColor(game.Field, Nuisance))
+	}
+	if !game.Cleared {
+		t.Error("Expected surviving the garbage to complete the mission")
+	}
+}
+
+func TestObjectivesInSequence(t *testing.T) {
+	game := missionGame(t, "gauntlet")
+	if o := game.Objective(); o == nil || o.Type != ObjectiveChain {
+		t.Fatal("Expected the chain objective first")
+	}
+
+	// A 2-chain that pops six reds in its second step finishes the first
+	// objective only; the pop objective starts counting afterwards
+	game.Field, _, _ = ParseFieldNotation(twoChainField, FieldWidth, FieldHeight)
+	runChain(game)
+	if game.MissionStep != 1 {
+		t.Fatalf("Expected the first objective done, got step %d", game.MissionStep)
+	}
+
+	for x := 0; x < 6; x++ {
+		game.Field.Grid[FieldHeight-1][x] = Red
+	}
+	runChain(game)
+	if game.MissionStep != 2 || game.Objective().Type != ObjectiveSurvive {
+		t.Fatalf("Expected the survive objective third, got step %d", game.MissionStep)
+	}
+	if game.Garbage.Pending != 18 {
+		t.Errorf("Expected the survive objective to queue 18 nuisance, got %d", game.Garbage.Pending)
+	}
+
+	lockWithoutChain(game)
+	if !game.Cleared {
+		t.Error("Expected the last objective to complete the mission")
+	}
+}
+
+func TestMissionTimeLimit(t *testing.T) {
+	game := missionGame(t, "green-8")
+	limit := MissionByID("green-8").TimeLimit * 60
+	if game.TimerFrames() != limit {
+		t.Errorf("Expected the timer to start at %d frames, got %d", limit, game.TimerFrames())
+	}
+
+	for i := 0; i < limit; i++ {
+		game.TickTimer()
+	}
+	if !game.GameOver || game.Cleared {
+		t.Error("Expected the mission to fail when time runs out")
+	}
+}
+
+func TestMissionSuspendKeepsProgress(t *testing.T) {
+	game := missionGame(t, "gauntlet")
+	game.MissionStep = 2
+	game.MissionPieces = 7
+
+	restored := mustRestore(t, game.Suspend())
+	if restored.MissionStep != 2 || restored.MissionPieces != 7 || restored.Mission() == nil {
+		t.Errorf("Expected mission progress to survive a suspend, got step %d", restored.MissionStep)
+	}
+}
+
+func TestMissionRecords(t *testing.T) {
+	r := NewMissionRecords()
+	date := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	if r.Add("a", false, 100, date) {
+		t.Error("Expected a failed attempt not to set a best time")
+	}
+	if !r.Add("a", true, 900, date) {
+		t.Error("Expected the first completion to set a best time")
+	}
+	if r.Add("a", true, 1000, date) {
+		t.Error("Expected a slower completion not to set a best time")
+	}
+	if !r.Add("a", true, 600, date) {
+		t.Error("Expected a faster completion to set a best time")
+	}
+
+	rec := r.Missions["a"]
+	if !rec.Completed || rec.BestFrames != 600 || rec.Attempts != 4 {
+		t.Errorf("Unexpected record %+v", rec)
+	}
+}
+
+func TestRecordMission(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	game := missionGame(t, "green-8")
+	if best, err := RecordMission(game); err != nil || best {
+		t.Fatalf("Expected an unfinished mission not to be recorded, got %v (%v)", best, err)
+	}
+
+	game.Frames = 321
+	game.finishRun(true)
+	if best, err := RecordMission(game); err != nil || !best {
+		t.Fatalf("Expected a best time, got %v (%v)", best, err)
+	}
+
+	r, err := LoadMissionRecords()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rec := r.Missions["green-8"]; !rec.Completed || rec.BestFrames != 321 || rec.Attempts != 1 {
+		t.Errorf("Unexpected stored record %+v", rec)
+	}
+}
+
+func TestRecordMissionKeepsCorruptFile(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	path, _ := getMissionRecordsPath()
+	if err := os.WriteFile(path, []byte(`{"missions": `), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	game := missionGame(t, "green-8")
+	game.finishRun(true)
+	if _, err := RecordMission(game); err == nil {
+		t.Error("Expected an error for a corrupt mission records file")
+	}
+	if data, _ := os.ReadFile(path); string(data) != `{"missions": ` {
+		t.Errorf("Expected the corrupt file to be left alone, got %q", data)
+	}
+}
+
+func TestLoadUserMissions(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	saved := append([]*Mission(nil), missions...)
+	t.Cleanup(func() { missions = saved })
+
+	dir := filepath.Join(home, ".puyo", "missions")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	good := `{"missions": [{"id": "mine", "name": {"ja": "自作"}, "time_limit": 30, "objectives": [{"type": "chain", "count": 4}]}]}`
+	if err := os.WriteFile(filepath.Join(dir, "good.json"), []byte(good), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "bad.json"), []byte(`{"missions": [{"id": "x"}]}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	err := LoadUserMissions()
+	if err == nil || !strings.Contains(err.Error(), "bad.json") {
+		t.Errorf("Expected the bad file to be reported, got %v", err)
+	}
+
+	m := MissionByID("mine")
+	if m == nil {
+		t.Fatal("Expected the user mission to be registered")
+	}
+	// Only a Japanese name: it is shown in any language
+	if m.Title() != "自作" {
+		t.Errorf("Expected the only name as the title, got %q", m.Title())
+	}
+	if len(Missions()) != len(saved)+1 {
+		t.Errorf("Expected %d missions, got %d", len(saved)+1, len(Missions()))
+	}
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// Field notation describes a field as text, one line per row from the top:
+// "." is an empty cell, R, G, B, Y, P and O are puyo colors and N is
+// nuisance. Rows are bottom-aligned, so only the occupied part needs to be
+// written.
+// Lowercase letters mark trigger puyos: cells the player is meant to fill,
+// which are left empty on the field.
+
+// notationColors maps notation letters to colors
+var notationColors = map[rune]Color{
+	'R': Red,
+	'G': Green,
+	'B': Blue,
+	'Y': Yellow,
+	'P': Purple,
+	'O': Orange,
+	'N': Nuisance,
+}
+
+// colorLetter returns the notation letter of a color
+func colorLetter(c Color) rune {
+	for r, color := range notationColors {
+		if color == c {
+			return r
+		}
+	}
+	return '.'
+}
+
+// ParseFieldNotation reads a width x height field from field notation
+// It returns the field and the trigger puyos marked in lowercase.
+func ParseFieldNotation(text string, width, height int) (*Field, []PieceCell, error) {
+	var rows []string
+	for _, line := range strings.Split(strings.TrimSpace(text), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			rows = append(rows, line)
+		}
+	}
+	if len(rows) > height {
+		return nil, nil, fmt.Errorf("field has %d rows, at most %d fit", len(rows), height)
+	}
+
+	f := NewFieldSize(width, height)
+	var triggers []PieceCell
+	top := height - len(rows)
+	for i, row := range rows {
+		cells := []rune(row)
+		if len(cells) != width {
+			return nil, nil, fmt.Errorf("row %d has %d cells, expected %d", i+1, len(cells), width)
+		}
+		for x, r := range cells {
+			if r == '.' {
+				continue
+			}
+			color, ok := notationColors[unicode.ToUpper(r)]
+			if !ok {
+				return nil, nil, fmt.Errorf("row %d: unknown cell %q", i+1, r)
+			}
+			if unicode.IsLower(r) {
+				triggers = append(triggers, PieceCell{Pos: Position{x, top + i}, Color: color})
+			} else {
+				f.Grid[top+i][x] = color
+			}
+		}
+	}
+	return f, triggers, nil
+}
+
+// Notation returns the field in field notation, from the highest occupied row
+func (f *Field) Notation() string {
+	top := f.Height
+	for y := f.Height - 1; y >= 0; y-- {
+		for x := 0; x < f.Width; x++ {
+			if f.Grid[y][x] != Empty {
+				top = y
+			}
+		}
+	}
+
+	var b strings.Builder
+	for y := top; y < f.Height; y++ {
+		for x := 0; x < f.Width; x++ {
+			b.WriteRune(colorLetter(f.Grid[y][x]))
+		}
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
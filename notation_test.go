@@ -0,0 +1,53 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestParseFieldNotation(t *testing.T) {
+	f, triggers, err := ParseFieldNotation(`
+		..r...
+		.GBY..
+		RPOR.G
+	`, 6, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if f.Grid[4][0] != Red || f.Grid[4][2] != Orange || f.Grid[4][5] != Green || f.Grid[3][3] != Yellow {
+		t.Errorf("Rows not bottom-aligned:\n%s", f.Notation())
+	}
+	if f.Grid[2][2] != Empty {
+		t.Error("Expected the trigger cell to be left empty")
+	}
+	if len(triggers) != 1 || triggers[0] != (PieceCell{Pos: Position{2, 2}, Color: Red}) {
+		t.Errorf("Unexpected triggers %v", triggers)
+	}
+}
+
+func TestParseFieldNotationErrors(t *testing.T) {
+	bad := []string{
+		"R.....\nR.....\nR.....", // Too many rows
+		"R....",                  // Too narrow
+		"R....X",                 // Unknown cell
+	}
+	for _, text := range bad {
+		if _, _, err := ParseFieldNotation(text, 6, 2); err == nil {
+			t.Errorf("Expected an error for %q", text)
+		}
+	}
+}
+
+func TestFieldNotationRoundTrip(t *testing.T) {
+	text := ".G....\nRRBY.P\n"
+	f, _, err := ParseFieldNotation(text, 6, 12)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := f.Notation(); got != text {
+		t.Errorf("Expected %q, got %q", text, got)
+	}
+	if NewField().Notation() != "" {
+		t.Error("Expected an empty field to have no rows")
+	}
+}
@@ -0,0 +1,241 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// PieceKind names the shape of a falling piece
+type PieceKind string
+
+const (
+	PiecePair   PieceKind = "pair"   // Main and sub, the sub turning around the main
+	PieceTriple PieceKind = "triple" // L of three: a vertical pair of one color and one puyo beside it
+	PieceQuad   PieceKind = "quad"   // 2x2 of two colors, one color per column
+	PieceBig    PieceKind = "big"    // 2x2 of one color; rotating changes the color
+)
+
+// PieceCell is one puyo of a falling piece
+type PieceCell struct {
+	Pos   Position
+	Color Color
+}
+
+// Shape is the rotation table of a piece kind
+type Shape struct {
+	Cells      [4][]Position // Offsets from the piece position, by rotation; cell 0 is the main puyo, cell 1 the sub
+	ColorCycle bool          // Rotating cycles the color instead of turning the piece
+}
+
+// Corners of the 2x2 box larger pieces turn in, relative to the bottom left
+var (
+	boxBL = Position{0, 0}
+	boxTL = Position{0, -1}
+	boxTR = Position{1, -1}
+	boxBR = Position{1, 0}
+)
+
+// shapes holds the rotation table of every piece kind
+// Pieces larger than a pair turn in place: every cell moves one corner of
+// the box clockwise per rotation.
+var shapes = map[PieceKind]Shape{
+	PiecePair: {Cells: [4][]Position{
+		{{0, 0}, {0, -1}}, // Sub on top
+		{{0, 0}, {1, 0}},  // Sub on the right
+		{{0, 0}, {0, 1}},  // Sub on the bottom
+		{{0, 0}, {-1, 0}}, // Sub on the left
+	}},
+	PieceTriple: {Cells: [4][]Position{
+		{boxBL, boxTL, boxBR},
+		{boxTL, boxTR, boxBL},
+		{boxTR, boxBR, boxTL},
+		{boxBR, boxBL, boxTR},
+	}},
+	PieceQuad: {Cells: [4][]Position{
+		{boxBL, boxTL, boxTR, boxBR},
+		{boxTL, boxTR, boxBR, boxBL},
+		{boxTR, boxBR, boxBL, boxTL},
+		{boxBR, boxBL, boxTL, boxTR},
+	}},
+	PieceBig: {Cells: [4][]Position{
+		{boxBL, boxTL, boxTR, boxBR},
+		{boxBL, boxTL, boxTR, boxBR},
+		{boxBL, boxTL, boxTR, boxBR},
+		{boxBL, boxTL, boxTR, boxBR},
+	}, ColorCycle: true},
+}
+
+// shape returns the piece's rotation table
+func (p *PuyoPair) shape() Shape {
+	if s, ok := shapes[p.Kind]; ok {
+		return s
+	}
+	return shapes[PiecePair]
+}
+
+// shapeKind returns the piece kind, PiecePair for a plain pair
+func (p *PuyoPair) shapeKind() PieceKind {
+	if p.Kind == "" {
+		return PiecePair
+	}
+	return p.Kind
+}
+
+// cellPositions returns where the piece's cells are at a position and rotation
+func (p *PuyoPair) cellPositions(pos Position, rotate int) []Position {
+	offsets := p.shape().Cells[(rotate%4+4)%4]
+	cells := make([]Position, len(offsets))
+	for i, o := range offsets {
+		cells[i] = Position{pos.X + o.X, pos.Y + o.Y}
+	}
+	return cells
+}
+
+// colors returns the color of every cell, main and sub first
+func (p *PuyoPair) colors() []Color {
+	colors := []Color{p.Main.Color, p.Sub.Color}
+	for _, puyo := range p.Extra {
+		colors = append(colors, puyo.Color)
+	}
+	return colors
+}
+
+// Cells returns the piece's cells on the field, main and sub first
+func (p *PuyoPair) Cells() []PieceCell {
+	positions := p.cellPositions(p.Pos, p.Rotate)
+	colors := p.colors()
+	cells := make([]PieceCell, len(positions))
+	for i, pos := range positions {
+		cells[i] = PieceCell{Pos: pos, Color: colors[i]}
+	}
+	return cells
+}
+
+// setColor paints every cell one color, for pieces that cycle their color
+func (p *PuyoPair) setColor(c Color) {
+	p.Main.Color = c
+	p.Sub.Color = c
+	for i := range p.Extra {
+		p.Extra[i].Color = c
+	}
+}
+
+// newPiece builds a piece of a kind from its colors
+// a is used for the main cells and b for the rest (see PieceKind).
+func newPiece(kind PieceKind, a, b Color) *PuyoPair {
+	p := &PuyoPair{Kind: kind, Main: Puyo{a}, Sub: Puyo{b}}
+	switch kind {
+	case PieceTriple:
+		p.Sub = Puyo{a}
+		p.Extra = []Puyo{{b}}
+	case PieceQuad:
+		p.Sub = Puyo{a}
+		p.Extra = []Puyo{{b}, {b}}
+	case PieceBig:
+		p.Sub = Puyo{a}
+		p.Extra = []Puyo{{a}, {a}}
+	default:
+		p.Kind = ""
+	}
+	return p
+}
+
+// DropSet is the repeating sequence of piece kinds a game deals
+type DropSet struct {
+	Name   string
+	Pieces []PieceKind
+}
+
+// dropSetLetters maps the drop set notation to piece kinds
+var dropSetLetters = map[rune]PieceKind{
+	'P': PiecePair,
+	'T': PieceTriple,
+	'Q': PieceQuad,
+	'B': PieceBig,
+}
+
+// dropSetPatterns holds the built-in drop sets in notation: P pair,
+// T triple, Q quad, B big puyo
+// The fever sets are 16-piece cycles in the style of Fever rules, one per play style.
+var dropSetPatterns = map[string]string{
+	"pairs":         "P",
+	"fever":         "PPTPPQPPTPPBPPTQ",
+	"fever-triples": "PTPTPTQPTPTPTPTB",
+	"fever-quads":   "PPQPPBPPQPPBPPQB",
+	"fever-gentle":  "PPPPTPPPPQPPPPTP",
+}
+
+// DropSetNames lists the built-in drop sets
+var DropSetNames = []string{"pairs", "fever", "fever-triples", "fever-quads", "fever-gentle"}
+
+// ParseDropSet reads a drop set from its notation
+func ParseDropSet(name, pattern string) (DropSet, error) {
+	set := DropSet{Name: name}
+	for _, r := range strings.ToUpper(pattern) {
+		kind, ok := dropSetLetters[r]
+		if !ok {
+			return DropSet{}, fmt.Errorf("drop set %q: unknown piece %q", name, r)
+		}
+		set.Pieces = append(set.Pieces, kind)
+	}
+	if len(set.Pieces) == 0 {
+		return DropSet{}, fmt.Errorf("drop set %q is empty", name)
+	}
+	return set, nil
+}
+
+// DropSetByName returns a built-in drop set; "" is the pairs-only set
+func DropSetByName(name string) (DropSet, error) {
+	if name == "" {
+		name = "pairs"
+	}
+	pattern, ok := dropSetPatterns[name]
+	if !ok {
+		known := append([]string(nil), DropSetNames...)
+		sort.Strings(known)
+		return DropSet{}, fmt.Errorf("unknown drop set %q (known: %s)", name, strings.Join(known, ", "))
+	}
+	return ParseDropSet(name, pattern)
+}
+
+// nextPieceKind returns the kind of the next piece dealt
+func (g *Game) nextPieceKind() PieceKind {
+	set, err := DropSetByName(g.Rules.DropSet)
+	if err != nil {
+		return PiecePair
+	}
+	kind := set.Pieces[g.PiecesDealt%len(set.Pieces)]
+	g.PiecesDealt++
+	return kind
+}
+
+// GhostCells returns where each cell of the current piece will come to rest
+// if it is dropped and locked now
+func (g *Game) GhostCells() []PieceCell {
+	if g.Current == nil {
+		return nil
+	}
+
+	cells := g.Current.Cells()
+
+	// The lowest cells land first, each on top of its own column
+	order := make([]int, len(cells))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return cells[order[a]].Pos.Y > cells[order[b]].Pos.Y
+	})
+
+	tops := make(map[int]int)
+	for _, i := range order {
+		x := cells[i].Pos.X
+		if _, ok := tops[x]; !ok {
+			tops[x] = g.columnTop(x)
+		}
+		cells[i].Pos.Y = tops[x]
+		tops[x]--
+	}
+	return cells
+}
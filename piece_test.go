@@ -0,0 +1,282 @@
+package main
+
+import (
+	"io"
+	"os"
+	"strings"
+	"testing"
+)
+
+// gameWithPiece returns a game whose current piece is replaced by one of kind
+func gameWithPiece(kind PieceKind, a, b Color) *Game {
+	game := NewGameWithSeed(4, 1)
+	game.Current = newPiece(kind, a, b)
+	game.Current.Pos = Position{2, 5}
+	return game
+}
+
+func TestShapeRotationTables(t *testing.T) {
+	for kind, shape := range shapes {
+		cells := len(shape.Cells[0])
+		for rotate, offsets := range shape.Cells {
+			if len(offsets) != cells {
+				t.Errorf("%s rotation %d: %d cells, expected %d", kind, rotate, len(offsets), cells)
+			}
+			seen := make(map[Position]bool)
+			for _, o := range offsets {
+				if seen[o] {
+					t.Errorf("%s rotation %d: cell %v used twice", kind, rotate, o)
+				}
+				seen[o] = true
+			}
+		}
+	}
+}
+
+func TestLargePiecesTurnInPlace(t *testing.T) {
+	for _, kind := range []PieceKind{PieceQuad, PieceBig} {
+		p := newPiece(kind, Red, Green)
+		box := make(map[Position]bool)
+		for _, pos := range p.cellPositions(Position{}, 0) {
+			box[pos] = true
+		}
+
+		for rotate := 1; rotate < 4; rotate++ {
+			for _, pos := range p.cellPositions(Position{}, rotate) {
+				if !box[pos] {
+					t.Errorf("%s rotation %d leaves the 2x2 box at %v", kind, rotate, pos)
+				}
+			}
+		}
+	}
+}
+
+func TestTripleRotation(t *testing.T) {
+	game := gameWithPiece(PieceTriple, Red, Blue)
+
+	// The vertical pair on the left with the odd color bottom right
+	cells := game.Current.Cells()
+	if cells[0].Pos != (Position{2, 5}) || cells[1].Pos != (Position{2, 4}) || cells[2].Pos != (Position{3, 5}) {
+		t.Fatalf("Unexpected triple layout %v", cells)
+	}
+	if cells[0].Color != Red || cells[1].Color != Red || cells[2].Color != Blue {
+		t.Fatalf("Unexpected triple colors %v", cells)
+	}
+
+	// Clockwise: the pair lies across the top, the odd color bottom left
+	if !game.Move(0, 0, 1) {
+		t.Fatal("Expected the triple to rotate")
+	}
+	cells = game.Current.Cells()
+	if cells[0].Pos != (Position{2, 4}) || cells[1].Pos != (Position{3, 4}) || cells[2].Pos != (Position{2, 5}) {
+		t.Errorf("Unexpected layout after rotating: %v", cells)
+	}
+
+	for i := 0; i < 3; i++ {
+		game.Move(0, 0, 1)
+	}
+	if game.Current.Rotate != 0 {
+		t.Errorf("Expected four rotations to come back to 0, got %d", game.Current.Rotate)
+	}
+}
+
+func TestQuadBlockedByWall(t *testing.T) {
+	game := gameWithPiece(PieceQuad, Red, Green)
+	game.Current.Pos.X = FieldWidth - 2
+
+	if game.CanMove(1, 0, 0) {
+		t.Error("Expected the right column of the quad to hit the wall")
+	}
+	if !game.CanMove(-1, 0, 0) {
+		t.Error("Expected the quad to move left")
+	}
+
+	game.Field.Grid[6][FieldWidth-1] = Red
+	if game.CanMove(0, 1, 0) {
+		t.Error("Expected the quad to land on the puyo under its right column")
+	}
+}
+
+func TestBigPuyoCyclesColor(t *testing.T) {
+	game := gameWithPiece(PieceBig, Red, Red)
+	events := recordEvents(game)
+	before := game.Current.cellPositions(game.Current.Pos, game.Current.Rotate)
+
+	if !game.Move(0, 0, 1) {
+		t.Fatal("Expected the big puyo to change color")
+	}
+	for _, cell := range game.Current.Cells() {
+		if cell.Color != Green {
+			t.Errorf("Expected every cell to turn green, got %v", cell.Color)
+		}
+	}
+	for i, pos := range game.Current.cellPositions(game.Current.Pos, game.Current.Rotate) {
+		if pos != before[i] {
+			t.Error("Expected the big puyo not to move when its color changes")
+		}
+	}
+	if len(*events) != 1 {
+		t.Errorf("Expected one rotation event, got %d", len(*events))
+	}
+
+	// Counter-clockwise from red wraps to the last color of the palette
+	game.Move(0, 0, -1)
+	game.Move(0, 0, -1)
+	if game.Current.Main.Color != Yellow {
+		t.Errorf("Expected yellow with 4 colors, got %v", game.Current.Main.Color.Name())
+	}
+}
+
+func TestLockPlacesEveryCell(t *testing.T) {
+	game := gameWithPiece(PieceQuad, Red, Green)
+	events := recordEvents(game)
+
+	game.HardDrop()
+	game.LockPair()
+
+	bottom := FieldHeight - 1
+	if game.Field.Grid[bottom][2] != Red || game.Field.Grid[bottom-1][2] != Red ||
+		game.Field.Grid[bottom][3] != Green || game.Field.Grid[bottom-1][3] != Green {
+		t.Errorf("Quad not placed: %v %v", game.Field.Grid[bottom-1], game.Field.Grid[bottom])
+	}
+
+	for _, ev := range *events {
+		if locked, ok := ev.(PairLocked); ok && len(locked.Cells) != 4 {
+			t.Errorf("Expected 4 locked cells, got %d", len(locked.Cells))
+		}
+	}
+}
+
+func TestGhostCellsTriple(t *testing.T) {
+	game := gameWithPiece(PieceTriple, Red, Blue)
+	game.Field.Grid[FieldHeight-1][2] = Green
+
+	cells := game.GhostCells()
+	bottom := FieldHeight - 1
+	if cells[0].Pos != (Position{2, bottom - 1}) || cells[1].Pos != (Position{2, bottom - 2}) || cells[2].Pos != (Position{3, bottom}) {
+		t.Errorf("Unexpected ghost cells %v", cells)
+	}
+}
+
+func TestParseDropSet(t *testing.T) {
+	set, err := ParseDropSet("test", "ptqb")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := []PieceKind{PiecePair, PieceTriple, PieceQuad, PieceBig}
+	for i, kind := range expect {
+		if set.Pieces[i] != kind {
+			t.Errorf("Piece %d: expected %s, got %s", i, kind, set.Pieces[i])
+		}
+	}
+
+	for _, pattern := range []string{"", "PPX"} {
+		if _, err := ParseDropSet("bad", pattern); err == nil {
+			t.Errorf("Expected an error for %q", pattern)
+		}
+	}
+
+	for _, name := range DropSetNames {
+		if _, err := DropSetByName(name); err != nil {
+			t.Errorf("%s: %v", name, err)
+		}
+	}
+	if _, err := DropSetByName("nope"); err == nil {
+		t.Error("Expected an error for an unknown drop set")
+	}
+}
+
+func TestDropSetDealsPattern(t *testing.T) {
+	game := NewGameWithRules(RulesByName("mixed"), 5)
+	set, _ := DropSetByName("fever")
+
+	kinds := []PieceKind{game.Current.shapeKind(), game.Next.shapeKind()}
+	for len(kinds) < 2*len(set.Pieces) {
+		kinds = append(kinds, game.generatePuyoPair().shapeKind())
+	}
+
+	for i, kind := range kinds {
+		if want := set.Pieces[i%len(set.Pieces)]; kind != want {
+			t.Fatalf("Piece %d: expected %s, got %s", i, want, kind)
+		}
+	}
+}
+
+func TestQuadHasTwoColors(t *testing.T) {
+	rules := DefaultRules()
+	rules.DropSet = "fever-quads"
+	game := NewGameWithRules(rules, 3)
+
+	for i := 0; i < 200; i++ {
+		p := game.generatePuyoPair()
+		if p.Kind == PieceQuad && p.Main.Color == p.Extra[0].Color {
+			t.Fatalf("Quad %d has one color", i)
+		}
+		for _, cell := range p.Cells() {
+			if cell.Color < Red || cell.Color > Yellow {
+				t.Fatalf("Piece %d has a color outside the palette: %v", i, cell.Color)
+			}
+		}
+	}
+}
+
+func TestWidePieceSpawnsInsideField(t *testing.T) {
+	rules := DefaultRules()
+	rules.DropSet = "fever-quads"
+	rules.SpawnColumn = rules.Width - 1
+	game := NewGameWithRules(rules, 1)
+
+	for i := 0; i < 16; i++ {
+		p := game.generatePuyoPair()
+		for _, cell := range p.Cells() {
+			if cell.Pos.X < 0 || cell.Pos.X >= rules.Width {
+				t.Fatalf("%s spawned outside the field at %v", p.shapeKind(), cell.Pos)
+			}
+		}
+	}
+}
+
+func TestSuspendKeepsPiece(t *testing.T) {
+	game := NewGameWithRules(RulesByName("mixed"), 9)
+	for game.Next.Kind == "" {
+		game.Next = game.generatePuyoPair()
+	}
+
+	restored := mustRestore(t, game.Suspend())
+	if restored.Next.Kind != game.Next.Kind || len(restored.Next.Extra) != len(game.Next.Extra) {
+		t.Errorf("Expected the next %s to be restored, got %+v", game.Next.Kind, restored.Next)
+	}
+	if restored.PiecesDealt != game.PiecesDealt {
+		t.Errorf("Expected the drop set position %d, got %d", game.PiecesDealt, restored.PiecesDealt)
+	}
+
+	// The piece sequence continues unchanged
+	for i := 0; i < 16; i++ {
+		if want, got := game.generatePuyoPair(), restored.generatePuyoPair(); want.shapeKind() != got.shapeKind() || want.Main != got.Main {
+			t.Fatalf("Piece %d differs after restore", i)
+		}
+	}
+}
+
+func TestDisplayDrawsEveryCellOnACopy(t *testing.T) {
+	game := gameWithPiece(PieceQuad, Red, Green)
+
+	// Capture the printed field
+	stdout := os.Stdout
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.Stdout = w
+	game.Display()
+	w.Close()
+	os.Stdout = stdout
+	out, _ := io.ReadAll(r)
+
+	if !game.Field.IsEmpty() {
+		t.Error("Expected Display not to write the piece into the field")
+	}
+	if n := strings.Count(string(out), Red.String()) + strings.Count(string(out), Green.String()); n != 4 {
+		t.Errorf("Expected all 4 cells of the quad to be drawn, got %d:\n%s", n, out)
+	}
+}
@@ -0,0 +1,94 @@
+package main
+
+// Chain potential
+//
+// The chain potential of a field is the longest chain that a few more
+// puyos could fire: for every column and color, 1 to K puyos of that color
+// are dropped into the column and the field is resolved. The best result
+// tells a hint overlay where to play, a CPU opponent how good a field is,
+// and the post-game report how much chain the player had built.
+
+// MaxKeyPuyos is the most puyos the potential search drops into a column
+const MaxKeyPuyos = 3
+
+// Potential is the best chain a field can fire with a few more puyos
+type Potential struct {
+	Chain    int   // Longest chain fired (0 if nothing fires)
+	Score    int   // Points for that chain
+	Column   int   // Column the puyos are dropped in, from 0 at the left
+	Color    Color // Color of the dropped puyos
+	KeyPuyos int   // Puyos needed to fire the chain
+}
+
+// better reports whether p beats q: a longer chain, then fewer key puyos,
+// then more points
+func (p Potential) better(q Potential) bool {
+	if p.Chain != q.Chain {
+		return p.Chain > q.Chain
+	}
+	if p.KeyPuyos != q.KeyPuyos {
+		return p.KeyPuyos < q.KeyPuyos
+	}
+	return p.Score > q.Score
+}
+
+// ChainPotential finds the longest chain fired by dropping 1 to maxPuyos
+// puyos of one color into one column
+// maxPuyos is clamped to 1..MaxKeyPuyos. Only colors of the rules' palette
+// are tried, and only as many puyos as fit in the column. The field is not
+// modified.
+func ChainPotential(f *Field, rules Rules, maxPuyos int) Potential {
+	maxPuyos = min(max(maxPuyos, 1), MaxKeyPuyos)
+
+	var try func(x int, c Color, n int) (ChainSummary, bool)
+	if base, err := NewBitboard(f); err == nil {
+		base.Drop()
+		try = func(x int, c Color, n int) (ChainSummary, bool) {
+			h := base.ColumnHeight(x)
+			if h+n > base.Height {
+				return ChainSummary{}, false
+			}
+			b := base
+			for i := 0; i < n; i++ {
+				b.Set(x, b.Height-1-h-i, c)
+			}
+			return b.Resolve(rules), true
+		}
+	} else {
+		// Fields too large for a bitboard go through the chain simulator
+		s := NewChainSimulator(rules)
+		field := f.Clone()
+		field.settle()
+		try = func(x int, c Color, n int) (ChainSummary, bool) {
+			h := field.columnHeight(x)
+			if h+n > field.Height {
+				return ChainSummary{}, false
+			}
+			for i := 0; i < n; i++ {
+				field.Grid[field.Height-1-h-i][x] = c
+			}
+			sum := s.Resolve(field)
+			for i := 0; i < n; i++ {
+				field.Grid[field.Height-1-h-i][x] = Empty
+			}
+			return sum, true
+		}
+	}
+
+	var best Potential
+	for x := 0; x < f.Width; x++ {
+		for _, c := range rules.palette() {
+			for n := 1; n <= maxPuyos; n++ {
+				sum, ok := try(x, c, n)
+				if !ok {
+					break
+				}
+				p := Potential{Chain: sum.Chain, Score: sum.Score, Column: x, Color: c, KeyPuyos: n}
+				if p.Chain > 0 && p.better(best) {
+					best = p
+				}
+			}
+		}
+	}
+	return best
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"testing"
+)
+
+// almostTwoChain is twoChainField without the red that fires it
+const almostTwoChain = `
+.GGG..
+GRRR..
+`
+
+func TestChainPotential(t *testing.T) {
+	field, _, _ := ParseFieldNotation(almostTwoChain, FieldWidth, FieldHeight)
+	before := field.Notation()
+
+	p := ChainPotential(field, DefaultRules(), 1)
+
+	if field.Notation() != before {
+		t.Error("Expected the field not to change")
+	}
+	want := Potential{Chain: 2, Score: 400, Column: 4, Color: Red, KeyPuyos: 1}
+	if p != want {
+		t.Errorf("Expected %+v, got %+v", want, p)
+	}
+}
+
+func TestChainPotentialKeyPuyos(t *testing.T) {
+	field, _, _ := ParseFieldNotation("RR....", FieldWidth, FieldHeight)
+
+	if p := ChainPotential(field, DefaultRules(), 1); p.Chain != 0 {
+		t.Errorf("Expected one puyo not to fire anything, got %+v", p)
+	}
+
+	p := ChainPotential(field, DefaultRules(), 2)
+	if p.Chain != 1 || p.Color != Red || p.KeyPuyos != 2 {
+		t.Errorf("Expected two reds to fire a 1-chain, got %+v", p)
+	}
+	if p.Column != 0 {
+		t.Errorf("Expected the leftmost column on a tie, got %d", p.Column)
+	}
+
+	// Limits are clamped
+	if p := ChainPotential(field, DefaultRules(), 10); p.KeyPuyos > MaxKeyPuyos {
+		t.Errorf("Expected at most %d key puyos, got %d", MaxKeyPuyos, p.KeyPuyos)
+	}
+}
+
+func TestChainPotentialFullColumn(t *testing.T) {
+	// Only a red in the full column would connect the three reds
+	field, _, _ := ParseFieldNotation(`
+.R....
+.G....
+.B....
+.Y....
+.R....
+.G....
+.B....
+.Y....
+.R....
+.G....
+.B....
+RYR...
+`, FieldWidth, FieldHeight)
+
+	p := ChainPotential(field, DefaultRules(), 1)
+	if p.Chain != 0 {
+		t.Errorf("Expected nothing to fit in the full column, got %+v", p)
+	}
+}
+
+func TestChainPotentialLargeField(t *testing.T) {
+	// Fields too large for a bitboard give the same answer
+	rules := DefaultRules()
+	rules.Width = BitboardMaxWidth + 2
+	small, _, _ := ParseFieldNotation(almostTwoChain, FieldWidth, FieldHeight)
+	field := NewFieldSize(rules.Width, rules.Height)
+	for y := range small.Grid {
+		copy(field.Grid[y], small.Grid[y])
+	}
+
+	before := field.Notation()
+
+	want := Potential{Chain: 2, Score: 400, Column: 4, Color: Red, KeyPuyos: 1}
+	if p := ChainPotential(field, rules, 2); p != want {
+		t.Errorf("Expected %+v, got %+v", want, p)
+	}
+	if field.Notation() != before {
+		t.Error("Expected the field not to change")
+	}
+}
+
+func BenchmarkChainPotential(b *testing.B) {
+	fields := chainBenchFields()
+	rules := DefaultRules()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		ChainPotential(fields[i%len(fields)], rules, MaxKeyPuyos)
+	}
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"time"
+)
+
+// FrameDuration is the length of one frame at 60 frames per second
+const FrameDuration = time.Second / 60
+
+// Gravity20G is the gravity at which a pair falls to the bottom as soon as it spawns
+const Gravity20G = 0
+
+// LevelUpBy selects what the level is counted from
+type LevelUpBy string
+
+const (
+	LevelUpByLinks  LevelUpBy = "links"  // Chain links cleared
+	LevelUpByPieces LevelUpBy = "pieces" // Pairs placed
+	LevelUpByScore  LevelUpBy = "score"  // Points scored
+)
+
+// SpeedStep is the speed from a level upwards
+type SpeedStep struct {
+	Level     int `json:"level"`                // First level this step applies to
+	Gravity   int `json:"gravity"`              // Frames per row, or Gravity20G
+	LockDelay int `json:"lock_delay,omitempty"` // Frames on the ground before locking (0 keeps the current delay)
+}
+
+// Progression describes how the level and speed advance during a game
+type Progression struct {
+	Name      string      `json:"name"`
+	LevelUpBy LevelUpBy   `json:"level_up_by"`
+	Threshold int         `json:"threshold"` // Links, pieces or points per level
+	MaxLevel  int         `json:"max_level"` // 0 for no limit
+	Speeds    []SpeedStep `json:"speeds"`    // Sorted by level, starting at level 1
+}
+
+// progressions holds the built-in progression presets by name
+var progressions = map[string]*Progression{
+	// The original curve: a level every 10 chain links, with the drop
+	// interval 500ms / (1 + 0.1 x level), capped at 100ms from level 20
+	"standard": {
+		Name:      "standard",
+		LevelUpBy: LevelUpByLinks,
+		Threshold: 10,
+		Speeds: []SpeedStep{
+			{Level: 1, Gravity: 30}, {Level: 2, Gravity: 25}, {Level: 3, Gravity: 23},
+			{Level: 4, Gravity: 21}, {Level: 5, Gravity: 20}, {Level: 6, Gravity: 19},
+			{Level: 7, Gravity: 18}, {Level: 8, Gravity: 17}, {Level: 9, Gravity: 16},
+			{Level: 10, Gravity: 15}, {Level: 11, Gravity: 14}, {Level: 13, Gravity: 13},
+			{Level: 14, Gravity: 12}, {Level: 17, Gravity: 11}, {Level: 19, Gravity: 10},
+			{Level: 20, Gravity: 6},
+		},
+	},
+
+	// Classic Tsu endless: speed rises with the score, slowly at first
+	"tsu": {
+		Name:      "tsu",
+		LevelUpBy: LevelUpByScore,
+		Threshold: 2000,
+		Speeds: []SpeedStep{
+			{Level: 1, Gravity: 32},
+			{Level: 3, Gravity: 24},
+			{Level: 5, Gravity: 16},
+			{Level: 7, Gravity: 12},
+			{Level: 9, Gravity: 8},
+			{Level: 11, Gravity: 6},
+			{Level: 13, Gravity: 4},
+			{Level: 15, Gravity: 2},
+			{Level: 17, Gravity: 1},
+		},
+	},
+
+	// Marathon: a level every 25 pairs up to level 20, ending at 20G with
+	// a shorter lock delay
+	"marathon": {
+		Name:      "marathon",
+		LevelUpBy: LevelUpByPieces,
+		Threshold: 25,
+		MaxLevel:  20,
+		Speeds: []SpeedStep{
+			{Level: 1, Gravity: 40},
+			{Level: 3, Gravity: 30},
+			{Level: 5, Gravity: 20},
+			{Level: 7, Gravity: 15},
+			{Level: 9, Gravity: 10},
+			{Level: 11, Gravity: 6},
+			{Level: 13, Gravity: 4},
+			{Level: 15, Gravity: 2, LockDelay: 30},
+			{Level: 17, Gravity: 1, LockDelay: 24},
+			{Level: 20, Gravity: Gravity20G, LockDelay: 20},
+		},
+	},
+}
+
+// ProgressionNames lists the built-in progressions in menu order
+var ProgressionNames = []string{"standard", "tsu", "marathon"}
+
+// ProgressionByName returns a built-in progression, or standard if the name is unknown
+func ProgressionByName(name string) *Progression {
+	if p, ok := progressions[name]; ok {
+		return p
+	}
+	return progressions["standard"]
+}
+
+// LevelFor returns the level reached with the given progress, never below current
+func (p *Progression) LevelFor(current, progress int) int {
+	level := current
+	if p.Threshold > 0 {
+		if l := progress/p.Threshold + 1; l > level {
+			level = l
+		}
+	}
+	if p.MaxLevel > 0 && level > p.MaxLevel {
+		level = p.MaxLevel
+	}
+	return level
+}
+
+// SpeedFor returns the speed step for a level
+func (p *Progression) SpeedFor(level int) SpeedStep {
+	step := SpeedStep{Level: 1, Gravity: 30}
+	for _, s := range p.Speeds {
+		if s.Level > level {
+			break
+		}
+		step = s
+	}
+	return step
+}
+
+// progress returns how far the game has advanced in the unit the progression counts
+func (g *Game) progress() int {
+	switch g.progression().LevelUpBy {
+	case LevelUpByPieces:
+		return g.PiecesPlaced
+	case LevelUpByScore:
+		return g.Score
+	default:
+		return g.LinesCleared
+	}
+}
+
+// progression returns the game's progression, defaulting to standard
+func (g *Game) progression() *Progression {
+	if g.Progression == nil {
+		return ProgressionByName("standard")
+	}
+	return g.Progression
+}
+
+// updateLevel raises the level when the game has progressed far enough
+func (g *Game) updateLevel() {
+	newLevel := g.progression().LevelFor(g.Level, g.progress())
+	if newLevel > g.Level {
+		g.Level = newLevel
+		g.applySpeed()
+		g.emit(LevelUp{Level: g.Level})
+	}
+}
+
+// applySpeed sets gravity, drop interval and lock delay for the current level
+func (g *Game) applySpeed() {
+	step := g.progression().SpeedFor(g.Level)
+	g.Gravity = step.Gravity
+	g.DropSpeed = gravityInterval(step.Gravity)
+	if step.LockDelay > 0 {
+		g.MaxGroundFrames = step.LockDelay
+	}
+}
+
+// gravityInterval returns the time between drops for a gravity in frames per row
+func gravityInterval(gravity int) time.Duration {
+	if gravity <= Gravity20G {
+		return FrameDuration
+	}
+	return time.Duration(gravity) * FrameDuration
+}
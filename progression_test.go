@@ -0,0 +1,133 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestStandardProgressionMatchesOriginalCurve(t *testing.T) {
+	p := ProgressionByName("standard")
+
+	for level := 1; level <= 25; level++ {
+		// The original drop interval
+		expected := 100 * time.Millisecond
+		switch {
+		case level <= 1:
+			expected = 500 * time.Millisecond
+		case level < 20:
+			expected = time.Duration(float64(500*time.Millisecond) / (1.0 + float64(level)*0.1))
+		}
+
+		got := gravityInterval(p.SpeedFor(level).Gravity)
+		if diff := got - expected; diff < -FrameDuration || diff > FrameDuration {
+			t.Errorf("Level %d: drop interval %v, original %v", level, got, expected)
+		}
+	}
+
+	if p.LevelFor(1, 9) != 1 || p.LevelFor(1, 10) != 2 || p.LevelFor(1, 35) != 4 {
+		t.Error("Expected a level every 10 links")
+	}
+}
+
+func TestProgressionLevelUpBy(t *testing.T) {
+	tests := []struct {
+		name   string
+		setup  func(g *Game)
+		expect int
+	}{
+		{"standard", func(g *Game) { g.LinesCleared = 20 }, 3},
+		{"marathon", func(g *Game) { g.PiecesPlaced = 50 }, 3},
+		{"tsu", func(g *Game) { g.Score = 4000 }, 3},
+		{"marathon", func(g *Game) { g.PiecesPlaced = 10000 }, 20},
+	}
+
+	for _, tt := range tests {
+		game := NewGameWithSeed(4, 1)
+		game.Progression = ProgressionByName(tt.name)
+		tt.setup(game)
+		game.updateLevel()
+
+		if game.Level != tt.expect {
+			t.Errorf("%s: expected level %d, got %d", tt.name, tt.expect, game.Level)
+		}
+	}
+}
+
+func TestLevelNeverDecreases(t *testing.T) {
+	game := NewGameWithSeed(4, 1)
+	game.Level = 5
+	game.updateLevel()
+
+	if game.Level != 5 {
+		t.Errorf("Expected starting level to be kept, got %d", game.Level)
+	}
+}
+
+func TestLevelUpByPiecesOnLock(t *testing.T) {
+	game := NewGameWithSeed(4, 1)
+	game.Progression = ProgressionByName("marathon")
+	game.PiecesPlaced = 24
+
+	game.HardDrop()
+	game.LockPair()
+
+	if game.Level != 2 {
+		t.Errorf("Expected level 2 after the 25th pair, got %d", game.Level)
+	}
+}
+
+func TestProgressionLockDelay(t *testing.T) {
+	game := NewGameWithSeed(4, 1)
+	game.Progression = ProgressionByName("marathon")
+	game.MaxGroundFrames = 32
+
+	game.Level = 15
+	game.applySpeed()
+	if game.MaxGroundFrames != 30 {
+		t.Errorf("Expected lock delay 30 at level 15, got %d", game.MaxGroundFrames)
+	}
+
+	// Steps without a lock delay keep the current one
+	game.Level = 16
+	game.applySpeed()
+	if game.MaxGroundFrames != 30 {
+		t.Errorf("Expected lock delay 30 at level 16, got %d", game.MaxGroundFrames)
+	}
+}
+
+func TestDrop20G(t *testing.T) {
+	game := NewGameWithSeed(4, 1)
+	game.Progression = ProgressionByName("marathon")
+	game.Level = 20
+	game.applySpeed()
+
+	if game.Gravity != Gravity20G {
+		t.Fatalf("Expected 20G at marathon level 20, got %d", game.Gravity)
+	}
+
+	if !game.Drop() {
+		t.Fatal("Expected the pair to drop")
+	}
+	if !game.IsOnGround() {
+		t.Error("Expected the pair to fall to the bottom at 20G")
+	}
+	if game.Drop() {
+		t.Error("Expected no further drop once on the ground")
+	}
+}
+
+func TestApplySettingsProgression(t *testing.T) {
+	s := DefaultSettings()
+	s.Progression = "marathon"
+	s.StartLevel = 3
+
+	game := NewGame()
+	game.ApplySettings(s)
+
+	if game.Progression.Name != "marathon" {
+		t.Errorf("Expected marathon progression, got %q", game.Progression.Name)
+	}
+	if game.DropSpeed != 30*FrameDuration {
+		t.Errorf("Expected marathon level 3 speed, got %v", game.DropSpeed)
+	}
+}
@@ -0,0 +1,265 @@
+package main
+
+import (
+	"fmt"
+)
+
+// WallKick selects how a rotation blocked by a wall or puyo is resolved
+type WallKick string
+
+const (
+	WallKickNone     WallKick = "none"     // Blocked rotations fail
+	WallKickSides    WallKick = "sides"    // Shift one column left or right
+	WallKickStandard WallKick = "standard" // Shift sideways, then one row up (floor kick)
+)
+
+// Scoring selects how chain steps are scored
+type Scoring string
+
+const (
+	ScoringClassic Scoring = "classic" // 100 x 2^(n-1) x n for a whole chain of n steps
+	ScoringTsu     Scoring = "tsu"     // 10 x puyos x (chain power + color bonus + group bonus)
+)
+
+// Nuisance conversion and the all-clear bonus, as in Tsu
+const (
+	DefaultTargetPoint    = 70                                    // Chain points per nuisance puyo
+	DefaultMarginTime     = 96                                    // Seconds before the target point starts to drop
+	DefaultMarginInterval = 16                                    // Seconds between drops of the target point
+	AllClearNuisance      = 30                                    // Extra nuisance puyos sent with the next attack
+	DefaultAllClearBonus  = AllClearNuisance * DefaultTargetPoint // Solo points, worth 30 nuisance
+)
+
+// Palette size limits
+const (
+	MinColors = 3
+	MaxColors = 6
+)
+
+// Rules configures a game variant
+type Rules struct {
+	Width          int      `json:"width"`           // Field columns
+	Height         int      `json:"height"`          // Field rows
+	PopCount       int      `json:"pop_count"`       // Connected puyos needed to pop
+	Colors         int      `json:"colors"`          // Palette size, MinColors to MaxColors
+	SpawnColumn    int      `json:"spawn_column"`    // Column the main puyo spawns in
+	LockDelay      int      `json:"lock_delay"`      // Frames on the ground before locking
+	AllClearBonus  int      `json:"all_clear_bonus"` // Points for emptying the field in solo play
+	TargetPoint    int      `json:"target_point"`    // Chain points per nuisance puyo in versus
+	MarginTime     int      `json:"margin_time"`     // Seconds before the target point drops (0 = never)
+	MarginInterval int      `json:"margin_interval"` // Seconds between further drops
+	WallKick       WallKick `json:"wall_kick"`
+	Scoring        Scoring  `json:"scoring"`
+	DropSet        string   `json:"drop_set,omitempty"`    // Piece sequence, see DropSetNames ("" for pairs only)
+	Fever          bool     `json:"fever,omitempty"`       // Chains fill a fever gauge that deals preset chain patterns
+	Goal           Goal     `json:"goal,omitempty"`        // What ends a timed run ("" plays until topping out)
+	GoalTarget     int      `json:"goal_target,omitempty"` // Chain length or score the goal needs
+	TimeLimit      int      `json:"time_limit,omitempty"`  // Frames before the run ends (0 = no limit)
+	Mission        string   `json:"mission,omitempty"`     // Mission id for the mission goal
+}
+
+// DefaultRules returns the standard rules: a 6x12 field, pop 4, 4 colors
+func DefaultRules() Rules {
+	return Rules{
+		Width:          FieldWidth,
+		Height:         FieldHeight,
+		PopCount:       MinChain,
+		Colors:         4,
+		SpawnColumn:    FieldWidth / 2,
+		LockDelay:      32, // Puyo Puyo Tsu specification
+		AllClearBonus:  DefaultAllClearBonus,
+		TargetPoint:    DefaultTargetPoint,
+		MarginTime:     DefaultMarginTime,
+		MarginInterval: DefaultMarginInterval,
+		WallKick:       WallKickStandard,
+		Scoring:        ScoringClassic,
+	}
+}
+
+// rulesPresets holds the named rule variants
+var rulesPresets = map[string]func() Rules{
+	"standard": DefaultRules,
+	"wide": func() Rules {
+		r := DefaultRules()
+		r.Width = 8
+		r.SpawnColumn = 4
+		return r
+	},
+	"casual": func() Rules {
+		r := DefaultRules()
+		r.PopCount = 3
+		return r
+	},
+	"kids": func() Rules {
+		r := DefaultRules()
+		r.Colors = 3
+		return r
+	},
+	"tsu": func() Rules {
+		r := DefaultRules()
+		r.Scoring = ScoringTsu
+		return r
+	},
+	"mixed": func() Rules {
+		r := DefaultRules()
+		r.DropSet = "fever"
+		return r
+	},
+	"fever": func() Rules {
+		r := DefaultRules()
+		r.DropSet = "fever"
+		r.Scoring = ScoringTsu
+		r.Fever = true
+		return r
+	},
+	"sprint-chain": func() Rules {
+		r := DefaultRules()
+		r.Goal = GoalChain
+		r.GoalTarget = 10
+		return r
+	},
+	"sprint-score": func() Rules {
+		r := DefaultRules()
+		r.Goal = GoalScore
+		r.GoalTarget = 100000
+		return r
+	},
+	"ultra": func() Rules {
+		r := DefaultRules()
+		r.Goal = GoalUltra
+		r.TimeLimit = 2 * 60 * 60
+		return r
+	},
+}
+
+// RulesNames lists the rule presets in menu order
+var RulesNames = []string{"standard", "wide", "casual", "kids", "tsu", "mixed", "fever", "sprint-chain", "sprint-score", "ultra"}
+
+// RulesByName returns a rule preset, or the standard rules if the name is unknown
+func RulesByName(name string) Rules {
+	if preset, ok := rulesPresets[name]; ok {
+		return preset()
+	}
+	return DefaultRules()
+}
+
+// Validate checks that the rules describe a playable game
+func (r Rules) Validate() error {
+	if r.Width < 2 || r.Height < 3 {
+		return fmt.Errorf("field must be at least 2x3, got %dx%d", r.Width, r.Height)
+	}
+	if r.PopCount < 2 {
+		return fmt.Errorf("pop count must be at least 2, got %d", r.PopCount)
+	}
+	if r.Colors < MinColors || r.Colors > MaxColors {
+		return fmt.Errorf("colors must be %d to %d, got %d", MinColors, MaxColors, r.Colors)
+	}
+	if r.SpawnColumn < 0 || r.SpawnColumn >= r.Width {
+		return fmt.Errorf("spawn column %d is outside the field", r.SpawnColumn)
+	}
+	if r.LockDelay < 0 {
+		return fmt.Errorf("lock delay must not be negative, got %d", r.LockDelay)
+	}
+	if r.AllClearBonus < 0 {
+		return fmt.Errorf("all-clear bonus must not be negative, got %d", r.AllClearBonus)
+	}
+	if r.TargetPoint < 1 {
+		return fmt.Errorf("target point must be at least 1, got %d", r.TargetPoint)
+	}
+	if r.MarginTime < 0 || (r.MarginTime > 0 && r.MarginInterval < 1) {
+		return fmt.Errorf("margin time needs a start of 0 or more and an interval of at least 1 second, got %d and %d", r.MarginTime, r.MarginInterval)
+	}
+	switch r.WallKick {
+	case WallKickNone, WallKickSides, WallKickStandard:
+	default:
+		return fmt.Errorf("unknown wall kick policy %q", r.WallKick)
+	}
+	switch r.Scoring {
+	case ScoringClassic, ScoringTsu:
+	default:
+		return fmt.Errorf("unknown scoring %q", r.Scoring)
+	}
+	switch r.Goal {
+	case GoalNone:
+	case GoalChain, GoalScore:
+		if r.GoalTarget < 1 {
+			return fmt.Errorf("%s goal needs a target of at least 1, got %d", r.Goal, r.GoalTarget)
+		}
+	case GoalUltra:
+		if r.TimeLimit < 1 {
+			return fmt.Errorf("ultra needs a time limit, got %d frames", r.TimeLimit)
+		}
+	case GoalMission:
+		if MissionByID(r.Mission) == nil {
+			return fmt.Errorf("unknown mission %q", r.Mission)
+		}
+	default:
+		return fmt.Errorf("unknown goal %q", r.Goal)
+	}
+	if r.TimeLimit < 0 {
+		return fmt.Errorf("time limit must not be negative, got %d", r.TimeLimit)
+	}
+	if _, err := DropSetByName(r.DropSet); err != nil {
+		return err
+	}
+	if r.Fever && (r.Width != FieldWidth || r.Height != FieldHeight || r.Colors < 4 || r.PopCount != MinChain) {
+		return fmt.Errorf("fever patterns need a %dx%d field, pop %d and at least 4 colors", FieldWidth, FieldHeight, MinChain)
+	}
+	return nil
+}
+
+// palette returns the colors pairs are drawn from
+func (r Rules) palette() []Color {
+	colors := []Color{Red, Green, Blue, Yellow, Purple, Orange}
+	return colors[:r.Colors]
+}
+
+// kickOffsets returns the shifts tried, in order, when a rotation is blocked
+func (r Rules) kickOffsets() []Position {
+	switch r.WallKick {
+	case WallKickNone:
+		return nil
+	case WallKickSides:
+		return []Position{{-1, 0}, {1, 0}}
+	default:
+		return []Position{{-1, 0}, {1, 0}, {0, -1}}
+	}
+}
+
+// Tsu scoring tables
+var (
+	tsuChainPower = []int{0, 8, 16, 32, 64, 96, 128, 160, 192, 224, 256, 288, 320, 352, 384, 416, 448, 480, 512}
+	tsuColorBonus = []int{0, 3, 6, 12, 24, 48}
+	tsuGroupBonus = []int{0, 2, 3, 4, 5, 6, 7, 10} // By puyos beyond the pop count
+)
+
+// stepScore returns the points for one chain step
+// groups holds the size of each group popped in the step.
+func (r Rules) stepScore(chain int, groups []int, colors int) int {
+	if r.Scoring != ScoringTsu {
+		return chainScore(chain) - chainScore(chain-1)
+	}
+
+	puyos, bonus := 0, 0
+	for _, size := range groups {
+		puyos += size
+		bonus += tableValue(tsuGroupBonus, size-r.PopCount)
+	}
+	bonus += tableValue(tsuChainPower, chain-1)
+	bonus += tableValue(tsuColorBonus, colors-1)
+	if bonus < 1 {
+		bonus = 1
+	}
+	return 10 * puyos * bonus
+}
+
+// tableValue returns table[i], clamped to the ends of the table
+func tableValue(table []int, i int) int {
+	if i < 0 {
+		i = 0
+	}
+	if i >= len(table) {
+		i = len(table) - 1
+	}
+	return table[i]
+}
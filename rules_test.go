@@ -0,0 +1,317 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+// runChain resolves the field as if a pair had just locked
+func runChain(g *Game) {
+	g.Current = nil
+	g.State = StateDropping
+	g.ChainCount = 0
+	for g.ProcessChainStep() {
+	}
+}
+
+func TestRulesPresetsValid(t *testing.T) {
+	for _, name := range RulesNames {
+		if err := RulesByName(name).Validate(); err != nil {
+			t.Errorf("%s: %v", name, err)
+		}
+	}
+
+	if RulesByName("unknown") != DefaultRules() {
+		t.Error("Expected unknown names to fall back to the standard rules")
+	}
+}
+
+func TestRulesValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(r *Rules)
+	}{
+		{"tiny field", func(r *Rules) { r.Width, r.Height = 1, 12 }},
+		{"pop count", func(r *Rules) { r.PopCount = 1 }},
+		{"too few colors", func(r *Rules) { r.Colors = 2 }},
+		{"too many colors", func(r *Rules) { r.Colors = 7 }},
+		{"spawn column", func(r *Rules) { r.SpawnColumn = r.Width }},
+		{"lock delay", func(r *Rules) { r.LockDelay = -1 }},
+		{"wall kick", func(r *Rules) { r.WallKick = "diagonal" }},
+		{"scoring", func(r *Rules) { r.Scoring = "fever" }},
+	}
+
+	for _, tt := range tests {
+		r := DefaultRules()
+		tt.modify(&r)
+		if r.Validate() == nil {
+			t.Errorf("%s: expected an error", tt.name)
+		}
+	}
+}
+
+func TestRulesFieldSize(t *testing.T) {
+	rules := DefaultRules()
+	rules.Width, rules.Height = 3, 4
+	rules.SpawnColumn = 1
+	game := NewGameWithRules(rules, 1)
+
+	if game.Field.Width != 3 || game.Field.Height != 4 || len(game.Field.Grid) != 4 || len(game.Field.Grid[0]) != 3 {
+		t.Fatalf("Expected a 3x4 field, got %dx%d", game.Field.Width, game.Field.Height)
+	}
+	if game.Current.Pos.X != 1 {
+		t.Errorf("Expected the pair to spawn in column 1, got %d", game.Current.Pos.X)
+	}
+	if game.CanMove(2, 0, 0) {
+		t.Error("Expected column 3 to be outside a 3-wide field")
+	}
+
+	// Fill the bottom row and the spawn column: the next pair cannot spawn
+	for y := 0; y < 4; y++ {
+		game.Field.Grid[y][1] = Red + Color(y%2)
+	}
+	game.SpawnNewPair()
+	if !game.GameOver {
+		t.Error("Expected game over when the spawn column is full")
+	}
+}
+
+func TestRulesPopCount(t *testing.T) {
+	game := NewGameWithRules(RulesByName("casual"), 1)
+	for x := 0; x < 3; x++ {
+		game.Field.Grid[FieldHeight-1][x] = Red
+	}
+
+	runChain(game)
+
+	if game.Field.Grid[FieldHeight-1][0] != Empty {
+		t.Error("Expected 3 connected puyos to pop with pop count 3")
+	}
+	if game.MaxChain != 1 {
+		t.Errorf("Expected a 1 chain, got %d", game.MaxChain)
+	}
+
+	standard := NewGameWithRules(DefaultRules(), 1)
+	for x := 0; x < 3; x++ {
+		standard.Field.Grid[FieldHeight-1][x] = Red
+	}
+	runChain(standard)
+	if standard.Field.Grid[FieldHeight-1][0] != Red {
+		t.Error("Expected 3 puyos to stay with the standard rules")
+	}
+}
+
+func TestRulesColors(t *testing.T) {
+	for _, count := range []int{3, 6} {
+		rules := DefaultRules()
+		rules.Colors = count
+		game := NewGameWithRules(rules, 42)
+
+		seen := make(map[Color]bool)
+		for i := 0; i < 500; i++ {
+			pair := game.generatePuyoPair()
+			seen[pair.Main.Color] = true
+			seen[pair.Sub.Color] = true
+		}
+
+		if len(seen) != count {
+			t.Errorf("%d colors: saw %d colors: %v", count, len(seen), seen)
+		}
+		if count == 3 && (seen[Yellow] || seen[Purple] || seen[Orange]) {
+			t.Errorf("3 colors: expected only red, green and blue, got %v", seen)
+		}
+		if count == 6 && !seen[Orange] {
+			t.Error("6 colors: expected orange")
+		}
+	}
+}
+
+func TestRulesWallKickNone(t *testing.T) {
+	for _, kick := range []WallKick{WallKickNone, WallKickSides, WallKickStandard} {
+		rules := DefaultRules()
+		rules.WallKick = kick
+		game := NewGameWithRules(rules, 1)
+
+		// Against the left wall, one of the two rotations needs a kick
+		rotated := 0
+		for _, dir := range []int{1, -1} {
+			game.Current.Pos = Position{0, 5}
+			game.Current.Rotate = 0
+			if game.Move(0, 0, dir) {
+				rotated++
+			}
+		}
+
+		if kick == WallKickNone && rotated != 1 {
+			t.Errorf("%s: expected the blocked rotation to fail, %d of 2 rotated", kick, rotated)
+		}
+		if kick != WallKickNone && rotated != 2 {
+			t.Errorf("%s: expected both rotations to succeed, %d of 2 rotated", kick, rotated)
+		}
+	}
+}
+
+func TestTsuStepScore(t *testing.T) {
+	r := RulesByName("tsu")
+
+	tests := []struct {
+		chain  int
+		groups []int
+		colors int
+		expect int
+	}{
+		{1, []int{4}, 1, 40},            // Minimum bonus of 1
+		{2, []int{4}, 1, 320},           // Chain power 8
+		{1, []int{5}, 1, 100},           // Group bonus 2
+		{1, []int{4, 4}, 2, 240},        // Color bonus 3
+		{3, []int{11}, 1, 110 * 26},     // Chain power 16 + group bonus 10
+		{30, []int{4}, 1, 40 * 512},     // Chain power capped at the end of the table
+		{1, []int{4, 4, 4}, 3, 12 * 60}, // Color bonus 6
+	}
+
+	for _, tt := range tests {
+		if got := r.stepScore(tt.chain, tt.groups, tt.colors); got != tt.expect {
+			t.Errorf("chain %d, groups %v, %d colors: expected %d, got %d", tt.chain, tt.groups, tt.colors, tt.expect, got)
+		}
+	}
+}
+
+func TestClassicStepScoresSumToChainScore(t *testing.T) {
+	r := DefaultRules()
+	for n := 1; n <= 10; n++ {
+		total := 0
+		for step := 1; step <= n; step++ {
+			total += r.stepScore(step, []int{4}, 1)
+		}
+		if total != chainScore(n) {
+			t.Errorf("%d chain: steps sum to %d, expected %d", n, total, chainScore(n))
+		}
+	}
+}
+
+func TestTsuScoringInGame(t *testing.T) {
+	game := NewGameWithRules(RulesByName("tsu"), 1)
+	for x := 0; x < 4; x++ {
+		game.Field.Grid[FieldHeight-1][x] = Red
+	}
+	game.Field.Grid[FieldHeight-1][5] = Blue // Not an all clear
+
+	runChain(game)
+
+	if game.Score != 40 {
+		t.Errorf("Expected 40 points for a single group of 4, got %d", game.Score)
+	}
+}
+
+func TestSuspendKeepsRules(t *testing.T) {
+	rules := RulesByName("wide")
+	rules.Colors = 5
+	rules.Scoring = ScoringTsu
+	game := NewGameWithRules(rules, 7)
+	game.Field.Grid[rules.Height-1][7] = Orange
+
+	data, err := json.Marshal(game.Suspend())
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	var state SuspendState
+	if err := json.Unmarshal(data, &state); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	restored := mustRestore(t, &state)
+
+	if restored.Rules != rules {
+		t.Errorf("Expected rules %+v, got %+v", rules, restored.Rules)
+	}
+	if restored.Field.Width != 8 || restored.Field.Grid[rules.Height-1][7] != Orange {
+		t.Error("Expected the wide field to be restored")
+	}
+}
+
+func TestAllClearAwardsBonus(t *testing.T) {
+	game := NewGameWithSeed(4, 1)
+	events := recordEvents(game)
+	for x := 0; x < 4; x++ {
+		game.Field.Grid[FieldHeight-1][x] = Red
+	}
+
+	runChain(game)
+
+	if game.AllClears != 1 || !game.AllClearPending {
+		t.Fatalf("Expected one pending all clear, got %d pending=%v", game.AllClears, game.AllClearPending)
+	}
+	if want := 100 + DefaultAllClearBonus; game.Score != want {
+		t.Errorf("Expected score %d with the bonus, got %d", want, game.Score)
+	}
+
+	found := false
+	for _, ev := range *events {
+		if ev, ok := ev.(AllClear); ok && ev.Bonus == DefaultAllClearBonus {
+			found = true
+		}
+	}
+	if !found {
+		t.Error("Expected an AllClear event with the bonus")
+	}
+}
+
+func TestNoAllClearWithPuyosLeft(t *testing.T) {
+	game := NewGameWithSeed(4, 1)
+	for x := 0; x < 4; x++ {
+		game.Field.Grid[FieldHeight-1][x] = Red
+	}
+	game.Field.Grid[FieldHeight-1][5] = Blue
+
+	runChain(game)
+
+	if game.AllClears != 0 || game.AllClearPending || game.Score != 100 {
+		t.Errorf("Expected no all clear, got %d with score %d", game.AllClears, game.Score)
+	}
+}
+
+func TestAllClearInVersusHasNoScoreBonus(t *testing.T) {
+	game := NewGameWithSeed(4, 1)
+	game.Versus = true
+	popFour(game)
+
+	runChain(game)
+
+	if game.Score != 100 || !game.AllClearPending {
+		t.Errorf("Expected only the chain score and a pending all clear, got %d pending=%v", game.Score, game.AllClearPending)
+	}
+}
+
+func TestAllClearBonusReachesScoreGoal(t *testing.T) {
+	rules := DefaultRules()
+	rules.Goal, rules.GoalTarget = GoalScore, 1000
+	game := NewGameWithRules(rules, 1)
+	popFour(game)
+
+	runChain(game)
+
+	if !game.Cleared || game.MaxChain != 1 {
+		t.Errorf("Expected the bonus to finish the run after one chain, got cleared=%v chain=%d", game.Cleared, game.MaxChain)
+	}
+}
+
+func TestAllClearBonusFromSettings(t *testing.T) {
+	s := DefaultSettings()
+	s.AllClear = 600
+	game := NewGameWithSeed(4, 1)
+	game.ApplySettings(s)
+	for x := 0; x < 4; x++ {
+		game.Field.Grid[FieldHeight-1][x] = Red
+	}
+
+	runChain(game)
+
+	if game.Score != 700 {
+		t.Errorf("Expected the configured bonus, got score %d", game.Score)
+	}
+
+	r := DefaultRules()
+	r.AllClearBonus = -1
+	if r.Validate() == nil {
+		t.Error("Expected a negative all clear bonus to be rejected")
+	}
+}
@@ -0,0 +1,285 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/gdamore/tcell/v2"
+	"os"
+	"strings"
+	"unicode"
+)
+
+// Action represents a game input that can be bound to a key
+type Action string
+
+const (
+	ActionLeft      Action = "left"
+	ActionRight     Action = "right"
+	ActionSoftDrop  Action = "soft_drop"
+	ActionHardDrop  Action = "hard_drop"
+	ActionRotateCCW Action = "rotate_ccw"
+	ActionRotateCW  Action = "rotate_cw"
+	ActionPause     Action = "pause"
+	ActionQuit      Action = "quit"
+)
+
+// Actions lists the bindable actions in display order
+var Actions = []Action{
+	ActionLeft, ActionRight, ActionSoftDrop, ActionHardDrop,
+	ActionRotateCCW, ActionRotateCW, ActionPause, ActionQuit,
+}
+
+// KeyBindings maps each action to a key name (see keyName)
+type KeyBindings map[Action]string
+
+// DefaultKeyBindings returns the standard key bindings
+func DefaultKeyBindings() KeyBindings {
+	return KeyBindings{
+		ActionLeft:      "Left",
+		ActionRight:     "Right",
+		ActionSoftDrop:  "Down",
+		ActionHardDrop:  "Up",
+		ActionRotateCCW: "z",
+		ActionRotateCW:  "x",
+		ActionPause:     "p",
+		ActionQuit:      "q",
+	}
+}
+
+// ActionFor returns the action bound to the key of an event
+func (kb KeyBindings) ActionFor(ev *tcell.EventKey) (Action, bool) {
+	name := keyName(ev)
+	for _, action := range Actions {
+		if kb[action] == name {
+			return action, true
+		}
+	}
+	return "", false
+}
+
+// reservedKeys are handled by the game before the bindings:
+// Esc quits and R restarts after game over
+var reservedKeys = []string{"Esc", "r"}
+
+// Bind binds a key to an action
+// An action that already has the key gets the action's old key instead, so
+// no two actions share a key. Returns false for a reserved key.
+func (kb KeyBindings) Bind(action Action, name string) bool {
+	if containsString(reservedKeys, name) {
+		return false
+	}
+	for _, other := range Actions {
+		if other != action && kb[other] == name {
+			kb[other] = kb[action]
+		}
+	}
+	kb[action] = name
+	return true
+}
+
+// keyName returns a stable name for the key of an event
+// Letters are case-insensitive, so runes are lowercased
+func keyName(ev *tcell.EventKey) string {
+	if ev.Key() == tcell.KeyRune {
+		if ev.Rune() == ' ' {
+			return "Space"
+		}
+		return string(unicode.ToLower(ev.Rune()))
+	}
+	if name, ok := tcell.KeyNames[ev.Key()]; ok {
+		return name
+	}
+	return ""
+}
+
+// keyLabel returns a short label for a key name for on-screen help
+func keyLabel(name string) string {
+	switch name {
+	case "Left":
+		return "←"
+	case "Right":
+		return "→"
+	case "Up":
+		return "↑"
+	case "Down":
+		return "↓"
+	}
+	if len([]rune(name)) == 1 {
+		return strings.ToUpper(name)
+	}
+	return name
+}
+
+// Settings represents the user's persistent preferences
+type Settings struct {
+	LockDelay   int         `json:"lock_delay"`      // Frames allowed on the ground (MaxGroundFrames)
+	StartLevel  int         `json:"start_level"`     // Level new games start at
+	Ghost       bool        `json:"ghost"`           // Show where the pair will land
+	Connected   bool        `json:"connected"`       // Draw same-colored neighbors joined
+	FallSpeed   int         `json:"fall_speed"`      // Frames per row for puyos falling in a chain
+	Progression string      `json:"progression"`     // Level and speed curve: "standard", "tsu" or "marathon"
+	AllClear    int         `json:"all_clear_bonus"` // Points for an all clear in solo play
+	DAS         int         `json:"das"`             // Frames a move key must be held before it repeats
+	ARR         int         `json:"arr"`             // Frames between repeated moves (0 = move to the wall)
+	Emoji       bool        `json:"emoji"`           // Draw puyos as full-width emoji
+	Report      bool        `json:"report"`          // Print and save an analysis report after each game
+	Sound       string      `json:"sound"`           // "bell", "pcm" or "off"
+	SoundOut    string      `json:"sound_output"`    // PCM output: "-" for stdout, or a .wav/raw file
+	Theme       string      `json:"theme"`
+	Language    string      `json:"language"` // "auto", "ja" or "en"
+	Keys        KeyBindings `json:"keys"`
+	ColorCount  int         `json:"color_count"` // Last selected number of colors
+	Rules       string      `json:"rules"`       // Last selected rules preset
+}
+
+// Setting limits
+const (
+	MinLockDelay  = 0
+	MaxLockDelay  = 120
+	MaxStartLevel = 20
+	MaxFallSpeed  = 10
+	MaxAllClear   = 10000
+	MaxDAS        = 30
+	MaxARR        = 10
+)
+
+// Languages lists the selectable language settings
+var Languages = []string{"auto", "ja", "en"}
+
+// DefaultSettings returns the default settings, matching the original game
+func DefaultSettings() *Settings {
+	return &Settings{
+		LockDelay:   32, // Puyo Puyo Tsu specification
+		StartLevel:  1,
+		Ghost:       false,
+		Connected:   true,
+		FallSpeed:   DefaultFallFrames,
+		Progression: "standard",
+		AllClear:    DefaultAllClearBonus,
+		DAS:         0,
+		ARR:         1,
+		Sound:       SoundBell,
+		SoundOut:    "-",
+		Theme:       "classic",
+		Language:    "auto",
+		Keys:        DefaultKeyBindings(),
+		ColorCount:  4,
+		Rules:       "standard",
+	}
+}
+
+// normalize clamps out-of-range values and fills in missing key bindings
+func (s *Settings) normalize() {
+	def := DefaultSettings()
+
+	s.LockDelay = clamp(s.LockDelay, MinLockDelay, MaxLockDelay)
+	s.StartLevel = clamp(s.StartLevel, 1, MaxStartLevel)
+	s.FallSpeed = clamp(s.FallSpeed, 1, MaxFallSpeed)
+	s.AllClear = clamp(s.AllClear, 0, MaxAllClear)
+	s.DAS = clamp(s.DAS, 0, MaxDAS)
+	s.ARR = clamp(s.ARR, 0, MaxARR)
+	if s.ColorCount < MinColors || s.ColorCount > MaxColors {
+		s.ColorCount = def.ColorCount
+	}
+	if !containsString(RulesNames, s.Rules) {
+		s.Rules = def.Rules
+	}
+	if !containsString(ProgressionNames, s.Progression) {
+		s.Progression = def.Progression
+	}
+	if !containsString(SoundModes, s.Sound) {
+		s.Sound = def.Sound
+	}
+	if s.Theme == "" {
+		s.Theme = def.Theme
+	}
+	if !containsString(Languages, s.Language) {
+		s.Language = def.Language
+	}
+	if s.Keys == nil {
+		s.Keys = KeyBindings{}
+	}
+	for _, action := range Actions {
+		if s.Keys[action] == "" {
+			s.Keys[action] = def.Keys[action]
+		}
+	}
+}
+
+// clamp limits v to the range [lo, hi]
+func clamp(v, lo, hi int) int {
+	if v < lo {
+		return lo
+	}
+	if v > hi {
+		return hi
+	}
+	return v
+}
+
+// containsString reports whether list contains s
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// getSettingsPath returns the path to the config file
+func getSettingsPath() (string, error) {
+	return getConfigPath("config.json")
+}
+
+// LoadSettings loads the settings from disk, falling back to defaults
+// when there is no config file. A corrupt file is an error.
+func LoadSettings() (*Settings, error) {
+	s := DefaultSettings()
+
+	path, err := getSettingsPath()
+	if err != nil {
+		return s, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return s, nil
+		}
+		return nil, err
+	}
+
+	if err := json.Unmarshal(data, s); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	s.normalize()
+
+	return s, nil
+}
+
+// SaveSettings saves the settings to disk
+func SaveSettings(s *Settings) error {
+	path, err := getSettingsPath()
+	if err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, data, 0644)
+}
+
+// ApplySettings configures a new game from the settings
+func (g *Game) ApplySettings(s *Settings) {
+	g.Rules.LockDelay = s.LockDelay
+	g.Rules.AllClearBonus = s.AllClear
+	g.MaxGroundFrames = s.LockDelay
+	g.FallFrames = s.FallSpeed
+	g.Progression = ProgressionByName(s.Progression)
+	g.Level = s.StartLevel
+	g.applySpeed()
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"github.com/gdamore/tcell/v2"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestSaveAndLoadSettings(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	// Missing file should give defaults
+	s, err := LoadSettings()
+	if err != nil {
+		t.Fatalf("LoadSettings failed: %v", err)
+	}
+	if s.LockDelay != 32 || s.ColorCount != 4 || s.Keys[ActionLeft] != "Left" {
+		t.Errorf("Expected default settings, got %+v", s)
+	}
+
+	s.LockDelay = 16
+	s.Ghost = true
+	s.ColorCount = 5
+	s.Keys[ActionRotateCW] = "c"
+	if err := SaveSettings(s); err != nil {
+		t.Fatalf("SaveSettings failed: %v", err)
+	}
+
+	loaded, err := LoadSettings()
+	if err != nil {
+		t.Fatalf("LoadSettings failed: %v", err)
+	}
+	if loaded.LockDelay != 16 || !loaded.Ghost || loaded.ColorCount != 5 {
+		t.Errorf("Settings not restored: %+v", loaded)
+	}
+	if loaded.Keys[ActionRotateCW] != "c" || loaded.Keys[ActionRotateCCW] != "z" {
+		t.Errorf("Key bindings not restored: %v", loaded.Keys)
+	}
+}
+
+func TestLoadSettingsRejectsCorruptFile(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	path, _ := getSettingsPath()
+	if err := os.WriteFile(path, []byte(`{"lock_delay": `), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if s, err := LoadSettings(); err == nil || s != nil {
+		t.Errorf("Expected an error for a corrupt config file, got %v", err)
+	}
+}
+
+func TestSettingsNormalize(t *testing.T) {
+	s := &Settings{
+		LockDelay:  1000,
+		StartLevel: 0,
+		DAS:        -1,
+		ARR:        99,
+		Language:   "fr",
+		ColorCount: 7,
+		Keys:       KeyBindings{ActionLeft: "a"},
+	}
+	s.normalize()
+
+	if s.LockDelay != MaxLockDelay || s.StartLevel != 1 || s.DAS != 0 || s.ARR != MaxARR {
+		t.Errorf("Values not clamped: %+v", s)
+	}
+	if s.Language != "auto" || s.ColorCount != 4 {
+		t.Errorf("Invalid language/color count not reset: %+v", s)
+	}
+	if s.Keys[ActionLeft] != "a" || s.Keys[ActionRight] != "Right" {
+		t.Errorf("Key bindings not merged with defaults: %v", s.Keys)
+	}
+}
+
+func TestApplySettings(t *testing.T) {
+	s := DefaultSettings()
+	s.LockDelay = 20
+	s.StartLevel = 5
+
+	game := NewGame()
+	game.ApplySettings(s)
+
+	if game.MaxGroundFrames != 20 {
+		t.Errorf("Expected MaxGroundFrames 20, got %d", game.MaxGroundFrames)
+	}
+	if game.Level != 5 {
+		t.Errorf("Expected level 5, got %d", game.Level)
+	}
+	if game.DropSpeed >= 500*time.Millisecond {
+		t.Errorf("Expected faster drop speed at level 5, got %v", game.DropSpeed)
+	}
+}
+
+func TestKeyBindingsActionFor(t *testing.T) {
+	kb := DefaultKeyBindings()
+
+	tests := []struct {
+		ev     *tcell.EventKey
+		action Action
+		bound  bool
+	}{
+		{tcell.NewEventKey(tcell.KeyLeft, 0, tcell.ModNone), ActionLeft, true},
+		{tcell.NewEventKey(tcell.KeyUp, 0, tcell.ModNone), ActionHardDrop, true},
+		{tcell.NewEventKey(tcell.KeyRune, 'X', tcell.ModNone), ActionRotateCW, true},
+		{tcell.NewEventKey(tcell.KeyRune, 'z', tcell.ModNone), ActionRotateCCW, true},
+		{tcell.NewEventKey(tcell.KeyRune, 'k', tcell.ModNone), "", false},
+	}
+
+	for _, tt := range tests {
+		action, bound := kb.ActionFor(tt.ev)
+		if action != tt.action || bound != tt.bound {
+			t.Errorf("ActionFor(%s) = %q, %v; want %q, %v", tt.ev.Name(), action, bound, tt.action, tt.bound)
+		}
+	}
+}
+
+func TestKeyBindingsBind(t *testing.T) {
+	kb := DefaultKeyBindings()
+
+	// Taking another action's key swaps the two
+	if !kb.Bind(ActionRotateCW, "z") {
+		t.Fatal("Expected z to be bound")
+	}
+	if kb[ActionRotateCW] != "z" || kb[ActionRotateCCW] != "x" {
+		t.Errorf("Expected the rotations to swap keys, got %v", kb)
+	}
+
+	for _, name := range []string{"r", "Esc"} {
+		if kb.Bind(ActionPause, name) || kb[ActionPause] != "p" {
+			t.Errorf("Expected %s to be reserved, got %v", name, kb)
+		}
+	}
+}
+
+func TestGhostPositions(t *testing.T) {
+	game := NewGame()
+	game.Field.Grid[FieldHeight-1][1] = Red
+
+	// Horizontal pair: each puyo falls to its own column
+	game.Current.Pos = Position{X: 1, Y: 2}
+	game.Current.Rotate = 1
+	mainPos, subPos, ok := game.GhostPositions()
+	if !ok {
+		t.Fatal("Expected ghost positions")
+	}
+	if mainPos != (Position{1, FieldHeight - 2}) || subPos != (Position{2, FieldHeight - 1}) {
+		t.Errorf("Unexpected ghost positions %v %v", mainPos, subPos)
+	}
+
+	// Vertical pair with sub on top
+	game.Current.Rotate = 0
+	mainPos, subPos, _ = game.GhostPositions()
+	if mainPos != (Position{1, FieldHeight - 2}) || subPos != (Position{1, FieldHeight - 3}) {
+		t.Errorf("Unexpected ghost positions %v %v", mainPos, subPos)
+	}
+
+	// Vertical pair with sub on the bottom
+	game.Current.Rotate = 2
+	mainPos, subPos, _ = game.GhostPositions()
+	if mainPos != (Position{1, FieldHeight - 3}) || subPos != (Position{1, FieldHeight - 2}) {
+		t.Errorf("Unexpected ghost positions %v %v", mainPos, subPos)
+	}
+}
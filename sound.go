@@ -0,0 +1,333 @@
+package main
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strings"
+	"sync"
+)
+
+// Sound identifies a sound effect
+type Sound int
+
+const (
+	SoundRotate         Sound = iota // The pair was rotated
+	SoundLock                        // The pair was locked into the field
+	SoundChain                       // A chain step popped
+	SoundAllClear                    // The field was cleared completely
+	SoundGarbageWarning              // Garbage is about to fall
+	SoundGameOver                    // The game ended
+)
+
+// SoundEvent is a sound effect to play
+type SoundEvent struct {
+	Sound Sound
+	Chain int // Chain number for SoundChain, starting at 1
+}
+
+// SoundPlayer plays sound effects
+// Play is called from the game loop, so it must return quickly.
+type SoundPlayer interface {
+	Play(ev SoundEvent)
+}
+
+// SoundHandler returns an event handler that plays the sound for each game event
+func SoundHandler(player SoundPlayer) func(Event) {
+	return func(ev Event) {
+		switch ev := ev.(type) {
+		case PairRotated:
+			player.Play(SoundEvent{Sound: SoundRotate})
+		case PairLocked:
+			player.Play(SoundEvent{Sound: SoundLock})
+		case ChainStep:
+			player.Play(SoundEvent{Sound: SoundChain, Chain: ev.Chain})
+		case AllClear:
+			player.Play(SoundEvent{Sound: SoundAllClear})
+		case GarbageQueued:
+			player.Play(SoundEvent{Sound: SoundGarbageWarning})
+		case GameOver:
+			player.Play(SoundEvent{Sound: SoundGameOver})
+		}
+	}
+}
+
+// Sound settings
+const (
+	SoundBell = "bell" // Terminal bell (default)
+	SoundPCM  = "pcm"  // Synthesized PCM written to Settings.SoundOut
+	SoundOff  = "off"
+)
+
+// SoundModes lists the selectable sound settings
+var SoundModes = []string{SoundBell, SoundPCM, SoundOff}
+
+// BellPlayer rings the terminal bell
+// The bell has a single pitch, so it only rings for events worth
+// interrupting for; rotations and locks would ring on every piece.
+type BellPlayer struct {
+	Beep func() error
+}
+
+// Play rings the bell for chains, all clears, garbage warnings and game over
+func (b *BellPlayer) Play(ev SoundEvent) {
+	switch ev.Sound {
+	case SoundRotate, SoundLock:
+		return
+	}
+	b.Beep()
+}
+
+// PCM output format: 16-bit signed little-endian mono
+// To listen through a pipe: puyo | aplay -f S16_LE -r 22050 -c 1
+const (
+	PCMSampleRate = 22050
+	pcmAmplitude  = 0.3 * math.MaxInt16
+	pcmFadeMillis = 5 // Fade in and out to avoid clicks
+)
+
+// tone is a sine tone in a sound effect
+type tone struct {
+	freq   float64 // Hz, 0 for silence
+	millis int
+}
+
+// soundTones returns the tones that make up a sound effect
+func soundTones(ev SoundEvent) []tone {
+	switch ev.Sound {
+	case SoundRotate:
+		return []tone{{880, 30}}
+	case SoundLock:
+		return []tone{{220, 40}}
+	case SoundChain:
+		// Each chain step is two semitones higher than the last
+		chain := ev.Chain
+		if chain < 1 {
+			chain = 1
+		}
+		freq := 440 * math.Pow(2, float64(2*(chain-1))/12)
+		return []tone{{freq, 120}}
+	case SoundAllClear:
+		return []tone{{523.25, 80}, {659.25, 80}, {783.99, 80}, {1046.5, 160}}
+	case SoundGarbageWarning:
+		return []tone{{110, 120}, {0, 60}, {110, 120}}
+	case SoundGameOver:
+		return []tone{{392, 200}, {329.63, 200}, {261.63, 400}}
+	default:
+		return nil
+	}
+}
+
+// pcmQueueSize is the number of sounds waiting to be written before more are dropped
+const pcmQueueSize = 16
+
+// PCMPlayer synthesizes sound effects and writes them as PCM samples to a sink
+// The samples are written by a goroutine, so a slow sink such as a full pipe
+// never blocks the game loop; sounds played while the queue is full are
+// dropped.
+type PCMPlayer struct {
+	w     io.Writer
+	queue chan SoundEvent
+	done  chan struct{} // Closed when the writer goroutine has finished
+
+	mu  sync.Mutex
+	err error // First write error; later sounds are dropped
+}
+
+// NewPCMPlayer creates a player writing raw PCM to w
+// Close stops the player once the queued sounds are written.
+func NewPCMPlayer(w io.Writer) *PCMPlayer {
+	p := &PCMPlayer{
+		w:     w,
+		queue: make(chan SoundEvent, pcmQueueSize),
+		done:  make(chan struct{}),
+	}
+	go p.run()
+	return p
+}
+
+// Play queues a sound effect, dropping it if the queue is full
+func (p *PCMPlayer) Play(ev SoundEvent) {
+	if p.Err() != nil {
+		return
+	}
+	select {
+	case p.queue <- ev:
+	default:
+	}
+}
+
+// run writes the queued sounds until the queue is closed
+func (p *PCMPlayer) run() {
+	defer close(p.done)
+	for ev := range p.queue {
+		if p.Err() != nil {
+			continue
+		}
+		if _, err := p.w.Write(synthesize(soundTones(ev))); err != nil {
+			p.mu.Lock()
+			p.err = err
+			p.mu.Unlock()
+		}
+	}
+}
+
+// Close writes the queued sounds and stops the player
+// It returns the first write error. Play must not be called after Close.
+func (p *PCMPlayer) Close() error {
+	close(p.queue)
+	<-p.done
+	return p.Err()
+}
+
+// Err returns the first error writing to the sink
+func (p *PCMPlayer) Err() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.err
+}
+
+// synthesize renders tones as PCM samples
+func synthesize(tones []tone) []byte {
+	var buf []byte
+	fade := PCMSampleRate * pcmFadeMillis / 1000
+
+	for _, t := range tones {
+		n := PCMSampleRate * t.millis / 1000
+		for i := 0; i < n; i++ {
+			var v float64
+			if t.freq > 0 {
+				v = math.Sin(2 * math.Pi * t.freq * float64(i) / PCMSampleRate)
+				if i < fade {
+					v *= float64(i) / float64(fade)
+				} else if n-i < fade {
+					v *= float64(n-i) / float64(fade)
+				}
+			}
+			buf = binary.LittleEndian.AppendUint16(buf, uint16(int16(v*pcmAmplitude)))
+		}
+	}
+
+	return buf
+}
+
+// WAVWriter writes PCM samples to a WAV file
+// The header sizes are filled in by Close.
+type WAVWriter struct {
+	w    io.WriteSeeker
+	size int
+}
+
+// wavHeaderSize is the size of the RIFF, fmt and data chunk headers
+const wavHeaderSize = 44
+
+// NewWAVWriter writes a WAV header to w
+func NewWAVWriter(w io.WriteSeeker) (*WAVWriter, error) {
+	wav := &WAVWriter{w: w}
+	if _, err := w.Write(wav.header()); err != nil {
+		return nil, err
+	}
+	return wav, nil
+}
+
+// header returns the WAV header for the samples written so far
+func (wav *WAVWriter) header() []byte {
+	const channels, bits = 1, 16
+	le := binary.LittleEndian
+
+	h := make([]byte, 0, wavHeaderSize)
+	h = append(h, "RIFF"...)
+	h = le.AppendUint32(h, uint32(wavHeaderSize-8+wav.size))
+	h = append(h, "WAVEfmt "...)
+	h = le.AppendUint32(h, 16) // fmt chunk size
+	h = le.AppendUint16(h, 1)  // PCM
+	h = le.AppendUint16(h, channels)
+	h = le.AppendUint32(h, PCMSampleRate)
+	h = le.AppendUint32(h, PCMSampleRate*channels*bits/8) // Byte rate
+	h = le.AppendUint16(h, channels*bits/8)               // Block align
+	h = le.AppendUint16(h, bits)
+	h = append(h, "data"...)
+	h = le.AppendUint32(h, uint32(wav.size))
+	return h
+}
+
+// Write appends PCM samples
+func (wav *WAVWriter) Write(p []byte) (int, error) {
+	n, err := wav.w.Write(p)
+	wav.size += n
+	return n, err
+}
+
+// Close rewrites the header with the final sizes
+func (wav *WAVWriter) Close() error {
+	if _, err := wav.w.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	if _, err := wav.w.Write(wav.header()); err != nil {
+		return err
+	}
+	if c, ok := wav.w.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
+
+// OpenSound creates the sound player chosen in the settings
+// The returned function closes the sound output. PCM output goes to
+// settings.SoundOut: "-" for stdout, a ".wav" file, or a raw PCM file.
+// Writing PCM to stdout while it is the terminal would garble the screen,
+// so that falls back to the bell.
+func OpenSound(settings *Settings, beep func() error) (SoundPlayer, func() error, error) {
+	noClose := func() error { return nil }
+	bell := &BellPlayer{Beep: beep}
+
+	switch settings.Sound {
+	case SoundOff:
+		return nil, noClose, nil
+	case SoundPCM:
+	default:
+		return bell, noClose, nil
+	}
+
+	output := settings.SoundOut
+	if output == "" || output == "-" {
+		if isTerminal(os.Stdout) {
+			return bell, noClose, fmt.Errorf("PCM sound needs stdout redirected to a player, using the bell")
+		}
+		player := NewPCMPlayer(os.Stdout)
+		return player, player.Close, nil
+	}
+
+	f, err := os.Create(output)
+	if err != nil {
+		return bell, noClose, err
+	}
+	sink := io.WriteCloser(f)
+	if strings.HasSuffix(strings.ToLower(output), ".wav") {
+		wav, err := NewWAVWriter(f)
+		if err != nil {
+			f.Close()
+			return bell, noClose, err
+		}
+		sink = wav
+	}
+
+	// The queued sounds are written before the sink is closed
+	player := NewPCMPlayer(sink)
+	closeAll := func() error {
+		err := player.Close()
+		if closeErr := sink.Close(); err == nil {
+			err = closeErr
+		}
+		return err
+	}
+	return player, closeAll, nil
+}
+
+// isTerminal reports whether f is a character device such as a terminal
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
@@ -0,0 +1,244 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// recordingPlayer records the sounds played instead of playing them
+type recordingPlayer struct {
+	events []SoundEvent
+}
+
+func (r *recordingPlayer) Play(ev SoundEvent) {
+	r.events = append(r.events, ev)
+}
+
+// has reports whether a sound was played
+func (r *recordingPlayer) has(sound Sound) bool {
+	for _, ev := range r.events {
+		if ev.Sound == sound {
+			return true
+		}
+	}
+	return false
+}
+
+func TestGameSoundsRotateAndLock(t *testing.T) {
+	player := &recordingPlayer{}
+	game := NewGameWithSeed(4, 1)
+	game.Events.Subscribe(SoundHandler(player))
+
+	game.Move(0, 0, 1)
+	if !player.has(SoundRotate) {
+		t.Error("Expected rotate sound")
+	}
+
+	// Moving sideways is silent
+	player.events = nil
+	game.Move(1, 0, 0)
+	if len(player.events) != 0 {
+		t.Errorf("Expected no sound for a move, got %v", player.events)
+	}
+
+	game.HardDrop()
+	game.LockPair()
+	if !player.has(SoundLock) {
+		t.Error("Expected lock sound")
+	}
+}
+
+func TestGameSoundsChainSteps(t *testing.T) {
+	player := &recordingPlayer{}
+	game := NewGameWithSeed(4, 1)
+	game.Events.Subscribe(SoundHandler(player))
+	game.Current = nil
+
+	// A two-step chain: blue pops, then red falls onto the reds
+	game.Field.Grid[FieldHeight-1][0] = Red
+	game.Field.Grid[FieldHeight-1][1] = Red
+	game.Field.Grid[FieldHeight-2][0] = Red
+	for y := FieldHeight - 5; y < FieldHeight-1; y++ {
+		game.Field.Grid[y][1] = Blue
+	}
+	game.Field.Grid[FieldHeight-6][1] = Red
+	game.Field.Grid[FieldHeight-5][0] = Green
+	game.State = StateDropping
+
+	for game.ProcessChainStep() {
+	}
+
+	var chains []int
+	for _, ev := range player.events {
+		if ev.Sound == SoundChain {
+			chains = append(chains, ev.Chain)
+		}
+	}
+	if len(chains) != 2 || chains[0] != 1 || chains[1] != 2 {
+		t.Errorf("Expected chain sounds for steps 1 and 2, got %v", chains)
+	}
+	if player.has(SoundAllClear) {
+		t.Error("Expected no all clear with puyos left")
+	}
+}
+
+func TestGameSoundsAllClear(t *testing.T) {
+	player := &recordingPlayer{}
+	game := NewGameWithSeed(4, 1)
+	game.Events.Subscribe(SoundHandler(player))
+	game.Current = nil
+
+	for y := FieldHeight - 4; y < FieldHeight; y++ {
+		game.Field.Grid[y][0] = Yellow
+	}
+	game.State = StateDropping
+
+	for game.ProcessChainStep() {
+	}
+
+	if !player.has(SoundAllClear) {
+		t.Error("Expected all clear sound")
+	}
+}
+
+func TestGameSoundsGameOver(t *testing.T) {
+	player := &recordingPlayer{}
+	game := NewGameWithSeed(4, 1)
+	game.Events.Subscribe(SoundHandler(player))
+
+	game.Field.Grid[0][FieldWidth/2] = Red
+	game.SpawnNewPair()
+
+	if !game.GameOver || !player.has(SoundGameOver) {
+		t.Error("Expected game over sound")
+	}
+}
+
+func TestBellPlayerSkipsFrequentSounds(t *testing.T) {
+	rings := 0
+	bell := &BellPlayer{Beep: func() error {
+		rings++
+		return nil
+	}}
+
+	bell.Play(SoundEvent{Sound: SoundRotate})
+	bell.Play(SoundEvent{Sound: SoundLock})
+	if rings != 0 {
+		t.Errorf("Expected no bell for rotate and lock, got %d", rings)
+	}
+
+	bell.Play(SoundEvent{Sound: SoundChain, Chain: 1})
+	bell.Play(SoundEvent{Sound: SoundGameOver})
+	if rings != 2 {
+		t.Errorf("Expected 2 bells, got %d", rings)
+	}
+}
+
+func TestChainPitchRises(t *testing.T) {
+	prev := 0.0
+	for chain := 1; chain <= 10; chain++ {
+		tones := soundTones(SoundEvent{Sound: SoundChain, Chain: chain})
+		if len(tones) == 0 || tones[0].freq <= prev {
+			t.Fatalf("Chain %d: expected pitch above %.1f Hz, got %v", chain, prev, tones)
+		}
+		prev = tones[0].freq
+	}
+}
+
+func TestPCMPlayer(t *testing.T) {
+	var buf bytes.Buffer
+	player := NewPCMPlayer(&buf)
+
+	player.Play(SoundEvent{Sound: SoundLock})
+	if err := player.Close(); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	// 40ms of 16-bit mono samples
+	expected := PCMSampleRate * 40 / 1000 * 2
+	if buf.Len() != expected {
+		t.Errorf("Expected %d bytes of PCM, got %d", expected, buf.Len())
+	}
+}
+
+// blockingWriter blocks every write until released, like a full pipe
+type blockingWriter struct {
+	release chan struct{}
+	writes  int
+}
+
+func (w *blockingWriter) Write(p []byte) (int, error) {
+	<-w.release
+	w.writes++
+	return len(p), nil
+}
+
+func TestPCMPlayerDoesNotBlock(t *testing.T) {
+	w := &blockingWriter{release: make(chan struct{})}
+	player := NewPCMPlayer(w)
+
+	// One sound is being written and the queue fills up; the rest are dropped
+	for i := 0; i < pcmQueueSize*4; i++ {
+		player.Play(SoundEvent{Sound: SoundRotate})
+	}
+
+	close(w.release)
+	if err := player.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if w.writes > pcmQueueSize+1 {
+		t.Errorf("Expected at most %d sounds written, got %d", pcmQueueSize+1, w.writes)
+	}
+	if w.writes == 0 {
+		t.Error("Expected the queued sounds to be written")
+	}
+}
+
+func TestWAVOutput(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sound.wav")
+	settings := DefaultSettings()
+	settings.Sound = SoundPCM
+	settings.SoundOut = path
+
+	player, closeSound, err := OpenSound(settings, nil)
+	if err != nil {
+		t.Fatalf("OpenSound failed: %v", err)
+	}
+	player.Play(SoundEvent{Sound: SoundRotate})
+	player.Play(SoundEvent{Sound: SoundGameOver})
+	if err := closeSound(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data[0:4]) != "RIFF" || string(data[8:12]) != "WAVE" || string(data[36:40]) != "data" {
+		t.Fatalf("Missing WAV chunk headers")
+	}
+	dataSize := int(binary.LittleEndian.Uint32(data[40:44]))
+	if dataSize != len(data)-wavHeaderSize || dataSize == 0 {
+		t.Errorf("Data size %d does not match file size %d", dataSize, len(data))
+	}
+	if riffSize := int(binary.LittleEndian.Uint32(data[4:8])); riffSize != len(data)-8 {
+		t.Errorf("RIFF size %d does not match file size %d", riffSize, len(data))
+	}
+}
+
+func TestOpenSoundModes(t *testing.T) {
+	settings := DefaultSettings()
+
+	player, _, err := OpenSound(settings, nil)
+	if _, ok := player.(*BellPlayer); !ok || err != nil {
+		t.Errorf("Expected bell by default, got %T (%v)", player, err)
+	}
+
+	settings.Sound = SoundOff
+	if player, _, _ := OpenSound(settings, nil); player != nil {
+		t.Errorf("Expected no player when sound is off, got %T", player)
+	}
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"fmt"
+)
+
+// Goal selects what ends a timed run
+type Goal string
+
+const (
+	GoalNone    Goal = ""        // Endless: play until topping out
+	GoalChain   Goal = "chain"   // Fire a chain of GoalTarget links as fast as possible
+	GoalScore   Goal = "score"   // Reach GoalTarget points as fast as possible
+	GoalUltra   Goal = "ultra"   // Score as much as possible before TimeLimit runs out
+	GoalMission Goal = "mission" // Complete the objectives of Rules.Mission, see mission.go
+)
+
+// timerRedrawFrames is how often a running timer is redrawn (every 0.1 seconds)
+const timerRedrawFrames = 6
+
+// Timed returns true if the rules have a goal or time limit
+func (r Rules) Timed() bool {
+	return r.Goal != GoalNone || r.TimeLimit > 0
+}
+
+// LeaderboardKey names the leaderboard a run of these rules is ranked on
+// Runs with a different goal target or time limit are ranked separately.
+func (r Rules) LeaderboardKey() string {
+	switch r.Goal {
+	case GoalChain, GoalScore:
+		return fmt.Sprintf("%s-%d", r.Goal, r.GoalTarget)
+	case GoalUltra:
+		return fmt.Sprintf("%s-%d", r.Goal, r.TimeLimit/60)
+	default:
+		return ""
+	}
+}
+
+// TickTimer counts one frame of play and ends the run when its time is up
+// Returns true when the timer display changes. A shared match clock is not
+// ticked here; TickMatch ticks it once for both players.
+func (g *Game) TickTimer() bool {
+	if g.GameOver || g.Paused {
+		return false
+	}
+	g.Frames++
+	if !g.Rules.Timed() {
+		return false
+	}
+
+	if g.Rules.TimeLimit > 0 && g.Frames >= g.Rules.TimeLimit {
+		// Ultra is over when the time runs out; a sprint has failed
+		g.finishRun(g.Rules.Goal == GoalUltra)
+		return true
+	}
+	return g.Frames%timerRedrawFrames == 0
+}
+
+// checkGoal ends a sprint as soon as its goal is reached
+// It is called after every chain step, so the clock stops on the link that
+// completes the chain or passes the target score.
+func (g *Game) checkGoal() {
+	switch g.Rules.Goal {
+	case GoalChain:
+		if g.ChainCount >= g.Rules.GoalTarget {
+			g.finishRun(true)
+		}
+	case GoalScore:
+		if g.Score >= g.Rules.GoalTarget {
+			g.finishRun(true)
+		}
+	case GoalMission:
+		g.checkObjective()
+	}
+}
+
+// finishRun ends a timed run
+func (g *Game) finishRun(cleared bool) {
+	if g.GameOver {
+		return
+	}
+	// A run that ends mid-chain still counts the chain in the stats
+	if g.State != StateNormal && g.ChainCount > 0 {
+		g.calculateScore()
+	}
+	g.Cleared = cleared
+	g.GameOver = true
+	if cleared {
+		g.emit(GoalReached{Frames: g.Frames, Score: g.Score})
+	}
+	g.emit(GameOver{Score: g.Score})
+}
+
+// TimerFrames returns the frames shown on the timer
+// Ultra and missions with a time limit count down to the end of the run;
+// sprints count up.
+func (g *Game) TimerFrames() int {
+	if g.Rules.TimeLimit > 0 && (g.Rules.Goal == GoalUltra || g.Rules.Goal == GoalMission) {
+		return max(g.Rules.TimeLimit-g.Frames, 0)
+	}
+	return g.Frames
+}
+
+// formatFrames shows a frame count as minutes, seconds and hundredths
+func formatFrames(frames int) string {
+	seconds := frames / 60
+	hundredths := frames % 60 * 100 / 60
+	return fmt.Sprintf("%d:%02d.%02d", seconds/60, seconds%60, hundredths)
+}
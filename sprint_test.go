@@ -0,0 +1,178 @@
+package main
+
+import (
+	"testing"
+)
+
+// sprintGame returns a game with a goal on the standard field
+func sprintGame(goal Goal, target, timeLimit int) *Game {
+	rules := DefaultRules()
+	rules.Goal = goal
+	rules.GoalTarget = target
+	rules.TimeLimit = timeLimit
+	return NewGameWithRules(rules, 1)
+}
+
+// twoChainField is a field that fires a 2-chain when resolved
+const twoChainField = `
+.GGG..
+GRRRR.
+`
+
+func TestChainGoalEndsRun(t *testing.T) {
+	game := sprintGame(GoalChain, 2, 0)
+	events := recordEvents(game)
+	game.Field, _, _ = ParseFieldNotation(twoChainField, FieldWidth, FieldHeight)
+	game.Frames = 754
+
+	runChain(game)
+
+	if !game.Cleared || !game.GameOver {
+		t.Fatalf("Expected the 2-chain to clear the run, cleared=%v over=%v", game.Cleared, game.GameOver)
+	}
+	if game.MaxChain != 2 {
+		t.Errorf("Expected the finishing chain in the stats, got a %d-chain", game.MaxChain)
+	}
+	if game.Frames != 754 {
+		t.Errorf("Expected the timer to stop at 754 frames, got %d", game.Frames)
+	}
+
+	reached := false
+	for _, ev := range *events {
+		if ev == (GoalReached{Frames: 754, Score: game.Score}) {
+			reached = true
+		}
+	}
+	if !reached {
+		t.Error("Expected a GoalReached event")
+	}
+}
+
+func TestChainGoalNotReached(t *testing.T) {
+	game := sprintGame(GoalChain, 3, 0)
+	game.Field, _, _ = ParseFieldNotation(twoChainField, FieldWidth, FieldHeight)
+
+	runChain(game)
+
+	if game.Cleared || game.GameOver {
+		t.Error("Expected a 2-chain not to reach a 3-chain goal")
+	}
+}
+
+func TestScoreGoalEndsRun(t *testing.T) {
+	game := sprintGame(GoalScore, 50, 0)
+	for x := 0; x < 4; x++ {
+		game.Field.Grid[FieldHeight-1][x] = Red
+	}
+
+	runChain(game)
+
+	if !game.Cleared || !game.GameOver {
+		t.Error("Expected reaching the score to clear the run")
+	}
+}
+
+func TestUltraTimeLimit(t *testing.T) {
+	game := sprintGame(GoalUltra, 0, 10)
+	game.Score = 500
+
+	for i := 0; i < 9; i++ {
+		game.TickTimer()
+	}
+	if game.GameOver {
+		t.Fatal("Expected the run to continue before the time limit")
+	}
+	if game.TimerFrames() != 1 {
+		t.Errorf("Expected 1 frame left on the timer, got %d", game.TimerFrames())
+	}
+
+	if !game.TickTimer() {
+		t.Error("Expected the display to change when time runs out")
+	}
+	if !game.Cleared || !game.GameOver || game.Frames != 10 {
+		t.Errorf("Expected the run to end at 10 frames, got cleared=%v frames=%d", game.Cleared, game.Frames)
+	}
+	if game.TickTimer() || game.Frames != 10 {
+		t.Error("Expected the timer to stop after the run")
+	}
+}
+
+func TestSprintTimerLimitFails(t *testing.T) {
+	game := sprintGame(GoalChain, 10, 5)
+	for i := 0; i < 5; i++ {
+		game.TickTimer()
+	}
+	if !game.GameOver || game.Cleared {
+		t.Error("Expected a sprint out of time to end without clearing")
+	}
+}
+
+func TestTickTimer(t *testing.T) {
+	game := NewGame()
+	game.TogglePause()
+	game.TickTimer()
+	if game.Frames != 0 {
+		t.Error("Expected the timer to stop while paused")
+	}
+	game.TogglePause()
+
+	for i := 1; i <= timerRedrawFrames; i++ {
+		if game.TickTimer() {
+			t.Fatal("Expected no timer redraws in endless mode")
+		}
+	}
+	if game.Frames != timerRedrawFrames {
+		t.Errorf("Expected %d frames, got %d", timerRedrawFrames, game.Frames)
+	}
+
+	sprint := sprintGame(GoalChain, 10, 0)
+	redraws := 0
+	for i := 0; i < 60; i++ {
+		if sprint.TickTimer() {
+			redraws++
+		}
+	}
+	if redraws != 60/timerRedrawFrames {
+		t.Errorf("Expected %d redraws a second, got %d", 60/timerRedrawFrames, redraws)
+	}
+}
+
+func TestFormatFrames(t *testing.T) {
+	tests := map[int]string{
+		0:         "0:00.00",
+		59:        "0:00.98",
+		62*60 + 3: "1:02.05",
+		7200:      "2:00.00",
+	}
+	for frames, want := range tests {
+		if got := formatFrames(frames); got != want {
+			t.Errorf("formatFrames(%d) = %q, expected %q", frames, got, want)
+		}
+	}
+}
+
+func TestSprintPresets(t *testing.T) {
+	for name, key := range map[string]string{
+		"sprint-chain": "chain-10",
+		"sprint-score": "score-100000",
+		"ultra":        "ultra-120",
+	} {
+		r := RulesByName(name)
+		if !r.Timed() || r.LeaderboardKey() != key {
+			t.Errorf("%s: expected a timed mode ranked on %q, got %q", name, key, r.LeaderboardKey())
+		}
+	}
+	if DefaultRules().Timed() || DefaultRules().LeaderboardKey() != "" {
+		t.Error("Expected endless play to be untimed")
+	}
+
+	bad := []Rules{DefaultRules(), DefaultRules(), DefaultRules()}
+	bad[0].Goal = GoalChain
+	bad[1].Goal = GoalUltra
+	bad[2].Goal = "marathon"
+	for _, r := range bad {
+		if r.Validate() == nil {
+			t.Errorf("Expected goal %q without a target to be rejected", r.Goal)
+		}
+	}
+}
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/user"
+	"sort"
+	"time"
+)
+
+// Stats represents lifetime statistics for one player
+type Stats struct {
+	GamesPlayed    int            `json:"games_played"`
+	TotalFrames    int            `json:"total_frames"`
+	PiecesPlaced   int            `json:"pieces_placed"`
+	TotalScore     int            `json:"total_score"`
+	BestChain      int            `json:"best_chain"`
+	AllClears      int            `json:"all_clears"`
+	ChainHistogram map[int]int    `json:"chain_histogram"`
+	PuyosPopped    map[string]int `json:"puyos_popped"` // Keyed by color name
+}
+
+// statsFile is the on-disk format of the stats file
+type statsFile struct {
+	Players map[string]*Stats `json:"players"`
+}
+
+// NewStats creates empty statistics
+func NewStats() *Stats {
+	return &Stats{
+		ChainHistogram: make(map[int]int),
+		PuyosPopped:    make(map[string]int),
+	}
+}
+
+// RecordGame adds the results of a finished game to the statistics
+func (s *Stats) RecordGame(g *Game) {
+	if s.ChainHistogram == nil {
+		s.ChainHistogram = make(map[int]int)
+	}
+	if s.PuyosPopped == nil {
+		s.PuyosPopped = make(map[string]int)
+	}
+
+	s.GamesPlayed++
+	s.TotalFrames += g.Frames
+	s.PiecesPlaced += g.PiecesPlaced
+	s.TotalScore += g.Score
+	s.AllClears += g.AllClears
+
+	if g.MaxChain > s.BestChain {
+		s.BestChain = g.MaxChain
+	}
+	for length, count := range g.ChainHistogram {
+		s.ChainHistogram[length] += count
+	}
+	for c, count := range g.PuyosPopped {
+		if count > 0 {
+			s.PuyosPopped[Color(c).Name()] += count
+		}
+	}
+}
+
+// TotalTime returns the total time spent playing
+func (s *Stats) TotalTime() time.Duration {
+	return time.Duration(s.TotalFrames) * time.Second / 60
+}
+
+// AverageScore returns the average score per game
+func (s *Stats) AverageScore() int {
+	if s.GamesPlayed == 0 {
+		return 0
+	}
+	return s.TotalScore / s.GamesPlayed
+}
+
+// PiecesPerSecond returns the average number of pairs placed per second
+func (s *Stats) PiecesPerSecond() float64 {
+	if s.TotalFrames == 0 {
+		return 0
+	}
+	return float64(s.PiecesPlaced) / s.TotalTime().Seconds()
+}
+
+// chainLengths returns the chain lengths in the histogram, shortest first
+func (s *Stats) chainLengths() []int {
+	lengths := make([]int, 0, len(s.ChainHistogram))
+	for length := range s.ChainHistogram {
+		lengths = append(lengths, length)
+	}
+	sort.Ints(lengths)
+	return lengths
+}
+
+// currentPlayerName returns the name stats are recorded under
+// PUYO_PLAYER overrides the login name
+func currentPlayerName() string {
+	if name := os.Getenv("PUYO_PLAYER"); name != "" {
+		return name
+	}
+	if u, err := user.Current(); err == nil && u.Username != "" {
+		return u.Username
+	}
+	return "player"
+}
+
+// getStatsPath returns the path to the stats file
+func getStatsPath() (string, error) {
+	return getConfigPath("stats.json")
+}
+
+// loadStatsFile loads the stats of all players from disk
+// A corrupt file is an error rather than empty stats, so saving never
+// overwrites the records of every player.
+func loadStatsFile() (*statsFile, error) {
+	file := &statsFile{Players: make(map[string]*Stats)}
+
+	path, err := getStatsPath()
+	if err != nil {
+		return file, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return file, nil
+		}
+		return nil, err
+	}
+
+	if err := json.Unmarshal(data, file); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if file.Players == nil {
+		file.Players = make(map[string]*Stats)
+	}
+
+	return file, nil
+}
+
+// LoadStats loads the statistics of the given player from disk
+func LoadStats(player string) (*Stats, error) {
+	file, err := loadStatsFile()
+	if err != nil {
+		return nil, err
+	}
+
+	if s, ok := file.Players[player]; ok && s != nil {
+		return s, nil
+	}
+	return NewStats(), nil
+}
+
+// SaveStats saves the statistics of the given player to disk
+func SaveStats(player string, s *Stats) error {
+	file, err := loadStatsFile()
+	if err != nil {
+		return err
+	}
+	file.Players[player] = s
+
+	path, err := getStatsPath()
+	if err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, data, 0644)
+}
+
+// UpdateStats adds a finished game to the player's stored statistics
+func UpdateStats(player string, game *Game) (*Stats, error) {
+	s, err := LoadStats(player)
+	if err != nil {
+		return nil, err
+	}
+
+	s.RecordGame(game)
+
+	if err := SaveStats(player, s); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"os"
+	"testing"
+)
+
+func TestRecordGame(t *testing.T) {
+	game := NewGame()
+	game.Score = 3000
+	game.PiecesPlaced = 30
+	game.Frames = 600
+	game.MaxChain = 3
+	game.ChainHistogram[1] = 2
+	game.ChainHistogram[3] = 1
+	game.PuyosPopped[Red] = 8
+	game.PuyosPopped[Blue] = 4
+	game.AllClears = 1
+
+	stats := NewStats()
+	stats.RecordGame(game)
+	stats.RecordGame(game)
+
+	if stats.GamesPlayed != 2 {
+		t.Errorf("Expected 2 games played, got %d", stats.GamesPlayed)
+	}
+	if stats.PiecesPlaced != 60 {
+		t.Errorf("Expected 60 pieces placed, got %d", stats.PiecesPlaced)
+	}
+	if stats.BestChain != 3 {
+		t.Errorf("Expected best chain 3, got %d", stats.BestChain)
+	}
+	if stats.ChainHistogram[1] != 4 || stats.ChainHistogram[3] != 2 {
+		t.Errorf("Unexpected chain histogram %v", stats.ChainHistogram)
+	}
+	if stats.PuyosPopped["red"] != 16 || stats.PuyosPopped["blue"] != 8 {
+		t.Errorf("Unexpected popped counts %v", stats.PuyosPopped)
+	}
+	if stats.AllClears != 2 {
+		t.Errorf("Expected 2 all clears, got %d", stats.AllClears)
+	}
+	if stats.AverageScore() != 3000 {
+		t.Errorf("Expected average score 3000, got %d", stats.AverageScore())
+	}
+	if pps := stats.PiecesPerSecond(); pps != 3 {
+		t.Errorf("Expected 3 pieces per second, got %v", pps)
+	}
+}
+
+func TestStatsEmpty(t *testing.T) {
+	stats := NewStats()
+
+	if stats.AverageScore() != 0 {
+		t.Errorf("Expected average score 0, got %d", stats.AverageScore())
+	}
+	if stats.PiecesPerSecond() != 0 {
+		t.Errorf("Expected 0 pieces per second, got %v", stats.PiecesPerSecond())
+	}
+}
+
+func TestChainStatsFromScore(t *testing.T) {
+	game := NewGame()
+
+	game.ChainCount = 2
+	game.calculateScore()
+	game.ChainCount = 1
+	game.calculateScore()
+
+	if game.MaxChain != 2 {
+		t.Errorf("Expected max chain 2, got %d", game.MaxChain)
+	}
+	if game.ChainHistogram[1] != 1 || game.ChainHistogram[2] != 1 {
+		t.Errorf("Unexpected chain histogram %v", game.ChainHistogram)
+	}
+}
+
+func TestUpdateStatsPerPlayer(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	game := NewGame()
+	game.Score = 1200
+	game.PiecesPlaced = 10
+
+	if _, err := UpdateStats("alice", game); err != nil {
+		t.Fatalf("UpdateStats failed: %v", err)
+	}
+	if _, err := UpdateStats("alice", game); err != nil {
+		t.Fatalf("UpdateStats failed: %v", err)
+	}
+	if _, err := UpdateStats("bob", game); err != nil {
+		t.Fatalf("UpdateStats failed: %v", err)
+	}
+
+	alice, err := LoadStats("alice")
+	if err != nil {
+		t.Fatalf("LoadStats failed: %v", err)
+	}
+	if alice.GamesPlayed != 2 || alice.TotalScore != 2400 {
+		t.Errorf("Unexpected stats for alice: %+v", alice)
+	}
+
+	bob, err := LoadStats("bob")
+	if err != nil {
+		t.Fatalf("LoadStats failed: %v", err)
+	}
+	if bob.GamesPlayed != 1 {
+		t.Errorf("Expected 1 game for bob, got %d", bob.GamesPlayed)
+	}
+
+	carol, err := LoadStats("carol")
+	if err != nil {
+		t.Fatalf("LoadStats failed: %v", err)
+	}
+	if carol.GamesPlayed != 0 {
+		t.Errorf("Expected no games for unknown player, got %d", carol.GamesPlayed)
+	}
+}
+
+func TestUpdateStatsKeepsCorruptFile(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	path, _ := getStatsPath()
+	if err := os.WriteFile(path, []byte(`{"players": {"alice": `), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := UpdateStats("alice", NewGame()); err == nil {
+		t.Error("Expected an error for a corrupt stats file")
+	}
+	if data, _ := os.ReadFile(path); string(data) != `{"players": {"alice": ` {
+		t.Errorf("Expected the corrupt file to be left alone, got %q", data)
+	}
+}
+
+func TestClearPuyosCountsColors(t *testing.T) {
+	game := NewGame()
+
+	for x := 0; x < 4; x++ {
+		game.Field.Grid[FieldHeight-1][x] = Green
+	}
+
+	game.clearPuyos()
+
+	if game.PuyosPopped[Green] != 4 {
+		t.Errorf("Expected 4 green puyos popped, got %d", game.PuyosPopped[Green])
+	}
+}
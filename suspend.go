@@ -0,0 +1,284 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"os"
+	"time"
+)
+
+// SuspendState is the serialized form of an in-progress game
+type SuspendState struct {
+	Field           *Field            `json:"field"`
+	Current         *PuyoPair         `json:"current"`
+	Next            *PuyoPair         `json:"next"`
+	Seed            int64             `json:"seed"`
+	RandDraws       int64             `json:"rand_draws"` // Values drawn from the RNG so far
+	Score           int               `json:"score"`
+	Level           int               `json:"level"`
+	Paused          bool              `json:"paused"`
+	ChainCount      int               `json:"chain_count"`
+	TotalChains     int               `json:"total_chains"`
+	LinesCleared    int               `json:"lines_cleared"`
+	DropSpeed       time.Duration     `json:"drop_speed"`
+	State           GameState         `json:"state"`
+	CurrentChainNum int               `json:"current_chain_num"`
+	GroundFrames    int               `json:"ground_frames"`
+	MaxGroundFrames int               `json:"max_ground_frames"`
+	ColorCount      int               `json:"color_count"`
+	PiecesPlaced    int               `json:"pieces_placed"`
+	PiecesDealt     int               `json:"pieces_dealt"`
+	MaxChain        int               `json:"max_chain"`
+	ChainHistogram  map[int]int       `json:"chain_histogram"`
+	PuyosPopped     [MaxColor + 1]int `json:"puyos_popped"`
+	Frames          int               `json:"frames"`
+	PopFrames       int               `json:"pop_frames"`
+	FallFrames      int               `json:"fall_frames"`
+	AnimFrame       int               `json:"anim_frame"`
+	Popping         []Position        `json:"popping"`
+	ChainPos        Position          `json:"chain_pos"`
+	Progression     string            `json:"progression"`
+	Gravity         int               `json:"gravity"`
+	Rules           *Rules            `json:"rules"`
+	FeverGauge      int               `json:"fever_gauge,omitempty"`
+	FeverActive     bool              `json:"fever_active,omitempty"`
+	FeverFrames     int               `json:"fever_frames,omitempty"`
+	FeverChain      int               `json:"fever_chain,omitempty"`
+	NormalField     *Field            `json:"normal_field,omitempty"`
+	AllClears       int               `json:"all_clears"`
+	AllClearPending bool              `json:"all_clear_pending,omitempty"`
+	Versus          bool              `json:"versus,omitempty"`
+	Garbage         GarbageQueue      `json:"garbage"`
+	NuisanceScore   int               `json:"nuisance_score,omitempty"`
+	ChainOffset     bool              `json:"chain_offset,omitempty"`
+	GarbageFell     bool              `json:"garbage_fell,omitempty"`
+	Clock           *MatchClock       `json:"clock,omitempty"`
+	MissionStep     int               `json:"mission_step,omitempty"`
+	MissionPieces   int               `json:"mission_pieces,omitempty"`
+}
+
+// Suspend captures the full state of the game
+func (g *Game) Suspend() *SuspendState {
+	rules := g.Rules
+	return &SuspendState{
+		Field:           g.Field.Clone(),
+		Current:         copyPair(g.Current),
+		Next:            copyPair(g.Next),
+		Seed:            g.source.seed,
+		RandDraws:       g.source.draws,
+		Score:           g.Score,
+		Level:           g.Level,
+		Paused:          g.Paused,
+		ChainCount:      g.ChainCount,
+		TotalChains:     g.TotalChains,
+		LinesCleared:    g.LinesCleared,
+		DropSpeed:       g.DropSpeed,
+		State:           g.State,
+		CurrentChainNum: g.CurrentChainNum,
+		GroundFrames:    g.GroundFrames,
+		MaxGroundFrames: g.MaxGroundFrames,
+		ColorCount:      g.ColorCount,
+		PiecesPlaced:    g.PiecesPlaced,
+		PiecesDealt:     g.PiecesDealt,
+		MaxChain:        g.MaxChain,
+		ChainHistogram:  g.ChainHistogram,
+		PuyosPopped:     g.PuyosPopped,
+		Frames:          g.Frames,
+		PopFrames:       g.PopFrames,
+		FallFrames:      g.FallFrames,
+		AnimFrame:       g.AnimFrame,
+		Popping:         append([]Position(nil), g.Popping...),
+		ChainPos:        g.ChainPos,
+		Progression:     g.progression().Name,
+		Gravity:         g.Gravity,
+		Rules:           &rules,
+		FeverGauge:      g.FeverGauge,
+		FeverActive:     g.FeverActive,
+		FeverFrames:     g.FeverFrames,
+		FeverChain:      g.FeverChain,
+		NormalField:     cloneField(g.NormalField),
+		AllClears:       g.AllClears,
+		AllClearPending: g.AllClearPending,
+		Versus:          g.Versus,
+		Garbage:         g.Garbage,
+		NuisanceScore:   g.NuisanceScore,
+		ChainOffset:     g.ChainOffset,
+		GarbageFell:     g.GarbageFell,
+		Clock:           copyClock(g.Clock),
+		MissionStep:     g.MissionStep,
+		MissionPieces:   g.MissionPieces,
+	}
+}
+
+// Restore recreates the suspended game
+// The RNG is replayed to the same point, so the pair sequence continues
+// unchanged. Rules that do not describe a playable game are an error.
+func (s *SuspendState) Restore() (*Game, error) {
+	if s.Rules == nil {
+		return nil, fmt.Errorf("suspended game has no rules")
+	}
+	rules := *s.Rules
+	if err := rules.Validate(); err != nil {
+		return nil, fmt.Errorf("suspended game: %w", err)
+	}
+	source := restoreCountingSource(s.Seed, s.RandDraws)
+
+	field := NewFieldSize(rules.Width, rules.Height)
+	if s.Field != nil {
+		for y := 0; y < len(s.Field.Grid) && y < field.Height; y++ {
+			copy(field.Grid[y], s.Field.Grid[y])
+		}
+	}
+
+	histogram := s.ChainHistogram
+	if histogram == nil {
+		histogram = make(map[int]int)
+	}
+
+	g := &Game{
+		Field:           field,
+		Rules:           rules,
+		Current:         copyPair(s.Current),
+		Next:            copyPair(s.Next),
+		rand:            rand.New(source),
+		source:          source,
+		Score:           s.Score,
+		Level:           s.Level,
+		Paused:          s.Paused,
+		ChainCount:      s.ChainCount,
+		TotalChains:     s.TotalChains,
+		LinesCleared:    s.LinesCleared,
+		DropSpeed:       s.DropSpeed,
+		State:           s.State,
+		CurrentChainNum: s.CurrentChainNum,
+		GroundFrames:    s.GroundFrames,
+		MaxGroundFrames: s.MaxGroundFrames,
+		ColorCount:      s.ColorCount,
+		PiecesPlaced:    s.PiecesPlaced,
+		PiecesDealt:     s.PiecesDealt,
+		MaxChain:        s.MaxChain,
+		ChainHistogram:  histogram,
+		PuyosPopped:     s.PuyosPopped,
+		Frames:          s.Frames,
+		PopFrames:       s.PopFrames,
+		FallFrames:      s.FallFrames,
+		AnimFrame:       s.AnimFrame,
+		Popping:         append([]Position(nil), s.Popping...),
+		ChainPos:        s.ChainPos,
+		Events:          NewEventBus(),
+		Progression:     ProgressionByName(s.Progression),
+		Gravity:         s.Gravity,
+		FeverGauge:      s.FeverGauge,
+		FeverActive:     s.FeverActive && s.NormalField != nil,
+		FeverFrames:     s.FeverFrames,
+		FeverChain:      s.FeverChain,
+		NormalField:     cloneField(s.NormalField),
+		AllClears:       s.AllClears,
+		AllClearPending: s.AllClearPending,
+		Versus:          s.Versus,
+		Garbage:         s.Garbage,
+		NuisanceScore:   s.NuisanceScore,
+		ChainOffset:     s.ChainOffset,
+		GarbageFell:     s.GarbageFell,
+		Clock:           copyClock(s.Clock),
+		MissionStep:     s.MissionStep,
+		MissionPieces:   s.MissionPieces,
+	}
+
+	return g, nil
+}
+
+// cloneField returns a copy of a field, or nil
+func cloneField(f *Field) *Field {
+	if f == nil {
+		return nil
+	}
+	return f.Clone()
+}
+
+// copyClock returns a copy of a match clock, or nil
+// A restored game has its own clock; LinkVersus shares one again.
+func copyClock(c *MatchClock) *MatchClock {
+	if c == nil {
+		return nil
+	}
+	clock := *c
+	return &clock
+}
+
+// copyPair returns a copy of a pair, or nil
+func copyPair(p *PuyoPair) *PuyoPair {
+	if p == nil {
+		return nil
+	}
+	c := *p
+	c.Extra = append([]Puyo(nil), p.Extra...)
+	return &c
+}
+
+// getSuspendPath returns the path to the suspended game file
+func getSuspendPath() (string, error) {
+	return getConfigPath("suspend.json")
+}
+
+// SaveSuspend writes the game to the suspend file
+func SaveSuspend(g *Game) error {
+	path, err := getSuspendPath()
+	if err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(g.Suspend())
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, data, 0644)
+}
+
+// LoadSuspend loads the suspended game
+// Returns nil without an error when there is no suspended game
+func LoadSuspend() (*Game, error) {
+	path, err := getSuspendPath()
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var s SuspendState
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, err
+	}
+
+	return s.Restore()
+}
+
+// HasSuspend reports whether a suspended game exists
+func HasSuspend() bool {
+	path, err := getSuspendPath()
+	if err != nil {
+		return false
+	}
+	_, err = os.Stat(path)
+	return err == nil
+}
+
+// ClearSuspend removes the suspended game
+func ClearSuspend() error {
+	path, err := getSuspendPath()
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"reflect"
+	"testing"
+)
+
+// mustRestore restores a suspended game, failing the test on an error
+func mustRestore(t *testing.T, s *SuspendState) *Game {
+	t.Helper()
+	g, err := s.Restore()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return g
+}
+
+func TestSuspendRestoresState(t *testing.T) {
+	game := NewGameWithSeed(5, 42)
+	game.Field.Grid[FieldHeight-1][0] = Red
+	game.Field.Grid[FieldHeight-1][1] = Blue
+	game.Score = 1234
+	game.Level = 3
+	game.GroundFrames = 7
+	game.Paused = true
+	game.State = StateDropping
+	game.Current.Pos.X = 1
+
+	restored := mustRestore(t, game.Suspend())
+
+	if !reflect.DeepEqual(restored.Field, game.Field) {
+		t.Error("Field was not restored")
+	}
+	if !reflect.DeepEqual(restored.Current, game.Current) || !reflect.DeepEqual(restored.Next, game.Next) {
+		t.Error("Current/next pairs were not restored")
+	}
+	if restored.Score != 1234 || restored.Level != 3 || restored.GroundFrames != 7 {
+		t.Errorf("Counters not restored: score=%d level=%d ground=%d",
+			restored.Score, restored.Level, restored.GroundFrames)
+	}
+	if !restored.Paused || restored.State != StateDropping || restored.ColorCount != 5 {
+		t.Error("Pause flag, chain state or color count not restored")
+	}
+
+	// The restored copy must not share the field with the original
+	restored.Field.Grid[0][0] = Green
+	if game.Field.Grid[0][0] == Green {
+		t.Error("Restored field shares memory with the original")
+	}
+}
+
+func TestSuspendRestoresPairSequence(t *testing.T) {
+	game := NewGameWithSeed(4, 7)
+	for i := 0; i < 5; i++ {
+		game.SpawnNewPair()
+	}
+
+	restored := mustRestore(t, game.Suspend())
+
+	for i := 0; i < 20; i++ {
+		want := game.generatePuyoPair()
+		got := restored.generatePuyoPair()
+		if !reflect.DeepEqual(want, got) {
+			t.Fatalf("Pair %d differs after restore: want %v/%v, got %v/%v",
+				i, want.Main.Color, want.Sub.Color, got.Main.Color, got.Sub.Color)
+		}
+	}
+}
+
+func TestSaveAndLoadSuspend(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	if HasSuspend() {
+		t.Fatal("Expected no suspended game in a fresh home directory")
+	}
+
+	game := NewGameWithSeed(4, 99)
+	game.Score = 500
+	if err := SaveSuspend(game); err != nil {
+		t.Fatalf("SaveSuspend failed: %v", err)
+	}
+	if !HasSuspend() {
+		t.Fatal("Expected suspended game after SaveSuspend")
+	}
+
+	loaded, err := LoadSuspend()
+	if err != nil {
+		t.Fatalf("LoadSuspend failed: %v", err)
+	}
+	if loaded.Score != 500 || loaded.Seed() != 99 {
+		t.Errorf("Unexpected loaded game: score=%d seed=%d", loaded.Score, loaded.Seed())
+	}
+	if !reflect.DeepEqual(loaded.Next, game.Next) {
+		t.Error("Next pair was not saved")
+	}
+
+	if err := ClearSuspend(); err != nil {
+		t.Fatalf("ClearSuspend failed: %v", err)
+	}
+	if HasSuspend() {
+		t.Error("Expected suspended game to be removed")
+	}
+
+	loaded, err = LoadSuspend()
+	if err != nil || loaded != nil {
+		t.Errorf("Expected nil game without error, got %v, %v", loaded, err)
+	}
+}
+
+func TestSuspendKeepsAllClear(t *testing.T) {
+	game := NewGameWithSeed(4, 1)
+	game.AllClears = 2
+	game.AllClearPending = true
+
+	data, err := json.Marshal(game.Suspend())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var state SuspendState
+	if err := json.Unmarshal(data, &state); err != nil {
+		t.Fatal(err)
+	}
+	restored := mustRestore(t, &state)
+
+	if restored.AllClears != 2 || !restored.AllClearPending {
+		t.Errorf("Expected the all clears to be restored, got %d pending=%v", restored.AllClears, restored.AllClearPending)
+	}
+}
+
+func TestLoadSuspendRejectsInvalidRules(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	game := NewGameWithSeed(4, 1)
+	state := game.Suspend()
+	state.Rules.Colors = 0
+
+	path, _ := getSuspendPath()
+	data, _ := json.Marshal(state)
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if g, err := LoadSuspend(); err == nil || g != nil {
+		t.Errorf("Expected invalid rules to be an error, got %v", err)
+	}
+}
@@ -0,0 +1,351 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/gdamore/tcell/v2"
+	"github.com/mattn/go-runewidth"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// PuyoLook is how a single puyo color is drawn
+type PuyoLook struct {
+	Fg    tcell.Color
+	Bg    tcell.Color
+	Glyph string // One or two columns wide
+	Bold  bool
+}
+
+// Border holds the characters used for the field frame
+type Border struct {
+	Horizontal, Vertical                       string
+	TopLeft, TopRight, BottomLeft, BottomRight string
+}
+
+// Theme maps each puyo color to a look and defines the field frame
+type Theme struct {
+	Name       string
+	Puyos      map[Color]PuyoLook
+	Ghost      string // Glyph for the landing preview, drawn in the puyo's color
+	Linked     string // Glyph for puyos touching the same color ("" keeps the normal glyph)
+	Bridge     string // Fills the gap between horizontally connected puyos
+	Border     Border
+	Monochrome bool // Playable without color, e.g. on TERM=vt100
+}
+
+var (
+	boxBorder   = Border{"─", "│", "┌", "┐", "└", "┘"}
+	asciiBorder = Border{"-", "|", "+", "+", "+", "+"}
+)
+
+// builtinThemes returns the themes shipped with the game
+func builtinThemes() []*Theme {
+	classic := &Theme{
+		Name:   "classic",
+		Puyos:  map[Color]PuyoLook{},
+		Ghost:  "○",
+		Linked: "█",
+		Bridge: "█",
+		Border: boxBorder,
+	}
+	for c := Red; c <= Nuisance; c++ {
+		classic.Puyos[c] = PuyoLook{Fg: getColorForPuyo(c), Bg: tcell.ColorDefault, Glyph: "●"}
+	}
+
+	// Bold letters on bright backgrounds
+	highContrast := &Theme{
+		Name: "high-contrast",
+		Puyos: map[Color]PuyoLook{
+			Red:      {Fg: tcell.ColorBlack, Bg: tcell.NewHexColor(0xff3030), Glyph: "R", Bold: true},
+			Green:    {Fg: tcell.ColorBlack, Bg: tcell.NewHexColor(0x30ff30), Glyph: "G", Bold: true},
+			Blue:     {Fg: tcell.ColorWhite, Bg: tcell.NewHexColor(0x2040ff), Glyph: "B", Bold: true},
+			Yellow:   {Fg: tcell.ColorBlack, Bg: tcell.NewHexColor(0xffff40), Glyph: "Y", Bold: true},
+			Purple:   {Fg: tcell.ColorWhite, Bg: tcell.NewHexColor(0xc040ff), Glyph: "P", Bold: true},
+			Orange:   {Fg: tcell.ColorBlack, Bg: tcell.NewHexColor(0xff9020), Glyph: "O", Bold: true},
+			Nuisance: {Fg: tcell.ColorBlack, Bg: tcell.NewHexColor(0xc0c0c0), Glyph: "N", Bold: true},
+		},
+		Ghost:  "·",
+		Bridge: " ", // Background fill joins the cells
+		Border: boxBorder,
+	}
+
+	// Okabe-Ito palette, distinguishable with deuteranopia and protanopia
+	colorblind := &Theme{
+		Name: "colorblind",
+		Puyos: map[Color]PuyoLook{
+			Red:      {Fg: tcell.NewHexColor(0xd55e00), Bg: tcell.ColorDefault, Glyph: "●"},
+			Green:    {Fg: tcell.NewHexColor(0x009e73), Bg: tcell.ColorDefault, Glyph: "●"},
+			Blue:     {Fg: tcell.NewHexColor(0x56b4e9), Bg: tcell.ColorDefault, Glyph: "●"},
+			Yellow:   {Fg: tcell.NewHexColor(0xf0e442), Bg: tcell.ColorDefault, Glyph: "●"},
+			Purple:   {Fg: tcell.NewHexColor(0xcc79a7), Bg: tcell.ColorDefault, Glyph: "●"},
+			Orange:   {Fg: tcell.NewHexColor(0xe69f00), Bg: tcell.ColorDefault, Glyph: "●"},
+			Nuisance: {Fg: tcell.NewHexColor(0xbbbbbb), Bg: tcell.ColorDefault, Glyph: "◎"},
+		},
+		Ghost:  "○",
+		Linked: "█",
+		Bridge: "█",
+		Border: boxBorder,
+	}
+
+	// Each color has its own shape, so color is optional
+	shapes := &Theme{
+		Name: "shapes",
+		Puyos: map[Color]PuyoLook{
+			Red:      {Fg: tcell.ColorRed, Bg: tcell.ColorDefault, Glyph: "●"},
+			Green:    {Fg: tcell.ColorGreen, Bg: tcell.ColorDefault, Glyph: "▲"},
+			Blue:     {Fg: tcell.ColorBlue, Bg: tcell.ColorDefault, Glyph: "■"},
+			Yellow:   {Fg: tcell.ColorYellow, Bg: tcell.ColorDefault, Glyph: "◆"},
+			Purple:   {Fg: tcell.ColorPurple, Bg: tcell.ColorDefault, Glyph: "★"},
+			Orange:   {Fg: tcell.ColorOrange, Bg: tcell.ColorDefault, Glyph: "▼"},
+			Nuisance: {Fg: tcell.ColorSilver, Bg: tcell.ColorDefault, Glyph: "◎"},
+		},
+		Ghost:      "·",
+		Bridge:     "─",
+		Border:     boxBorder,
+		Monochrome: true,
+	}
+
+	// Plain ASCII without color for minimal terminals
+	ascii := &Theme{
+		Name: "ascii",
+		Puyos: map[Color]PuyoLook{
+			Red:      {Fg: tcell.ColorDefault, Bg: tcell.ColorDefault, Glyph: "O"},
+			Green:    {Fg: tcell.ColorDefault, Bg: tcell.ColorDefault, Glyph: "X"},
+			Blue:     {Fg: tcell.ColorDefault, Bg: tcell.ColorDefault, Glyph: "#"},
+			Yellow:   {Fg: tcell.ColorDefault, Bg: tcell.ColorDefault, Glyph: "$"},
+			Purple:   {Fg: tcell.ColorDefault, Bg: tcell.ColorDefault, Glyph: "%"},
+			Orange:   {Fg: tcell.ColorDefault, Bg: tcell.ColorDefault, Glyph: "&"},
+			Nuisance: {Fg: tcell.ColorDefault, Bg: tcell.ColorDefault, Glyph: "="},
+		},
+		Ghost:      ".",
+		Bridge:     "-",
+		Border:     asciiBorder,
+		Monochrome: true,
+	}
+
+	return []*Theme{classic, highContrast, colorblind, shapes, ascii}
+}
+
+// themes holds the available themes by name
+var themes = map[string]*Theme{}
+
+// themeOrder lists theme names in the order they are offered
+var themeOrder []string
+
+func init() {
+	for _, theme := range builtinThemes() {
+		registerTheme(theme)
+	}
+}
+
+// registerTheme adds a theme, replacing any theme with the same name
+func registerTheme(theme *Theme) {
+	if _, exists := themes[theme.Name]; !exists {
+		themeOrder = append(themeOrder, theme.Name)
+	}
+	themes[theme.Name] = theme
+}
+
+// ThemeNames lists the available themes
+func ThemeNames() []string {
+	return append([]string(nil), themeOrder...)
+}
+
+// ThemeByName returns the named theme, or the classic theme if it doesn't exist
+func ThemeByName(name string) *Theme {
+	if theme, ok := themes[name]; ok {
+		return theme
+	}
+	return themes["classic"]
+}
+
+// Look returns how a color is drawn, with the glyph padded to two columns
+func (t *Theme) Look(c Color) (string, tcell.Style) {
+	look, ok := t.Puyos[c]
+	if !ok {
+		return "  ", tcell.StyleDefault
+	}
+	style := tcell.StyleDefault.Foreground(look.Fg).Background(look.Bg).Bold(look.Bold)
+	return padCell(look.Glyph), style
+}
+
+// LinkedLook returns how a color is drawn in the field given the directions
+// it connects to same-colored neighbors
+// Connected puyos use the theme's linked glyph, and a bridge fills the gap
+// to a connected neighbor on the right.
+func (t *Theme) LinkedLook(c Color, links Link) (string, tcell.Style) {
+	look, ok := t.Puyos[c]
+	if !ok {
+		return "  ", tcell.StyleDefault
+	}
+	style := tcell.StyleDefault.Foreground(look.Fg).Background(look.Bg).Bold(look.Bold)
+
+	glyph := look.Glyph
+	if links != 0 && t.Linked != "" {
+		glyph = t.Linked
+	}
+	if runewidth.StringWidth(glyph) >= 2 {
+		return glyph, style
+	}
+	if links&LinkRight != 0 && t.Bridge != "" {
+		return glyph + t.Bridge, style
+	}
+	return padCell(glyph), style
+}
+
+// GhostLook returns how the landing preview of a color is drawn
+func (t *Theme) GhostLook(c Color) (string, tcell.Style) {
+	style := tcell.StyleDefault.Dim(true)
+	if look, ok := t.Puyos[c]; ok && !t.Monochrome {
+		style = style.Foreground(look.Fg)
+	}
+	return padCell(t.Ghost), style
+}
+
+// padCell pads a glyph with spaces to fill a two-column field cell
+func padCell(glyph string) string {
+	if w := runewidth.StringWidth(glyph); w < 2 {
+		return glyph + strings.Repeat(" ", 2-w)
+	}
+	return glyph
+}
+
+// themeFile is the on-disk format of a user theme
+//
+//	{
+//	  "name": "mine",
+//	  "puyos": {"red": {"fg": "#ff0000", "bg": "default", "glyph": "●", "bold": false}, ...},
+//	  "ghost": "○",
+//	  "linked": "█",
+//	  "bridge": "█",
+//	  "border": "box",
+//	  "monochrome": false
+//	}
+type themeFile struct {
+	Name  string `json:"name"`
+	Puyos map[string]struct {
+		Fg    string `json:"fg"`
+		Bg    string `json:"bg"`
+		Glyph string `json:"glyph"`
+		Bold  bool   `json:"bold"`
+	} `json:"puyos"`
+	Ghost      string `json:"ghost"`
+	Linked     string `json:"linked"`
+	Bridge     string `json:"bridge"`
+	Border     string `json:"border"` // "box" or "ascii"
+	Monochrome bool   `json:"monochrome"`
+}
+
+// ParseTheme parses and validates a theme definition
+func ParseTheme(data []byte) (*Theme, error) {
+	var f themeFile
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, err
+	}
+	if f.Name == "" {
+		return nil, fmt.Errorf("theme has no name")
+	}
+
+	theme := &Theme{
+		Name:       f.Name,
+		Puyos:      map[Color]PuyoLook{},
+		Ghost:      f.Ghost,
+		Linked:     f.Linked,
+		Bridge:     f.Bridge,
+		Border:     boxBorder,
+		Monochrome: f.Monochrome,
+	}
+	if theme.Ghost == "" {
+		theme.Ghost = "○"
+	}
+	if w := runewidth.StringWidth(theme.Linked); w > 2 {
+		return nil, fmt.Errorf("theme %q: linked glyph must be 1 or 2 columns wide", f.Name)
+	}
+	if w := runewidth.StringWidth(theme.Bridge); w > 1 {
+		return nil, fmt.Errorf("theme %q: bridge must be 1 column wide", f.Name)
+	}
+
+	switch f.Border {
+	case "", "box":
+	case "ascii":
+		theme.Border = asciiBorder
+	default:
+		return nil, fmt.Errorf("theme %q: unknown border %q", f.Name, f.Border)
+	}
+
+	for c := Red; c <= Nuisance; c++ {
+		entry, ok := f.Puyos[c.Name()]
+		if !ok {
+			return nil, fmt.Errorf("theme %q: missing color %q", f.Name, c.Name())
+		}
+		if w := runewidth.StringWidth(entry.Glyph); w < 1 || w > 2 {
+			return nil, fmt.Errorf("theme %q: glyph for %q must be 1 or 2 columns wide", f.Name, c.Name())
+		}
+		fg, err := parseThemeColor(entry.Fg)
+		if err != nil {
+			return nil, fmt.Errorf("theme %q: %s fg: %v", f.Name, c.Name(), err)
+		}
+		bg, err := parseThemeColor(entry.Bg)
+		if err != nil {
+			return nil, fmt.Errorf("theme %q: %s bg: %v", f.Name, c.Name(), err)
+		}
+		theme.Puyos[c] = PuyoLook{Fg: fg, Bg: bg, Glyph: entry.Glyph, Bold: entry.Bold}
+	}
+
+	return theme, nil
+}
+
+// parseThemeColor parses a color name or #rrggbb value
+func parseThemeColor(name string) (tcell.Color, error) {
+	if name == "" || name == "default" {
+		return tcell.ColorDefault, nil
+	}
+	c := tcell.GetColor(name)
+	if c == tcell.ColorDefault {
+		return c, fmt.Errorf("unknown color %q", name)
+	}
+	return c, nil
+}
+
+// getThemesDir returns the directory user themes are loaded from
+func getThemesDir() (string, error) {
+	return getConfigPath("themes")
+}
+
+// LoadUserThemes registers the themes in ~/.puyo/themes/*.json
+// Invalid files are skipped and reported in the returned error.
+func LoadUserThemes() error {
+	dir, err := getThemesDir()
+	if err != nil {
+		return err
+	}
+
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return err
+	}
+	sort.Strings(paths)
+
+	var problems []string
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			problems = append(problems, err.Error())
+			continue
+		}
+		theme, err := ParseTheme(data)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %v", filepath.Base(path), err))
+			continue
+		}
+		registerTheme(theme)
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("could not load themes: %s", strings.Join(problems, "; "))
+	}
+	return nil
+}
@@ -0,0 +1,201 @@
+package main
+
+import (
+	"github.com/gdamore/tcell/v2"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testThemeJSON = `{
+	"name": "test",
+	"puyos": {
+		"red":    {"fg": "#ff0000", "glyph": "r"},
+		"green":  {"fg": "green", "glyph": "g"},
+		"blue":   {"fg": "blue", "bg": "white", "glyph": "b", "bold": true},
+		"yellow": {"fg": "yellow", "glyph": "y"},
+		"purple": {"fg": "purple", "glyph": "p"},
+		"orange": {"fg": "#ff8700", "glyph": "o"},
+		"nuisance": {"fg": "gray", "glyph": "n"}
+	},
+	"border": "ascii"
+}`
+
+func TestBuiltinThemesComplete(t *testing.T) {
+	for _, theme := range builtinThemes() {
+		for c := Red; c <= Nuisance; c++ {
+			look, ok := theme.Puyos[c]
+			if !ok {
+				t.Errorf("Theme %q has no look for %s", theme.Name, c.Name())
+				continue
+			}
+			if look.Glyph == "" {
+				t.Errorf("Theme %q has an empty glyph for %s", theme.Name, c.Name())
+			}
+		}
+	}
+}
+
+func TestShapesThemeUsesDistinctGlyphs(t *testing.T) {
+	for _, name := range []string{"shapes", "ascii", "high-contrast"} {
+		theme := ThemeByName(name)
+		seen := make(map[string]Color)
+		for c := Red; c <= Nuisance; c++ {
+			glyph := theme.Puyos[c].Glyph
+			if other, ok := seen[glyph]; ok {
+				t.Errorf("Theme %q uses %q for both %s and %s", name, glyph, other.Name(), c.Name())
+			}
+			seen[glyph] = c
+		}
+	}
+}
+
+func TestParseTheme(t *testing.T) {
+	theme, err := ParseTheme([]byte(testThemeJSON))
+	if err != nil {
+		t.Fatalf("ParseTheme failed: %v", err)
+	}
+
+	if theme.Name != "test" || theme.Border != asciiBorder || theme.Ghost == "" {
+		t.Errorf("Unexpected theme %+v", theme)
+	}
+
+	glyph, style := theme.Look(Blue)
+	if glyph != "b " {
+		t.Errorf("Expected padded glyph %q, got %q", "b ", glyph)
+	}
+	fg, bg, attrs := style.Decompose()
+	if fg != tcell.ColorBlue || bg != tcell.ColorWhite || attrs&tcell.AttrBold == 0 {
+		t.Errorf("Unexpected style fg=%v bg=%v attrs=%v", fg, bg, attrs)
+	}
+}
+
+func TestParseThemeInvalid(t *testing.T) {
+	tests := []string{
+		`{"puyos": {}}`,
+		`{"name": "x", "puyos": {}}`,
+		`{"name": "x", "border": "fancy", "puyos": {}}`,
+		`{"name": "x", "puyos": {
+			"red": {"glyph": "abc"}, "green": {"glyph": "g"}, "blue": {"glyph": "b"},
+			"yellow": {"glyph": "y"}, "purple": {"glyph": "p"}}}`,
+		`{"name": "x", "puyos": {
+			"red": {"fg": "nosuchcolor", "glyph": "r"}, "green": {"glyph": "g"}, "blue": {"glyph": "b"},
+			"yellow": {"glyph": "y"}, "purple": {"glyph": "p"}}}`,
+		`not json`,
+	}
+
+	for _, data := range tests {
+		if _, err := ParseTheme([]byte(data)); err == nil {
+			t.Errorf("Expected error for theme %s", data)
+		}
+	}
+
+	// Every color must be given, and the error names the missing one
+	_, err := ParseTheme([]byte(`{"name": "x", "puyos": {
+		"red": {"glyph": "r"}, "green": {"glyph": "g"}, "blue": {"glyph": "b"},
+		"yellow": {"glyph": "y"}, "purple": {"glyph": "p"}, "orange": {"glyph": "o"}}}`))
+	if err == nil || !strings.Contains(err.Error(), `"nuisance"`) {
+		t.Errorf("Expected an error naming the missing nuisance color, got %v", err)
+	}
+}
+
+func TestLoadUserThemes(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+
+	dir := filepath.Join(home, ".puyo", "themes")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "test.json"), []byte(testThemeJSON), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "broken.json"), []byte("{"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	defer delete(themes, "test")
+
+	if err := LoadUserThemes(); err == nil {
+		t.Error("Expected an error for the broken theme")
+	}
+	if ThemeByName("test").Name != "test" {
+		t.Error("Expected valid user theme to be registered")
+	}
+}
+
+func TestThemeByNameFallback(t *testing.T) {
+	if ThemeByName("no-such-theme").Name != "classic" {
+		t.Error("Expected unknown theme to fall back to classic")
+	}
+}
+
+func TestDrawFieldAsciiTheme(t *testing.T) {
+	screen := newTestScreen(t)
+
+	game := NewGame()
+	game.Field.Grid[FieldHeight-1][0] = Red
+
+	ui := newUIWithScreen(screen, game)
+	ui.Settings.Theme = "ascii"
+	ui.Draw()
+
+	l := ui.layout()
+	if got := cellRune(screen, l.FieldX, l.FieldY); got != '+' {
+		t.Errorf("Expected ASCII corner, got %q", got)
+	}
+	if got := cellRune(screen, l.FieldX, l.FieldY+1); got != '|' {
+		t.Errorf("Expected ASCII border, got %q", got)
+	}
+	if got := cellRune(screen, l.CellX(0), l.CellY(FieldHeight-1)); got != 'O' {
+		t.Errorf("Expected ASCII red puyo, got %q", got)
+	}
+}
+
+func TestLinkedLook(t *testing.T) {
+	classic := ThemeByName("classic")
+
+	if glyph, _ := classic.LinkedLook(Red, 0); glyph != "● " {
+		t.Errorf("Expected isolated glyph, got %q", glyph)
+	}
+	if glyph, _ := classic.LinkedLook(Red, LinkUp); glyph != "█ " {
+		t.Errorf("Expected linked glyph without bridge, got %q", glyph)
+	}
+	if glyph, _ := classic.LinkedLook(Red, LinkRight); glyph != "██" {
+		t.Errorf("Expected linked glyph with bridge, got %q", glyph)
+	}
+
+	// Shape themes keep their glyph and only bridge the gap
+	shapes := ThemeByName("shapes")
+	if glyph, _ := shapes.LinkedLook(Green, LinkRight|LinkDown); glyph != "▲─" {
+		t.Errorf("Expected shape with bridge, got %q", glyph)
+	}
+}
+
+func TestDrawFieldConnected(t *testing.T) {
+	screen := newTestScreen(t)
+
+	game := NewGame()
+	game.Field.Grid[FieldHeight-1][0] = Red
+	game.Field.Grid[FieldHeight-1][1] = Red
+	game.Field.Grid[FieldHeight-1][3] = Red
+
+	ui := newUIWithScreen(screen, game)
+	ui.Draw()
+
+	l := ui.layout()
+	y := l.CellY(FieldHeight - 1)
+	expected := []rune{'█', '█', '█', ' ', ' ', ' ', '●', ' '}
+	for i, r := range expected {
+		if got := cellRune(screen, l.CellX(0)+i, y); got != r {
+			t.Errorf("Column %d: expected %q, got %q", i, r, got)
+		}
+	}
+
+	// Disabling the setting draws every puyo on its own
+	ui.Settings.Connected = false
+	ui.Draw()
+	if got := cellRune(screen, l.CellX(0)+1, y); got != ' ' {
+		t.Errorf("Expected gap between unconnected puyos, got %q", got)
+	}
+}
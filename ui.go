@@ -1,17 +1,44 @@
 package main
 
 import (
-	"fmt"
 	"github.com/gdamore/tcell/v2"
+	"github.com/mattn/go-runewidth"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
 	"time"
 )
 
 // UI represents the terminal UI
 type UI struct {
-	screen tcell.Screen
-	game   *Game
+	screen      tcell.Screen
+	game        *Game
+	Settings    *Settings   // Ghost, DAS/ARR and key bindings
+	OnGameEnd   func(*Game) // Called with a finished game before it is replaced by a restart
+	OnGameStart func(*Game) // Called with the new game after a restart
+
+	// Auto-shift state for DAS/ARR, counted in frames
+	frame          int
+	shiftAction    Action
+	shiftPressed   int // Frame the shift key was first pressed
+	shiftLastEvent int // Frame of the last event for the shift key
+	shiftLastMove  int // Frame of the last move made by the shift key
+
+	allClearUntil int // Frame the all-clear banner is shown until
 }
 
+// shiftHoldGap is the longest gap between key events (in frames) that still
+// counts as holding the key. Terminals only report key repeats, not releases,
+// so this must cover the terminal's initial repeat delay.
+const shiftHoldGap = 40
+
+// popBlinkFrames is how long popping puyos stay visible or hidden while blinking
+const popBlinkFrames = 4
+
+// allClearBannerFrames is how long the all-clear banner stays on screen
+const allClearBannerFrames = 120
+
 // NewUI creates a new UI
 func NewUI(game *Game) (*UI, error) {
 	screen, err := tcell.NewScreen()
@@ -23,13 +50,19 @@ func NewUI(game *Game) (*UI, error) {
 		return nil, err
 	}
 
+	return newUIWithScreen(screen, game), nil
+}
+
+// newUIWithScreen creates a UI drawing to an initialized screen
+func newUIWithScreen(screen tcell.Screen, game *Game) *UI {
 	screen.SetStyle(tcell.StyleDefault)
 	screen.Clear()
 
 	return &UI{
-		screen: screen,
-		game:   game,
-	}, nil
+		screen:   screen,
+		game:     game,
+		Settings: DefaultSettings(),
+	}
 }
 
 // Close closes the UI
@@ -39,149 +72,325 @@ func (ui *UI) Close() {
 
 // drawText draws text at the given position
 func (ui *UI) drawText(x, y int, text string, style tcell.Style) {
-	for i, r := range text {
-		ui.screen.SetContent(x+i, y, r, nil, style)
+	drawText(ui.screen, x, y, text, style)
+}
+
+// drawText draws text on a screen and returns the column after it
+// Columns advance by the display width of each rune, so wide characters
+// (Japanese text, emoji) take two cells and zero-width runes combine with
+// the previous character.
+func drawText(screen tcell.Screen, x, y int, text string, style tcell.Style) int {
+	var (
+		mainc rune
+		combc []rune
+		width int
+	)
+	flush := func() {
+		if width > 0 {
+			screen.SetContent(x, y, mainc, combc, style)
+			x += width
+		}
+	}
+
+	for _, r := range text {
+		w := runewidth.RuneWidth(r)
+		if w == 0 && width > 0 {
+			// Combining mark or variation selector
+			combc = append(combc, r)
+			continue
+		}
+		flush()
+		mainc, combc, width = r, nil, w
+	}
+	flush()
+
+	return x
+}
+
+// theme returns the theme to draw with
+// Terminals with fewer than 8 colors fall back to the ASCII theme unless the
+// chosen theme is already playable without color.
+func (ui *UI) theme() *Theme {
+	theme := ThemeByName(ui.Settings.Theme)
+	if ui.screen.Colors() < 8 && !theme.Monochrome {
+		return ThemeByName("ascii")
+	}
+	return theme
+}
+
+// cellGlyph returns the two-column text and style for a field cell
+// In emoji mode, puyos are drawn as the full-width Color.String() glyphs.
+func (ui *UI) cellGlyph(c Color, style tcell.Style) (string, tcell.Style) {
+	if c == Empty {
+		return "  ", style
+	}
+	if ui.Settings.Emoji {
+		return c.String(), style
+	}
+	return ui.theme().Look(c)
+}
+
+// layout computes the screen layout from the current screen size
+func (ui *UI) layout() Layout {
+	width, height := ui.screen.Size()
+	return computeLayout(width, height, ui.game.Field.Width, ui.game.Field.Height)
+}
+
+// drawCell draws a two-column cell at field position (x, y), scaled to the layout
+func (ui *UI) drawCell(l Layout, x, y int, text string, style tcell.Style) {
+	ui.drawScaled(l, l.CellX(x), l.CellY(y), text, style)
+}
+
+// drawScaled draws a two-column cell at a screen position, scaled to the layout
+func (ui *UI) drawScaled(l Layout, x, y int, text string, style tcell.Style) {
+	text = l.scaleCell(text)
+	for row := 0; row < l.CellH; row++ {
+		ui.drawText(x, y+row, text, style)
 	}
 }
 
+// drawTooSmall tells the player to enlarge the terminal
+func (ui *UI) drawTooSmall(l Layout) {
+	style := tcell.StyleDefault.Foreground(tcell.ColorRed).Bold(true)
+	ui.drawText(0, 0, T("game.too_small"), style)
+	ui.drawText(0, 1, T("game.too_small_size", l.MinWidth, l.MinHeight), tcell.StyleDefault)
+}
+
 // Draw draws the game state
 func (ui *UI) Draw() {
 	ui.screen.Clear()
 
+	l := ui.layout()
+	if l.TooSmall {
+		ui.drawTooSmall(l)
+		ui.screen.Show()
+		return
+	}
+
 	style := tcell.StyleDefault
 	titleStyle := tcell.StyleDefault.Bold(true)
 	headerStyle := tcell.StyleDefault.Foreground(tcell.ColorYellow)
 
-	// Title
-	ui.drawText(2, 1, "Terminal Puyo", titleStyle)
+	// Title, or the name of the mission being played
+	if l.Mode != LayoutCompact {
+		title := "Terminal Puyo"
+		if m := ui.game.Mission(); m != nil {
+			title = m.Title()
+		}
+		ui.drawText(l.InfoX, l.InfoY-2, title, titleStyle)
+	}
 
 	// Score and stats
-	ui.drawText(2, 3, fmt.Sprintf("Score: %d", ui.game.Score), headerStyle)
-	ui.drawText(2, 4, fmt.Sprintf("Level: %d", ui.game.Level), headerStyle)
-	ui.drawText(2, 5, fmt.Sprintf("Chains: %d", ui.game.TotalChains), headerStyle)
-	ui.drawText(2, 6, fmt.Sprintf("Colors: %d", ui.game.ColorCount), headerStyle)
+	ui.drawText(l.InfoX, l.InfoY, T("game.score", ui.game.Score), headerStyle)
+	ui.drawText(l.InfoX, l.InfoY+1, T("game.level", ui.game.Level), headerStyle)
+	ui.drawText(l.InfoX, l.InfoY+2, T("game.chains", ui.game.TotalChains), headerStyle)
+	switch {
+	case ui.game.Rules.Timed():
+		ui.drawText(l.InfoX, l.InfoY+3, T("game.time", formatFrames(ui.game.TimerFrames())), headerStyle)
+	case ui.game.FeverActive:
+		feverStyle := style.Foreground(tcell.ColorFuchsia).Bold(true)
+		ui.drawText(l.InfoX, l.InfoY+3, T("game.fever_time", float64(ui.game.FeverFrames)/60), feverStyle)
+	case ui.game.Rules.Fever:
+		gauge := strings.Repeat("■", ui.game.FeverGauge) + strings.Repeat("□", FeverGaugeMax-ui.game.FeverGauge)
+		ui.drawText(l.InfoX, l.InfoY+3, T("game.fever_gauge", gauge), headerStyle)
+	default:
+		ui.drawText(l.InfoX, l.InfoY+3, T("game.colors", ui.game.ColorCount), headerStyle)
+	}
 
-	// High score
-	if ui.game.HighScore != nil && ui.game.HighScore.Score > 0 {
+	// The target point replaces the high score in versus; it turns red in margin time
+	if ui.game.Versus {
+		target := ui.game.TargetPoint()
+		targetStyle := headerStyle
+		if target < ui.game.Rules.TargetPoint {
+			targetStyle = style.Foreground(tcell.ColorRed).Bold(true)
+		}
+		ui.drawText(l.InfoX, l.InfoY+4, T("game.target_point", target), targetStyle)
+	} else if goal := ui.goalText(); goal != "" {
+		ui.drawText(l.InfoX, l.InfoY+4, goal, headerStyle)
+	} else if ui.game.HighScore != nil && ui.game.HighScore.Score > 0 && !ui.game.Rules.Timed() {
 		hsStyle := tcell.StyleDefault.Foreground(tcell.ColorGreen)
-		ui.drawText(2, 7, fmt.Sprintf("High Score: %d", ui.game.HighScore.Score), hsStyle)
+		ui.drawText(l.InfoX, l.InfoY+4, T("game.high_score", ui.game.HighScore.Score), hsStyle)
 	}
 
 	// Create a copy of the field to overlay the current pair
-	display := ui.game.Field.Grid
+	field := ui.game.Field
+	display := field.Clone().Grid
+
+	// Ghost puyos show where the pair will land
+	ghost := NewFieldSize(field.Width, field.Height).Grid
+	if ui.Settings.Ghost && ui.game.State == StateNormal {
+		for _, cell := range ui.game.GhostCells() {
+			if field.InBounds(cell.Pos.X, cell.Pos.Y) {
+				ghost[cell.Pos.Y][cell.Pos.X] = cell.Color
+			}
+		}
+	}
 
 	if ui.game.Current != nil {
-		subPos := ui.game.Current.GetSubPosition()
-		if subPos.Y >= 0 && subPos.Y < FieldHeight && subPos.X >= 0 && subPos.X < FieldWidth {
-			display[subPos.Y][subPos.X] = ui.game.Current.Sub.Color
-		}
-		if ui.game.Current.Pos.Y >= 0 && ui.game.Current.Pos.Y < FieldHeight {
-			display[ui.game.Current.Pos.Y][ui.game.Current.Pos.X] = ui.game.Current.Main.Color
+		for _, cell := range ui.game.Current.Cells() {
+			if field.InBounds(cell.Pos.X, cell.Pos.Y) {
+				display[cell.Pos.Y][cell.Pos.X] = cell.Color
+			}
 		}
 	}
 
 	// Draw field border and content
-	startY := 8
-	startX := 2
+	border := ui.theme().Border
+	fieldW, fieldH := l.fieldSize()
+	right := l.FieldX + fieldW - 1
+	bottom := l.FieldY + fieldH - 1
+
+	// Top and bottom border
+	ui.drawText(l.FieldX, l.FieldY, border.TopLeft, style)
+	ui.drawText(l.FieldX, bottom, border.BottomLeft, style)
+	for i := l.FieldX + 1; i < right; i++ {
+		ui.drawText(i, l.FieldY, border.Horizontal, style)
+		ui.drawText(i, bottom, border.Horizontal, style)
+	}
+	ui.drawText(right, l.FieldY, border.TopRight, style)
+	ui.drawText(right, bottom, border.BottomRight, style)
 
-	// Top border
-	ui.drawText(startX, startY, "┌", style)
-	for i := 0; i < FieldWidth*2; i++ {
-		ui.drawText(startX+1+i, startY, "─", style)
+	// Side borders
+	for y := l.FieldY + 1; y < bottom; y++ {
+		ui.drawText(l.FieldX, y, border.Vertical, style)
+		ui.drawText(right, y, border.Vertical, style)
+	}
+
+	// Garbage tray over the top border
+	trayStyle := style.Foreground(tcell.ColorSilver).Bold(true)
+	for i, icon := range ui.game.Garbage.Tray(field.Width) {
+		glyph := icon.Glyph
+		if border == asciiBorder {
+			glyph = icon.ASCII
+		}
+		ui.drawText(l.CellX(i), l.FieldY, padCell(glyph), trayStyle)
 	}
-	ui.drawText(startX+FieldWidth*2+1, startY, "┐", style)
 
 	// Field content
-	for y := 0; y < FieldHeight; y++ {
-		ui.drawText(startX, startY+1+y, "│", style)
-		for x := 0; x < FieldWidth; x++ {
-			color := display[y][x]
-			cellStyle := style
-			char := "  "
-
-			switch color {
-			case Red:
-				cellStyle = style.Foreground(tcell.ColorRed)
-				char = "●"
-			case Green:
-				cellStyle = style.Foreground(tcell.ColorGreen)
-				char = "●"
-			case Blue:
-				cellStyle = style.Foreground(tcell.ColorBlue)
-				char = "●"
-			case Yellow:
-				cellStyle = style.Foreground(tcell.ColorYellow)
-				char = "●"
-			case Purple:
-				cellStyle = style.Foreground(tcell.ColorPurple)
-				char = "●"
+	for y := 0; y < field.Height; y++ {
+		for x := 0; x < field.Width; x++ {
+			char, cellStyle := ui.cellGlyph(display[y][x], style)
+			if links := field.Links(x, y); links != 0 && ui.Settings.Connected && !ui.Settings.Emoji {
+				// Settled puyos are drawn joined to their same-colored neighbors
+				char, cellStyle = ui.theme().LinkedLook(display[y][x], links)
+			}
+			if ui.game.IsPopping(x, y) && (ui.game.AnimFrame/popBlinkFrames)%2 == 1 {
+				// Popping puyos blink before they are removed
+				char = "  "
+			}
+			if display[y][x] == Empty && ghost[y][x] != Empty {
+				char, cellStyle = ui.theme().GhostLook(ghost[y][x])
 			}
 
-			ui.drawText(startX+1+x*2, startY+1+y, char+" ", cellStyle)
+			ui.drawCell(l, x, y, char, cellStyle)
 		}
-		ui.drawText(startX+FieldWidth*2+1, startY+1+y, "│", style)
 	}
 
-	// Bottom border
-	ui.drawText(startX, startY+FieldHeight+1, "└", style)
-	for i := 0; i < FieldWidth*2; i++ {
-		ui.drawText(startX+1+i, startY+FieldHeight+1, "─", style)
-	}
-	ui.drawText(startX+FieldWidth*2+1, startY+FieldHeight+1, "┘", style)
-
 	// Next puyo
-	nextY := startY + 2
-	nextX := startX + FieldWidth*2 + 5
-
-	ui.drawText(nextX, nextY, "Next:", headerStyle)
+	ui.drawText(l.NextX, l.NextY, T("game.next"), headerStyle)
 	if ui.game.Next != nil {
-		nextStyle := style.Foreground(getColorForPuyo(ui.game.Next.Main.Color))
-		ui.drawText(nextX, nextY+1, "●", nextStyle)
-
-		nextStyle = style.Foreground(getColorForPuyo(ui.game.Next.Sub.Color))
-		ui.drawText(nextX, nextY+2, "●", nextStyle)
+		// Drawn as it spawns, in a box of up to 2x2 cells
+		next := *ui.game.Next
+		next.Pos, next.Rotate = Position{0, 1}, 0
+		for _, cell := range next.Cells() {
+			char, nextStyle := ui.cellGlyph(cell.Color, style)
+			ui.drawScaled(l, l.NextX+cell.Pos.X*l.CellW, l.NextY+1+cell.Pos.Y*l.CellH, char, nextStyle)
+		}
 	}
 
-	// Chain display
+	// Chain display, next to the popped group
 	if ui.game.State != StateNormal && ui.game.CurrentChainNum > 0 {
-		chainY := startY + FieldHeight/2 - 2
-		chainX := startX + FieldWidth - 2
 		chainStyle := style.Foreground(tcell.ColorYellow).Bold(true)
-		chainText := fmt.Sprintf("%d CHAIN!", ui.game.CurrentChainNum)
+		chainText := T("game.chain", ui.game.CurrentChainNum)
+
+		// Above the group, or below it when the group touches the top
+		chainY := l.CellY(ui.game.ChainPos.Y) - 1
+		if ui.game.ChainPos.Y == 0 {
+			chainY = l.CellY(1)
+		}
+		chainX := l.CellX(ui.game.ChainPos.X)
+		if maxX := right - runewidth.StringWidth(chainText); chainX > maxX {
+			chainX = maxX
+		}
+		if chainX < l.FieldX+1 {
+			chainX = l.FieldX + 1
+		}
 		ui.drawText(chainX, chainY, chainText, chainStyle)
 	}
 
 	// Controls
-	controlsY := startY + 6
-	ui.drawText(nextX, controlsY, "Controls:", headerStyle)
-	ui.drawText(nextX, controlsY+1, "←→: Move", style)
-	ui.drawText(nextX, controlsY+2, "↓: Drop", style)
-	ui.drawText(nextX, controlsY+3, "Z/X: Rotate", style)
-	ui.drawText(nextX, controlsY+4, "P: Pause", style)
-	ui.drawText(nextX, controlsY+5, "Q: Quit", style)
+	keys := ui.Settings.Keys
+	if l.Mode != LayoutCompact {
+		controlsY := l.ControlsY
+		ui.drawText(l.NextX, controlsY, T("game.controls"), headerStyle)
+		ui.drawText(l.NextX, controlsY+1, T("game.move", keyLabel(keys[ActionLeft])+keyLabel(keys[ActionRight])), style)
+		ui.drawText(l.NextX, controlsY+2, T("game.drop", keyLabel(keys[ActionSoftDrop])), style)
+		ui.drawText(l.NextX, controlsY+3, T("game.hard_drop", keyLabel(keys[ActionHardDrop])), style)
+		ui.drawText(l.NextX, controlsY+4, T("game.rotate", keyLabel(keys[ActionRotateCCW])+"/"+keyLabel(keys[ActionRotateCW])), style)
+		ui.drawText(l.NextX, controlsY+5, T("game.pause", keyLabel(keys[ActionPause])), style)
+		ui.drawText(l.NextX, controlsY+6, T("game.quit", keyLabel(keys[ActionQuit])), style)
+	}
+
+	// Messages over the middle of the field
+	msgY := l.FieldY + fieldH/2
+	msgX := l.FieldX + 1
+
+	// All-clear banner, above the pause and game over messages
+	if ui.frame < ui.allClearUntil {
+		allClearStyle := style.Foreground(tcell.ColorFuchsia).Bold(true)
+		ui.drawText(msgX+1, msgY-2, T("game.all_clear"), allClearStyle)
+		if bonus := ui.game.Rules.AllClearBonus; bonus > 0 && !ui.game.Versus {
+			ui.drawText(msgX+1, msgY-1, T("game.all_clear_bonus", bonus), style)
+		}
+	}
 
 	// Pause message
 	if ui.game.Paused {
-		msgY := startY + FieldHeight/2
-		msgX := startX + 2
 		pauseStyle := style.Foreground(tcell.ColorAqua).Bold(true)
-		ui.drawText(msgX, msgY, "PAUSED", pauseStyle)
-		ui.drawText(msgX-2, msgY+2, "Press P to resume", style)
+		ui.drawText(msgX+1, msgY, T("game.paused"), pauseStyle)
+		ui.drawText(msgX-1, msgY+2, T("game.resume_hint", keyLabel(keys[ActionPause])), style)
 	}
 
-	// Game over message
+	// Game over message, or the result of a timed run
 	if ui.game.GameOver {
-		msgY := startY + FieldHeight/2
-		msgX := startX + 3
 		gameOverStyle := style.Foreground(tcell.ColorRed).Bold(true)
-		ui.drawText(msgX, msgY, "GAME OVER!", gameOverStyle)
-		ui.drawText(msgX-2, msgY+2, "Press R to restart", style)
-		ui.drawText(msgX-2, msgY+3, "Press Q to quit", style)
+		switch {
+		case ui.game.Cleared && ui.game.Rules.Goal == GoalUltra:
+			ui.drawText(msgX+2, msgY, T("game.time_up"), gameOverStyle.Foreground(tcell.ColorAqua))
+		case ui.game.Cleared:
+			ui.drawText(msgX+2, msgY, T("game.finish"), gameOverStyle.Foreground(tcell.ColorAqua))
+			ui.drawText(msgX+2, msgY+1, formatFrames(ui.game.Frames), style)
+		case ui.game.Rules.Goal == GoalMission:
+			ui.drawText(msgX+2, msgY, T("game.failed"), gameOverStyle)
+		default:
+			ui.drawText(msgX+2, msgY, T("game.over"), gameOverStyle)
+		}
+		ui.drawText(msgX, msgY+2, T("game.restart_hint"), style)
+		ui.drawText(msgX, msgY+3, T("game.quit_hint", keyLabel(keys[ActionQuit])), style)
 	}
 
 	ui.screen.Show()
 }
 
+// goalText describes the goal of a sprint or the current mission objective,
+// or returns "" for other modes
+func (ui *UI) goalText() string {
+	switch ui.game.Rules.Goal {
+	case GoalMission:
+		m, o := ui.game.Mission(), ui.game.Objective()
+		if m == nil || o == nil {
+			return ""
+		}
+		return T("mission.step", ui.game.MissionStep+1, len(m.Objectives), o.Describe())
+	case GoalChain:
+		return T("game.goal_chain", ui.game.Rules.GoalTarget)
+	case GoalScore:
+		return T("game.goal_score", ui.game.Rules.GoalTarget)
+	default:
+		return ""
+	}
+}
+
 // getColorForPuyo returns the tcell color for a puyo color
 func getColorForPuyo(c Color) tcell.Color {
 	switch c {
@@ -195,6 +404,10 @@ func getColorForPuyo(c Color) tcell.Color {
 		return tcell.ColorYellow
 	case Purple:
 		return tcell.ColorPurple
+	case Orange:
+		return tcell.ColorOrange
+	case Nuisance:
+		return tcell.ColorSilver
 	default:
 		return tcell.ColorWhite
 	}
@@ -209,10 +422,6 @@ func (ui *UI) Run() {
 	frameTicker := time.NewTicker(time.Second / 60)
 	defer frameTicker.Stop()
 
-	// Chain animation ticker (faster than normal drop)
-	chainTicker := time.NewTicker(300 * time.Millisecond)
-	defer chainTicker.Stop()
-
 	// Input channel
 	eventChan := make(chan tcell.Event)
 	go func() {
@@ -221,10 +430,29 @@ func (ui *UI) Run() {
 		}
 	}()
 
+	// Stop on SIGTERM/SIGHUP so the caller can suspend the game
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, syscall.SIGTERM, syscall.SIGHUP)
+	defer signal.Stop(sigChan)
+
+	// A new pair starts with a full drop interval at the current level's speed
+	unsubscribe := ui.game.Events.Subscribe(func(ev Event) {
+		switch ev.(type) {
+		case PairSpawned:
+			ticker.Reset(ui.game.DropSpeed)
+		case AllClear:
+			ui.allClearUntil = ui.frame + allClearBannerFrames
+		}
+	})
+	defer unsubscribe()
+
 	ui.Draw()
 
 	for {
 		select {
+		case <-sigChan:
+			return
+
 		case <-ticker.C:
 			if !ui.game.GameOver && !ui.game.Paused && ui.game.State == StateNormal {
 				// Update ticker speed if level changed
@@ -242,7 +470,23 @@ func (ui *UI) Run() {
 			}
 
 		case <-frameTicker.C:
-			if !ui.game.GameOver && !ui.game.Paused && ui.game.State == StateNormal {
+			ui.frame++
+			if ui.frame == ui.allClearUntil {
+				ui.Draw()
+			}
+			if ui.game.TickTimer() {
+				ui.Draw()
+			}
+			if !ui.game.GameOver && !ui.game.Paused && ui.game.State != StateNormal {
+				// Advance the chain animation frame by frame
+				ui.game.UpdateChain()
+				ui.Draw()
+			} else if !ui.game.GameOver && !ui.game.Paused {
+				// The fever timer runs while the player is placing pieces
+				if ui.game.TickFever() {
+					ui.Draw()
+				}
+
 				// Count ground frames at 60fps
 				if ui.game.IsOnGround() {
 					ui.game.GroundFrames++
@@ -255,27 +499,16 @@ func (ui *UI) Run() {
 				}
 			}
 
-		case <-chainTicker.C:
-			if !ui.game.GameOver && !ui.game.Paused && ui.game.State != StateNormal {
-				// Process chain animation step by step
-				hasMore := ui.game.ProcessChainStep()
-				ui.Draw()
-
-				if !hasMore {
-					// Chain finished, reset to normal ticker
-					ticker.Reset(ui.game.DropSpeed)
-				}
-			}
-
 		case ev := <-eventChan:
 			switch ev := ev.(type) {
 			case *tcell.EventKey:
-				if ev.Key() == tcell.KeyEscape || ev.Rune() == 'q' || ev.Rune() == 'Q' {
+				action, bound := ui.Settings.Keys.ActionFor(ev)
+				if ev.Key() == tcell.KeyEscape || action == ActionQuit {
 					return
 				}
 
 				// Handle pause
-				if ev.Rune() == 'p' || ev.Rune() == 'P' {
+				if action == ActionPause {
 					ui.game.TogglePause()
 					ui.Draw()
 					continue
@@ -283,29 +516,39 @@ func (ui *UI) Run() {
 
 				if ui.game.GameOver {
 					if ev.Rune() == 'r' || ev.Rune() == 'R' {
-						// Save the high score and color count before restarting
-						oldHighScore := ui.game.HighScore
-						oldColorCount := ui.game.ColorCount
-						ui.game = NewGameWithColors(oldColorCount)
-						ui.game.HighScore = oldHighScore
+						if ui.OnGameEnd != nil {
+							ui.OnGameEnd(ui.game)
+						}
+
+						// Keep the high score, rules and subscribers for the new game
+						old := ui.game
+						ui.game = newGame(old.Rules, time.Now().UnixNano(), old.Events)
+						ui.game.ApplySettings(ui.Settings)
+						ui.game.HighScore = old.HighScore
+						if ui.OnGameStart != nil {
+							ui.OnGameStart(ui.game)
+						}
+
+						// The subscribers see the first pair of the new game
+						ui.game.start()
 						ui.Draw()
 					}
 					continue
 				}
 
 				// Ignore input during chain animation or when paused
-				if ui.game.State != StateNormal || ui.game.Paused {
+				if !bound || ui.game.State != StateNormal || ui.game.Paused {
 					continue
 				}
 
-				switch ev.Key() {
-				case tcell.KeyLeft:
-					ui.game.Move(-1, 0, 0)
+				switch action {
+				case ActionLeft:
+					ui.shift(action, -1)
 					ui.Draw()
-				case tcell.KeyRight:
-					ui.game.Move(1, 0, 0)
+				case ActionRight:
+					ui.shift(action, 1)
 					ui.Draw()
-				case tcell.KeyDown:
+				case ActionSoftDrop:
 					// Soft drop - drop quickly (relies on key repeat)
 					ui.game.Drop()
 
@@ -314,21 +557,59 @@ func (ui *UI) Run() {
 						ui.game.LockPair()
 					}
 					ui.Draw()
-				case tcell.KeyRune:
-					switch ev.Rune() {
-					case 'z', 'Z':
-						ui.game.Move(0, 0, -1) // Rotate counter-clockwise
-						ui.Draw()
-					case 'x', 'X':
-						ui.game.Move(0, 0, 1) // Rotate clockwise
-						ui.Draw()
-					}
+				case ActionHardDrop:
+					ui.game.HardDrop()
+					ui.game.LockPair()
+					ui.Draw()
+				case ActionRotateCCW:
+					ui.game.Move(0, 0, -1) // Rotate counter-clockwise
+					ui.Draw()
+				case ActionRotateCW:
+					ui.game.Move(0, 0, 1) // Rotate clockwise
+					ui.Draw()
 				}
 
 			case *tcell.EventResize:
+				// Don't let the game run while the board can't be seen
+				if ui.layout().TooSmall && !ui.game.Paused && !ui.game.GameOver {
+					ui.game.TogglePause()
+				}
 				ui.screen.Sync()
 				ui.Draw()
 			}
 		}
 	}
 }
+
+// shift moves the current pair horizontally, applying DAS and ARR to
+// repeated key events
+func (ui *UI) shift(action Action, dx int) {
+	held := action == ui.shiftAction && ui.frame-ui.shiftLastEvent <= shiftHoldGap
+	ui.shiftLastEvent = ui.frame
+
+	if !held {
+		// Fresh press: move once and start the DAS timer
+		ui.shiftAction = action
+		ui.shiftPressed = ui.frame
+		ui.shiftLastMove = ui.frame
+		ui.game.Move(dx, 0, 0)
+		return
+	}
+
+	// Repeats are ignored until the key has been held for DAS frames
+	if ui.frame-ui.shiftPressed < ui.Settings.DAS {
+		return
+	}
+
+	if ui.Settings.ARR == 0 {
+		for ui.game.Move(dx, 0, 0) {
+			// Move all the way to the wall
+		}
+		return
+	}
+
+	if ui.frame-ui.shiftLastMove >= ui.Settings.ARR {
+		ui.shiftLastMove = ui.frame
+		ui.game.Move(dx, 0, 0)
+	}
+}
@@ -0,0 +1,249 @@
+package main
+
+import (
+	"github.com/gdamore/tcell/v2"
+	"strings"
+	"testing"
+)
+
+// newTestScreen creates a 60x30 simulation screen, which uses the normal layout
+func newTestScreen(t *testing.T) tcell.SimulationScreen {
+	t.Helper()
+
+	screen := tcell.NewSimulationScreen("UTF-8")
+	if err := screen.Init(); err != nil {
+		t.Fatalf("Failed to initialize simulation screen: %v", err)
+	}
+	screen.SetSize(60, 30)
+	t.Cleanup(screen.Fini)
+
+	return screen
+}
+
+// cellRune returns the main rune drawn at a position
+func cellRune(screen tcell.Screen, x, y int) rune {
+	r, _, _, _ := screen.GetContent(x, y)
+	return r
+}
+
+func TestDrawTextWideRunes(t *testing.T) {
+	screen := newTestScreen(t)
+
+	end := drawText(screen, 0, 0, "色数: 4", tcell.StyleDefault)
+
+	expected := map[int]rune{0: '色', 2: '数', 4: ':', 5: ' ', 6: '4'}
+	for x, r := range expected {
+		if got := cellRune(screen, x, 0); got != r {
+			t.Errorf("Cell %d: expected %q, got %q", x, r, got)
+		}
+	}
+	if end != 7 {
+		t.Errorf("Expected text to end at column 7, got %d", end)
+	}
+}
+
+func TestDrawTextNarrowSymbols(t *testing.T) {
+	screen := newTestScreen(t)
+
+	// Box drawing and "●" are single-width and must not leave gaps
+	drawText(screen, 0, 0, "│●│", tcell.StyleDefault)
+
+	for x, r := range []rune{'│', '●', '│'} {
+		if got := cellRune(screen, x, 0); got != r {
+			t.Errorf("Cell %d: expected %q, got %q", x, r, got)
+		}
+	}
+}
+
+func TestDrawTextEmoji(t *testing.T) {
+	screen := newTestScreen(t)
+
+	end := drawText(screen, 0, 0, Red.String()+Blue.String()+"|", tcell.StyleDefault)
+
+	if got := cellRune(screen, 0, 0); got != '🔴' {
+		t.Errorf("Expected red emoji at column 0, got %q", got)
+	}
+	if got := cellRune(screen, 2, 0); got != '🔵' {
+		t.Errorf("Expected blue emoji at column 2, got %q", got)
+	}
+	if got := cellRune(screen, 4, 0); got != '|' {
+		t.Errorf("Expected border at column 4, got %q", got)
+	}
+	if end != 5 {
+		t.Errorf("Expected text to end at column 5, got %d", end)
+	}
+}
+
+func TestDrawFieldEmojiMode(t *testing.T) {
+	screen := newTestScreen(t)
+
+	game := NewGame()
+	game.Field.Grid[FieldHeight-1][0] = Red
+	game.Field.Grid[FieldHeight-1][FieldWidth-1] = Purple
+
+	ui := newUIWithScreen(screen, game)
+	ui.Settings.Emoji = true
+	ui.Draw()
+
+	l := ui.layout()
+	rowY := l.CellY(FieldHeight - 1)
+
+	if got := cellRune(screen, l.CellX(0), rowY); got != '🔴' {
+		t.Errorf("Expected red emoji in first column, got %q", got)
+	}
+	if got := cellRune(screen, l.CellX(FieldWidth-1), rowY); got != '🟣' {
+		t.Errorf("Expected purple emoji in last column, got %q", got)
+	}
+
+	// The field border must stay aligned on every row
+	for y := l.CellY(0); y <= rowY; y++ {
+		if got := cellRune(screen, l.FieldX, y); got != '│' {
+			t.Errorf("Row %d: expected left border, got %q", y, got)
+		}
+		if got := cellRune(screen, l.CellX(FieldWidth), y); got != '│' {
+			t.Errorf("Row %d: expected right border, got %q", y, got)
+		}
+	}
+}
+
+func TestDrawFieldDefaultMode(t *testing.T) {
+	screen := newTestScreen(t)
+
+	game := NewGame()
+	game.Field.Grid[FieldHeight-1][1] = Green
+
+	ui := newUIWithScreen(screen, game)
+	ui.Draw()
+
+	l := ui.layout()
+	x := l.CellX(1)
+	y := l.CellY(FieldHeight - 1)
+
+	r, _, style, _ := screen.GetContent(x, y)
+	if r != '●' {
+		t.Errorf("Expected puyo glyph, got %q", r)
+	}
+	if fg, _, _ := style.Decompose(); fg != tcell.ColorGreen {
+		t.Errorf("Expected green foreground, got %v", fg)
+	}
+	if got := cellRune(screen, l.CellX(FieldWidth), y); got != '│' {
+		t.Errorf("Expected right border, got %q", got)
+	}
+}
+
+// screenText returns the text drawn on a row of the screen
+func screenText(screen tcell.SimulationScreen, y int) string {
+	w, _ := screen.Size()
+	var b []rune
+	for x := 0; x < w; x++ {
+		b = append(b, cellRune(screen, x, y))
+	}
+	return string(b)
+}
+
+// screenContains reports whether any row of the screen contains text
+func screenContains(screen tcell.SimulationScreen, text string) bool {
+	_, h := screen.Size()
+	for y := 0; y < h; y++ {
+		if strings.Contains(screenText(screen, y), text) {
+			return true
+		}
+	}
+	return false
+}
+
+func TestDrawAllClearBanner(t *testing.T) {
+	screen := newTestScreen(t)
+	game := NewGame()
+	ui := newUIWithScreen(screen, game)
+
+	ui.allClearUntil = ui.frame + allClearBannerFrames
+	ui.Draw()
+	if !screenContains(screen, T("game.all_clear")) {
+		t.Error("Expected the all-clear banner")
+	}
+
+	ui.frame = ui.allClearUntil
+	ui.Draw()
+	if screenContains(screen, T("game.all_clear")) {
+		t.Error("Expected the banner to go away")
+	}
+}
+
+func TestDrawGarbageTray(t *testing.T) {
+	screen := newTestScreen(t)
+	game := NewGame()
+	game.ReceiveGarbage(30 + 6 + 1)
+
+	ui := newUIWithScreen(screen, game)
+	ui.Draw()
+
+	l := ui.layout()
+	want := []rune{'★', '●', '•'}
+	for i, r := range want {
+		if got := cellRune(screen, l.CellX(i), l.FieldY); got != r {
+			t.Errorf("Tray icon %d: expected %q, got %q", i, r, got)
+		}
+	}
+	if got := cellRune(screen, l.CellX(len(want)), l.FieldY); got != '─' {
+		t.Errorf("Expected the border after the tray, got %q", got)
+	}
+}
+
+func TestDrawTargetPointInVersus(t *testing.T) {
+	screen := newTestScreen(t)
+	game := NewGame()
+	LinkVersus(game, NewGame())
+	game.Clock.Frames = DefaultMarginTime * 60
+
+	ui := newUIWithScreen(screen, game)
+	ui.Draw()
+
+	if !screenContains(screen, T("game.target_point", 52)) {
+		t.Error("Expected the target point in versus")
+	}
+}
+
+func TestDrawSprintTimer(t *testing.T) {
+	screen := newTestScreen(t)
+	game := NewGameWithRules(RulesByName("sprint-chain"), 1)
+	game.Frames = 62*60 + 3
+
+	ui := newUIWithScreen(screen, game)
+	ui.Draw()
+
+	if !screenContains(screen, T("game.time", "1:02.05")) {
+		t.Error("Expected the timer")
+	}
+	if !screenContains(screen, T("game.goal_chain", 10)) {
+		t.Error("Expected the goal")
+	}
+
+	game.Cleared, game.GameOver = true, true
+	ui.Draw()
+	if !screenContains(screen, T("game.finish")) || screenContains(screen, T("game.over")) {
+		t.Error("Expected the finish message instead of game over")
+	}
+}
+
+func TestDrawMission(t *testing.T) {
+	screen := newTestScreen(t)
+	m := MissionByID("green-8")
+	game := NewGameWithRules(m.GameRules(), 1)
+
+	ui := newUIWithScreen(screen, game)
+	ui.Draw()
+
+	if !screenContains(screen, m.Title()) {
+		t.Error("Expected the mission name as the title")
+	}
+	if !screenContains(screen, T("mission.step", 1, 1, m.Objectives[0].Describe())) {
+		t.Error("Expected the current objective")
+	}
+
+	game.finishRun(false)
+	ui.Draw()
+	if !screenContains(screen, T("game.failed")) {
+		t.Error("Expected the failed message")
+	}
+}